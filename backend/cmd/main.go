@@ -1,6 +1,7 @@
 package main
 
 import (
+	models "dimplom_harmonic/domain"
 	userHandler "dimplom_harmonic/internal/auth/handler"
 	userRepo "dimplom_harmonic/internal/auth/repository"
 	userService "dimplom_harmonic/internal/auth/service"
@@ -22,11 +23,81 @@ import (
 	scheduleRepo "dimplom_harmonic/internal/schedule/repository"
 	scheduleService "dimplom_harmonic/internal/schedule/service"
 
+	paymentLib "dimplom_harmonic/internal/payment"
+	paymentHandler "dimplom_harmonic/internal/payment/handler"
+	paymentProvider "dimplom_harmonic/internal/payment/provider"
+	paymentRepo "dimplom_harmonic/internal/payment/repository"
+	paymentService "dimplom_harmonic/internal/payment/service"
+
+	entitlementRepo "dimplom_harmonic/internal/entitlement/repository"
+	entitlementService "dimplom_harmonic/internal/entitlement/service"
+
+	oauthLib "dimplom_harmonic/internal/oauth"
+	oauthHandler "dimplom_harmonic/internal/oauth/handler"
+	oauthProvider "dimplom_harmonic/internal/oauth/provider"
+	oauthRepo "dimplom_harmonic/internal/oauth/repository"
+	oauthService "dimplom_harmonic/internal/oauth/service"
+
+	adminHandler "dimplom_harmonic/internal/admin/handler"
+	adminRepo "dimplom_harmonic/internal/admin/repository"
+	adminService "dimplom_harmonic/internal/admin/service"
+
+	classroomHandler "dimplom_harmonic/internal/classroom/handler"
+	classroomRepo "dimplom_harmonic/internal/classroom/repository"
+	classroomService "dimplom_harmonic/internal/classroom/service"
+
+	moderationHandler "dimplom_harmonic/internal/moderation/handler"
+	moderationRepo "dimplom_harmonic/internal/moderation/repository"
+	moderationService "dimplom_harmonic/internal/moderation/service"
+
+	realtimeHandler "dimplom_harmonic/internal/realtime/handler"
+
+	eventsRepo "dimplom_harmonic/internal/events/repository"
+	jobsHandler "dimplom_harmonic/internal/jobs/handler"
+	jobsRepo "dimplom_harmonic/internal/jobs/repository"
+
+	notificationChannel "dimplom_harmonic/internal/notification/channel"
+	notificationHandler "dimplom_harmonic/internal/notification/handler"
+	notificationRepo "dimplom_harmonic/internal/notification/repository"
+	notificationService "dimplom_harmonic/internal/notification/service"
+
+	pushHandler "dimplom_harmonic/internal/webpush/handler"
+	pushRepo "dimplom_harmonic/internal/webpush/repository"
+	pushService "dimplom_harmonic/internal/webpush/service"
+
+	webhookHandler "dimplom_harmonic/internal/webhook/handler"
+	webhookRepo "dimplom_harmonic/internal/webhook/repository"
+	webhookService "dimplom_harmonic/internal/webhook/service"
+
+	syncHandler "dimplom_harmonic/internal/offlineSync/handler"
+	syncRepo "dimplom_harmonic/internal/offlineSync/repository"
+	syncService "dimplom_harmonic/internal/offlineSync/service"
+
+	trashHandler "dimplom_harmonic/internal/trash/handler"
+	trashService "dimplom_harmonic/internal/trash/service"
+
+	"context"
+	"dimplom_harmonic/internal/events"
+	"dimplom_harmonic/internal/idempotency"
+	"dimplom_harmonic/internal/jobs"
+	"dimplom_harmonic/internal/jwtkeys"
+	"dimplom_harmonic/internal/mailer"
 	"dimplom_harmonic/internal/middleware"
+	"dimplom_harmonic/internal/notification"
+	"dimplom_harmonic/internal/ratelimit"
+	"dimplom_harmonic/internal/realtime"
+	"dimplom_harmonic/internal/webhook"
+	"dimplom_harmonic/internal/webpush"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	chiMD "github.com/go-chi/chi/v5/middleware"
@@ -51,17 +122,142 @@ func main() {
 
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", dbHost, dbPort, dbUser, dbPassword, dbName)
 
-	jwtKeyString := os.Getenv("JWT_SECRET_KEY")
-	if jwtKeyString == "" {
-		log.Fatal("JWT_KEY_STRING must be set")
+	jwtSecret := []byte(os.Getenv("JWT_SECRET_KEY"))
+
+	var jwtKeys *jwtkeys.KeySet
+	if signingKeyFile := os.Getenv("JWT_SIGNING_KEY_FILE"); signingKeyFile != "" {
+		var verificationKeyFiles []string
+		for _, file := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				verificationKeyFiles = append(verificationKeyFiles, file)
+			}
+		}
+
+		var err error
+		jwtKeys, err = jwtkeys.LoadKeySet(signingKeyFile, verificationKeyFiles, jwtSecret)
+		if err != nil {
+			log.Fatalf("Can't load JWT keys: %v", err)
+		}
+	} else {
+		if len(jwtSecret) == 0 {
+			log.Fatal("JWT_SIGNING_KEY_FILE or JWT_SECRET_KEY must be set")
+		}
+		log.Print("JWT_SIGNING_KEY_FILE is not set, signing access tokens with JWT_SECRET_KEY (HS256)")
+		jwtKeys = jwtkeys.NewHMACKeySet(jwtSecret)
 	}
-	jwtKey := []byte(jwtKeyString)
 
 	httpServer := os.Getenv("HTTP_SERVER")
 	if httpServer == "" {
 		httpServer = "8080"
 	}
 
+	trashRetentionDays := 30
+	if days := os.Getenv("TRASH_RETENTION_DAYS"); days != "" {
+		parsedDays, err := strconv.Atoi(days)
+		if err != nil || parsedDays <= 0 {
+			log.Fatal("TRASH_RETENTION_DAYS must be a positive number")
+		}
+		trashRetentionDays = parsedDays
+	}
+	trashRetention := time.Duration(trashRetentionDays) * 24 * time.Hour
+
+	reportsHideThreshold := 3
+	if threshold := os.Getenv("REPORTS_HIDE_THRESHOLD"); threshold != "" {
+		parsedThreshold, err := strconv.Atoi(threshold)
+		if err != nil || parsedThreshold < 0 {
+			log.Fatal("REPORTS_HIDE_THRESHOLD must be a non-negative number")
+		}
+		reportsHideThreshold = parsedThreshold
+	}
+
+	idempotencyTTLHours := 24
+	if hours := os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"); hours != "" {
+		parsedHours, err := strconv.Atoi(hours)
+		if err != nil || parsedHours <= 0 {
+			log.Fatal("IDEMPOTENCY_KEY_TTL_HOURS must be a positive number")
+		}
+		idempotencyTTLHours = parsedHours
+	}
+	idempotencyTTL := time.Duration(idempotencyTTLHours) * time.Hour
+
+	stripeAPIURL := os.Getenv("STRIPE_API_URL")
+	if stripeAPIURL == "" {
+		stripeAPIURL = "https://api.stripe.com"
+	}
+	stripeSecretKey := os.Getenv("STRIPE_SECRET_KEY")
+	stripeWebhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	// With an empty webhook secret anyone could sign a payment event
+	if stripeSecretKey == "" || stripeWebhookSecret == "" {
+		log.Fatal("STRIPE_SECRET_KEY and STRIPE_WEBHOOK_SECRET must be set")
+	}
+
+	paymentPlans := []paymentLib.Plan{
+		{Id: "month", PriceId: os.Getenv("STRIPE_PRICE_MONTH"), Months: 1, Recurring: true},
+		{Id: "year", PriceId: os.Getenv("STRIPE_PRICE_YEAR"), Months: 12, Recurring: true},
+		{Id: "lifetime", PriceId: os.Getenv("STRIPE_PRICE_LIFETIME")},
+	}
+
+	paymentSuccessURL := os.Getenv("PAYMENT_SUCCESS_URL")
+	if paymentSuccessURL == "" {
+		paymentSuccessURL = "http://localhost:5173/profile"
+	}
+	paymentCancelURL := os.Getenv("PAYMENT_CANCEL_URL")
+	if paymentCancelURL == "" {
+		paymentCancelURL = "http://localhost:5173/payment"
+	}
+
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:5173"
+	}
+
+	var Mailer mailer.Mailer
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		smtpPort := os.Getenv("SMTP_PORT")
+		if smtpPort == "" {
+			smtpPort = "587"
+		}
+		Mailer = mailer.NewSMTPMailer(os.Getenv("SMTP_HOST"), smtpPort, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"), os.Getenv("MAIL_FROM"))
+	default:
+		Mailer = mailer.NewFileMailer(os.Getenv("MAIL_FILE"))
+	}
+
+	apiURL := os.Getenv("API_URL")
+	if apiURL == "" {
+		apiURL = "http://localhost:" + httpServer
+	}
+	oauthRedirectURL := func(provider string) string {
+		return apiURL + "/api/auth/oauth/" + provider + "/callback"
+	}
+
+	var oauthProviders []oauthLib.Provider
+	if clientId := os.Getenv("GOOGLE_CLIENT_ID"); clientId != "" {
+		oauthProviders = append(oauthProviders, oauthProvider.NewOIDCProvider("google", oauthProvider.GoogleIssuer, clientId, os.Getenv("GOOGLE_CLIENT_SECRET"), oauthRedirectURL("google")))
+	}
+	if clientId := os.Getenv("GITHUB_CLIENT_ID"); clientId != "" {
+		oauthProviders = append(oauthProviders, oauthProvider.NewGitHubProvider(clientId, os.Getenv("GITHUB_CLIENT_SECRET"), oauthRedirectURL("github")))
+	}
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		oidcName := os.Getenv("OIDC_NAME")
+		if oidcName == "" {
+			oidcName = "oidc"
+		}
+		oauthProviders = append(oauthProviders, oauthProvider.NewOIDCProvider(oidcName, issuer, os.Getenv("OIDC_CLIENT_ID"), os.Getenv("OIDC_CLIENT_SECRET"), oauthRedirectURL(oidcName)))
+	}
+
+	var vapid *webpush.VAPID
+	if vapidKeyFile := os.Getenv("VAPID_PRIVATE_KEY_FILE"); vapidKeyFile != "" {
+		var err error
+		vapid, err = webpush.LoadVAPID(vapidKeyFile, os.Getenv("VAPID_SUBJECT"))
+		if err != nil {
+			log.Fatalf("Can't load VAPID key: %v", err)
+		}
+	} else {
+		log.Print("VAPID_PRIVATE_KEY_FILE is not set, web push is disabled")
+	}
+	pushAllowPrivate := os.Getenv("WEBPUSH_ALLOW_PRIVATE_ENDPOINTS") == "true"
+
 	db := ConnectToDB(dsn)
 	log.Println("We are connected to DB")
 
@@ -70,20 +266,92 @@ func main() {
 	DeckRepository := deckRepo.NewDeckRepository(db)
 	CardRepository := cardRepo.NewCardRepository(db)
 	WordSetRepository := wordSetRepo.NewWordSetRepository(db)
-
-	UserService := userService.NewUserService(UserRepository, WordSetRepository, ScheduleRepository, DeckRepository, CardRepository, jwtKey, db)
-	WordSetService := wordSetService.NewWordSetService(WordSetRepository, CardRepository, db)
-	DeckService := deckService.NewDeckService(DeckRepository, ScheduleRepository, CardRepository, UserRepository, WordSetRepository, db)
-	CardService := cardService.NewCardService(CardRepository, WordSetRepository, db)
+	PaymentRepository := paymentRepo.NewPaymentRepository(db)
+	EntitlementRepository := entitlementRepo.NewEntitlementRepository(db)
+	OAuthRepository := oauthRepo.NewOAuthRepository(db)
+	AdminRepository := adminRepo.NewAdminRepository(db)
+	ModerationRepository := moderationRepo.NewModerationRepository(db)
+	ClassroomRepository := classroomRepo.NewClassroomRepository(db)
+	SyncRepository := syncRepo.NewSyncRepository(db)
+	OutboxRepository := eventsRepo.NewOutboxRepository(db)
+	WebhookRepository := webhookRepo.NewWebhookRepository(db)
+	NotificationRepository := notificationRepo.NewNotificationRepository(db)
+	PushRepository := pushRepo.NewPushRepository(db)
+	JobRepository := jobsRepo.NewJobRepository(db)
+
+	StripeProvider := paymentProvider.NewStripeProvider(stripeAPIURL, stripeSecretKey, stripeWebhookSecret)
+	EventBus := realtime.NewMemoryBus()
+	DomainEvents := events.NewBus()
+	JobRunner := jobs.NewRunner(JobRepository)
+
+	EntitlementService := entitlementService.NewEntitlementService(EntitlementRepository, UserRepository, DeckRepository, WordSetRepository)
+	PaymentService := paymentService.NewPaymentService(PaymentRepository, UserRepository, StripeProvider, EntitlementService, paymentPlans, paymentSuccessURL, paymentCancelURL, db)
+	UserService := userService.NewUserService(UserRepository, WordSetRepository, ScheduleRepository, DeckRepository, CardRepository, EntitlementService, PaymentService, OutboxRepository, Mailer, appURL, jwtKeys, db)
+	WordSetService := wordSetService.NewWordSetService(WordSetRepository, CardRepository, EntitlementService, OutboxRepository, EventBus, db)
+	DeckService := deckService.NewDeckService(DeckRepository, ScheduleRepository, CardRepository, WordSetRepository, EntitlementService, OutboxRepository, EventBus, db)
+	CardService := cardService.NewCardService(CardRepository, WordSetRepository, EntitlementService, OutboxRepository, EventBus, db)
 	ScheduleService := scheduleService.NewScheduleService(ScheduleRepository, db)
+	TrashService := trashService.NewTrashService(DeckRepository, WordSetRepository, CardRepository, trashRetention)
+	OAuthService := oauthService.NewOAuthService(OAuthRepository, UserService, oauthProviders)
+	AdminService := adminService.NewAdminService(AdminRepository, UserRepository, WordSetRepository, EntitlementService, Mailer, appURL, db)
+	ModerationService := moderationService.NewModerationService(ModerationRepository, WordSetRepository, AdminRepository, AdminService, reportsHideThreshold, db)
+	ClassroomService := classroomService.NewClassroomService(ClassroomRepository, DeckRepository, CardRepository, WordSetRepository, ScheduleRepository, EntitlementService, OutboxRepository, db)
+	SyncService := syncService.NewSyncService(SyncRepository, DeckService, db)
+	WebhookService := webhookService.NewWebhookService(WebhookRepository)
+	NotificationService := notificationService.NewNotificationService(NotificationRepository)
+	PushService := pushService.NewPushService(PushRepository, vapid, pushAllowPrivate)
+
+	WebhookService.Subscribe(DomainEvents)
 
 	CardHandler := cardHandler.NewCardHandler(CardService)
 	DeckHandler := deckHandler.NewDeckHandler(DeckService)
 	WordSetHandler := wordSetHandler.NewWordSetHandler(WordSetService)
 	UserHandler := userHandler.NewUserHandler(UserService)
 	ScheduleHandler := scheduleHandler.NewScheduleHandler(ScheduleService)
+	PaymentHandler := paymentHandler.NewPaymentHandler(PaymentService)
+	TrashHandler := trashHandler.NewTrashHandler(TrashService)
+	OAuthHandler := oauthHandler.NewOAuthHandler(OAuthService, UserHandler, appURL)
+	AdminHandler := adminHandler.NewAdminHandler(AdminService)
+	ModerationHandler := moderationHandler.NewModerationHandler(ModerationService)
+	ClassroomHandler := classroomHandler.NewClassroomHandler(ClassroomService)
+	SyncHandler := syncHandler.NewSyncHandler(SyncService)
+	EventsHandler := realtimeHandler.NewEventsHandler(EventBus)
+	WebhookHandler := webhookHandler.NewWebhookHandler(WebhookService)
+	NotificationHandler := notificationHandler.NewNotificationHandler(NotificationService)
+	PushHandler := pushHandler.NewPushHandler(PushService)
+	JobsHandler := jobsHandler.NewJobsHandler(JobRunner)
+
+	authMiddleware := middleware.NewAuthMiddleware(jwtKeys)
+	streamAuthMiddleware := middleware.NewStreamAuthMiddleware(jwtKeys)
+	adminOnly := middleware.NewRoleMiddleware(UserRepository, models.RoleAdmin)
+	moderatorOnly := middleware.NewRoleMiddleware(UserRepository, models.RoleAdmin, models.RoleModerator)
+
+	var rateLimitStore ratelimit.Store
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "postgres":
+		rateLimitStore = ratelimit.NewPostgresStore(db)
+	default:
+		rateLimitStore = ratelimit.NewMemoryStore()
+	}
 
-	authMiddleware := middleware.NewAuthMiddleware(jwtKey)
+	loginLimit := middleware.NewRateLimitMiddleware(rateLimitStore,
+		ratelimit.Limit{Name: "login-ip", Requests: 20, Window: time.Minute, KeyFunc: ratelimit.ByIP},
+		ratelimit.Limit{Name: "login-account", Requests: 10, Window: 15 * time.Minute, KeyFunc: ratelimit.ByEmail},
+	)
+	registerLimit := middleware.NewRateLimitMiddleware(rateLimitStore,
+		ratelimit.Limit{Name: "register-ip", Requests: 5, Window: time.Hour, KeyFunc: ratelimit.ByIP},
+	)
+	refreshLimit := middleware.NewRateLimitMiddleware(rateLimitStore,
+		ratelimit.Limit{Name: "refresh-ip", Requests: 60, Window: time.Minute, KeyFunc: ratelimit.ByIP},
+		ratelimit.Limit{Name: "refresh-session", Requests: 10, Window: time.Minute, KeyFunc: ratelimit.ByCookie("refresh_token")},
+	)
+
+	reauthLimit := middleware.NewRateLimitMiddleware(rateLimitStore,
+		ratelimit.Limit{Name: "reauth-ip", Requests: 20, Window: time.Minute, KeyFunc: ratelimit.ByIP},
+		ratelimit.Limit{Name: "reauth-user", Requests: 10, Window: 15 * time.Minute, KeyFunc: ratelimit.ByContext(middleware.UserIDKey)},
+	)
+
+	idempotent := middleware.NewIdempotencyMiddleware(idempotency.NewPostgresStore(db), idempotencyTTL)
 
 	r := chi.NewRouter()
 
@@ -95,38 +363,78 @@ func main() {
 
 		// MUST include "Set-Cookie" if you want to see it? (Actually not strictly required for credentials, but good practice)
 		// Authorization is required for Bearer token
-		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders: []string{"Link"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Reauth-Token", "If-Match", "If-None-Match", "Idempotency-Key"},
+		ExposedHeaders: []string{"Link", "ETag", "Idempotent-Replayed"},
 		// CRITICAL for cookies:
 		AllowCredentials: true,
 
 		MaxAge: 300,
 	}))
 
+	if os.Getenv("TRUST_PROXY") == "true" {
+		r.Use(chiMD.RealIP)
+	}
+	r.Use(middleware.NewRedactQueryMiddleware("access_token"))
 	r.Use(chiMD.Logger)
 	// CORS middleware должен быть подключен до этого
 
+	r.Get("/.well-known/jwks.json", jwtKeys.HDJWKS)
+
 	// ГРУППА /api
 	r.Route("/api", func(r chi.Router) {
 
-		r.Post("/register", UserHandler.HandlerRegisterUser)
-		r.Post("/login", UserHandler.HandlerLoginUser)
-		r.Post("/auth/refresh", UserHandler.Refresh)
+		r.With(registerLimit).Post("/register", UserHandler.HandlerRegisterUser)
+		r.With(loginLimit).Post("/login", UserHandler.HandlerLoginUser)
+		r.With(loginLimit).Post("/login/2fa", UserHandler.HDLoginTwoFactor)
+		r.With(refreshLimit).Post("/auth/refresh", UserHandler.Refresh)
 		r.Post("/logout", UserHandler.HDLogoutUser)
+		r.Post("/payment/webhook", PaymentHandler.HDWebhook)
+		r.Post("/auth/verify-email", UserHandler.HDVerifyEmail)
+		r.With(loginLimit).Post("/auth/password/forgot", UserHandler.HDForgotPassword)
+		r.Post("/auth/password/reset", UserHandler.HDResetPassword)
+		r.Post("/auth/email/confirm", UserHandler.HDConfirmEmailChange)
+		r.Get("/auth/oauth/providers", OAuthHandler.HDGetProviders)
+		r.With(streamAuthMiddleware).Get("/events", EventsHandler.HDStream)
+		r.Get("/auth/oauth/{provider}", OAuthHandler.HDLogin)
+		r.Get("/auth/oauth/{provider}/callback", OAuthHandler.HDCallback)
 
 		r.Group(func(r chi.Router) {
 			r.Use(authMiddleware)
 
 			r.Get("/profile", UserHandler.HandlerGetProfile)
-			r.Post("/payment/mock", UserHandler.HDMockPayment)
-
-			r.Post("/decks", DeckHandler.HDCreateDeck)
+			r.Post("/auth/verify-email/resend", UserHandler.HDResendVerification)
+			r.Get("/profile/identities", UserHandler.HDGetIdentities)
+			r.Post("/profile/identities/{provider}", OAuthHandler.HDLink)
+			r.Delete("/profile/identities/{provider}", UserHandler.HDUnlinkIdentity)
+			r.With(reauthLimit).Post("/auth/reauth", UserHandler.HDReauthenticate)
+			r.Post("/account/email", UserHandler.HDChangeEmail)
+			r.Put("/account/login", UserHandler.HDChangeLogin)
+			r.Put("/account/password", UserHandler.HDChangePassword)
+			r.Delete("/account", UserHandler.HDDeleteAccount)
+			r.Get("/2fa", UserHandler.HDGetTwoFactor)
+			r.Post("/2fa/setup", UserHandler.HDSetupTwoFactor)
+			r.Post("/2fa/enable", UserHandler.HDEnableTwoFactor)
+			r.Post("/2fa/disable", UserHandler.HDDisableTwoFactor)
+			r.Post("/2fa/recovery-codes", UserHandler.HDRegenerateRecoveryCodes)
+			r.Get("/sessions", UserHandler.HDGetSessions)
+			r.Delete("/sessions", UserHandler.HDRevokeAllSessions)
+			r.Delete("/sessions/{sessionID}", UserHandler.HDRevokeSession)
+			r.With(idempotent).Post("/payment/checkout", PaymentHandler.HDCreateCheckout)
+			r.With(idempotent).Post("/payment/subscription/cancel", PaymentHandler.HDCancelSubscription)
+			r.Get("/payment/history", PaymentHandler.HDGetPayments)
+
+			r.With(idempotent).Post("/decks", DeckHandler.HDCreateDeck)
 			r.Get("/decks", DeckHandler.HDGetDecks)
 			r.Get("/decks/{deckID}", DeckHandler.HDGetDeckByID)
-			r.Post("/decks/{deckID}/review", DeckHandler.HDReview)
+			r.With(idempotent).Post("/decks/{deckID}/review", DeckHandler.HDReview)
 			r.Put("/decks/{deckID}", DeckHandler.HDUpdateDeck)
 			r.Delete("/decks/{deckID}", DeckHandler.HDDeleteDeck)
 			r.Delete("/decks/{deckID}/histories", DeckHandler.HDRestart)
+			r.Post("/decks/{deckID}/duplicate", DeckHandler.HDDuplicateDeck)
+			r.Post("/decks/{deckID}/merge", DeckHandler.HDMergeDecks)
+			r.Post("/decks/{deckID}/split", DeckHandler.HDSplitDeck)
+			r.Post("/decks/{deckID}/cards", DeckHandler.HDAddCardsToDeck)
+			r.Delete("/decks/{deckID}/cards", DeckHandler.HDRemoveCardsFromDeck)
 
 			r.Post("/cards", CardHandler.HDCreateCard)
 			r.Delete("/cards/{cardID}", CardHandler.HDDeleteCard)
@@ -135,26 +443,136 @@ func main() {
 
 			r.Post("/word-sets", WordSetHandler.HDCreateWordSet)
 			r.Get("/word-sets", WordSetHandler.HDGetAllWordSet)
+			r.Get("/word-sets/invitations", WordSetHandler.HDGetInvitations)
 			r.Get("/word-sets/{wordSetID}", WordSetHandler.HDGetWordSetById)
 			r.Put("/word-sets/{wordSetID}", WordSetHandler.HDUpdateWordSet)
 			r.Delete("/word-sets/{wordSetID}", WordSetHandler.HDDeleteWordSet)
 			r.Post("/word-sets/{wordSetID}/copy", WordSetHandler.HDCopyWordSet)
-			r.Post("/word-sets/{wordSetID}/cards/batch", WordSetHandler.HDCreateBatchCards)
+			r.With(idempotent).Post("/word-sets/{wordSetID}/cards/batch", WordSetHandler.HDCreateBatchCards)
+			r.Post("/word-sets/{wordSetID}/report", ModerationHandler.HDReportWordSet)
+			r.Post("/word-sets/{wordSetID}/cards/{cardID}/report", ModerationHandler.HDReportCard)
+			r.Get("/word-sets/{wordSetID}/members", WordSetHandler.HDGetMembers)
+			r.Post("/word-sets/{wordSetID}/members", WordSetHandler.HDInviteMember)
+			r.Put("/word-sets/{wordSetID}/members/{userID}", WordSetHandler.HDUpdateMember)
+			r.Delete("/word-sets/{wordSetID}/members/{userID}", WordSetHandler.HDRemoveMember)
+			r.Post("/word-sets/{wordSetID}/invitation/accept", WordSetHandler.HDAcceptInvitation)
+			r.Get("/word-sets/{wordSetID}/activity", WordSetHandler.HDGetActivity)
 
 			r.Post("/schedules", ScheduleHandler.HDCreateSchedule)
 			r.Get("/schedules", ScheduleHandler.HDGetAllSchedules)
 			r.Delete("/schedules/{scheduleID}", ScheduleHandler.HDDeleteSchedule)
 			r.Put("/schedules/{scheduleID}", ScheduleHandler.HDUpdateSchedule)
+
+			r.Post("/groups", ClassroomHandler.HDCreateGroup)
+			r.Get("/groups", ClassroomHandler.HDGetGroups)
+			r.Post("/groups/join", ClassroomHandler.HDJoinGroup)
+			r.Get("/groups/{groupID}", ClassroomHandler.HDGetGroup)
+			r.Delete("/groups/{groupID}", ClassroomHandler.HDDeleteGroup)
+			r.Post("/groups/{groupID}/join-code", ClassroomHandler.HDRegenerateJoinCode)
+			r.Put("/groups/{groupID}/members/{userID}", ClassroomHandler.HDUpdateMember)
+			r.Delete("/groups/{groupID}/members/{userID}", ClassroomHandler.HDRemoveMember)
+			r.Post("/groups/{groupID}/assignments", ClassroomHandler.HDCreateAssignment)
+			r.Get("/groups/{groupID}/assignments", ClassroomHandler.HDGetAssignments)
+			r.Get("/groups/{groupID}/assignments/{assignmentID}/progress", ClassroomHandler.HDGetAssignmentProgress)
+			r.Get("/groups/{groupID}/assignments/{assignmentID}/students/{userID}", ClassroomHandler.HDGetStudentProgress)
+
+			r.With(idempotent).Post("/sync", SyncHandler.HDSync)
+
+			r.Post("/webhooks", WebhookHandler.HDCreateWebhook)
+			r.Get("/webhooks", WebhookHandler.HDGetWebhooks)
+			r.Put("/webhooks/{webhookID}", WebhookHandler.HDUpdateWebhook)
+			r.Delete("/webhooks/{webhookID}", WebhookHandler.HDDeleteWebhook)
+			r.Get("/webhooks/{webhookID}/deliveries", WebhookHandler.HDGetDeliveries)
+
+			r.Get("/notifications", NotificationHandler.HDGetNotifications)
+			r.Get("/notifications/preferences", NotificationHandler.HDGetPreferences)
+			r.Put("/notifications/preferences", NotificationHandler.HDUpdatePreferences)
+
+			r.Get("/push/public-key", PushHandler.HDGetPublicKey)
+			r.Post("/push/subscriptions", PushHandler.HDSubscribe)
+			r.Get("/push/subscriptions", PushHandler.HDGetSubscriptions)
+			r.Delete("/push/subscriptions/{subscriptionID}", PushHandler.HDUnsubscribe)
+
+			r.Get("/trash", TrashHandler.HDGetTrash)
+			r.Post("/trash/decks/{deckID}/restore", TrashHandler.HDRestoreDeck)
+			r.Post("/trash/word-sets/{wordSetID}/restore", TrashHandler.HDRestoreWordSet)
+			r.Post("/trash/cards/{cardID}/restore", TrashHandler.HDRestoreCard)
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Group(func(r chi.Router) {
+					r.Use(moderatorOnly)
+
+					r.Post("/word-sets/{wordSetID}/unpublish", AdminHandler.HDUnpublishWordSet)
+					r.Get("/reports", ModerationHandler.HDGetReports)
+					r.Post("/reports/{reportID}/dismiss", ModerationHandler.HDDismissReport)
+					r.Post("/reports/{reportID}/action", ModerationHandler.HDActionReport)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(adminOnly)
+
+					r.Get("/users", AdminHandler.HDListUsers)
+					r.Get("/users/{userID}", AdminHandler.HDGetUser)
+					r.Put("/users/{userID}/role", AdminHandler.HDSetRole)
+					r.Post("/users/{userID}/premium", AdminHandler.HDGrantPremium)
+					r.Delete("/users/{userID}/premium", AdminHandler.HDRevokePremium)
+					r.Get("/audit-log", AdminHandler.HDGetAuditLog)
+					r.Get("/jobs", JobsHandler.HDGetJobs)
+					r.Get("/jobs/{jobName}/runs", JobsHandler.HDGetRuns)
+				})
+			})
 		})
 	})
 
-	cleaner := workers.NewCleaner(db)
-	cleaner.StartClean()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Println("Starting server on :" + httpServer)
-	if err := http.ListenAndServe(":"+httpServer, r); err != nil {
-		log.Fatalf("could not start server %v", err)
+	cleaner := workers.NewCleaner(db, trashRetention)
+	JobRunner.Register(cleaner.Jobs()...)
+	JobRunner.Start(ctx)
+
+	dispatcher := workers.NewOutboxDispatcher(db, OutboxRepository, DomainEvents)
+	dispatcher.StartDispatch(ctx)
+
+	// Local receivers like cmd/mockwebhook need WEBHOOK_ALLOW_PRIVATE_URLS=true
+	webhookSender := webhook.NewHTTPSender(os.Getenv("WEBHOOK_ALLOW_PRIVATE_URLS") == "true")
+	deliverer := workers.NewWebhookDeliverer(WebhookRepository, webhookSender)
+	deliverer.StartDeliver(ctx)
+
+	reminderChannels := []notification.Channel{notificationChannel.NewEmailChannel(Mailer, appURL)}
+	if vapid != nil {
+		pushSender := webpush.NewSender(vapid, pushAllowPrivate)
+		reminderChannels = append(reminderChannels, notificationChannel.NewPushChannel(PushRepository, pushSender))
+	}
+	reminder := workers.NewReviewReminder(NotificationRepository, appURL, reminderChannels...)
+	reminder.StartRemind(ctx)
+
+	server := &http.Server{Addr: ":" + httpServer, Handler: r}
+	server.RegisterOnShutdown(EventsHandler.Shutdown)
+
+	go func() {
+		log.Println("Starting server on :" + httpServer)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("could not start server %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Graceful shutdown error: %v", err)
+		server.Close()
 	}
+
+	// Running jobs see the cancelled ctx and record themselves as cancelled
+	JobRunner.Wait()
+	dispatcher.Wait()
+	deliverer.Wait()
+	reminder.Wait()
 }
 
 func ConnectToDB(dsn string) *gorm.DB {
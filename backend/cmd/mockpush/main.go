@@ -0,0 +1,127 @@
+// Command mockpush is a local stand-in for a browser push service. It plays
+// the browser too: on start it prints a subscription to register, then
+// checks the VAPID header of every push, decrypts it and logs the payload:
+//
+//	go run ./cmd/mockpush
+//	WEBPUSH_ALLOW_PRIVATE_ENDPOINTS=true VAPID_PRIVATE_KEY_FILE=vapid.pem VAPID_SUBJECT=mailto:dev@example.com go run ./cmd/main.go
+//
+// POST the printed JSON to /api/push/subscriptions. Pushes to any other
+// endpoint of the mock, or to all of them with MOCK_PUSH_GONE=true, get 410
+// Gone, so the server should delete the subscription.
+package main
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"dimplom_harmonic/internal/webpush"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func main() {
+	addr := os.Getenv("MOCK_PUSH_ADDR")
+	if addr == "" {
+		addr = ":9092"
+	}
+	baseURL := os.Getenv("MOCK_PUSH_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:9092"
+	}
+	gone := os.Getenv("MOCK_PUSH_GONE") == "true"
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authSecret := make([]byte, 16)
+	id := make([]byte, 8)
+	if _, err := rand.Read(authSecret); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := rand.Read(id); err != nil {
+		log.Fatal(err)
+	}
+
+	var subscription webpush.SubscribeDTO
+	subscription.Endpoint = baseURL + "/push/" + base64.RawURLEncoding.EncodeToString(id)
+	subscription.Keys.P256dh = base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	subscription.Keys.Auth = base64.RawURLEncoding.EncodeToString(authSecret)
+
+	http.HandleFunc("POST /push/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := checkVAPID(r, baseURL); err != nil {
+			log.Printf("Rejected push: %v", err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if gone || r.PathValue("id") != base64.RawURLEncoding.EncodeToString(id) {
+			log.Printf("Push to %s: 410 Gone", r.URL.Path)
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payload, err := webpush.Decrypt(body, key, authSecret)
+		if err != nil {
+			log.Printf("Can't decrypt push: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("Push (TTL %s, topic %q): %s", r.Header.Get("TTL"), r.Header.Get("Topic"), payload)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	printed, _ := json.Marshal(subscription)
+	log.Println("Mock push service on " + addr + ", subscription: " + string(printed))
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+
+// checkVAPID verifies the "vapid t=<jwt>, k=<key>" header: the token must be
+// signed by k and meant for this push service.
+func checkVAPID(r *http.Request, audience string) error {
+	params, ok := strings.CutPrefix(r.Header.Get("Authorization"), "vapid ")
+	if !ok {
+		return errors.New("no VAPID authorization")
+	}
+
+	var token, publicKey string
+	for _, part := range strings.Split(params, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "t":
+			token = value
+		case "k":
+			publicKey = value
+		}
+	}
+
+	keyBytes, err := base64.RawURLEncoding.DecodeString(publicKey)
+	if err != nil {
+		return err
+	}
+	key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), keyBytes)
+	if err != nil {
+		return err
+	}
+
+	_, err = jwt.Parse(token, func(*jwt.Token) (any, error) { return key, nil },
+		jwt.WithValidMethods([]string{"ES256"}),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	return err
+}
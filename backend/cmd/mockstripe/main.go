@@ -0,0 +1,416 @@
+// Command mockstripe is a minimal Stripe stand-in for local development. It
+// serves the API calls the payment provider makes, shows a checkout page with
+// Pay/Cancel buttons and sends signed webhooks back to the server:
+//
+//	STRIPE_SECRET_KEY=sk_test_dev STRIPE_WEBHOOK_SECRET=whsec_dev go run ./cmd/mockstripe
+//	STRIPE_API_URL=http://localhost:12111 STRIPE_SECRET_KEY=sk_test_dev STRIPE_WEBHOOK_SECRET=whsec_dev go run ./cmd/main.go
+//
+// Subscriptions don't renew by themselves. Trigger the events by hand:
+//
+//	curl -X POST localhost:12111/mock/subscriptions/<id>/renew  # invoice.paid
+//	curl -X POST localhost:12111/mock/subscriptions/<id>/fail   # invoice.payment_failed
+//	curl -X POST localhost:12111/mock/subscriptions/<id>/end    # customer.subscription.deleted
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type checkoutSession struct {
+	Id                string
+	Mode              string
+	Price             string
+	ClientReferenceId string
+	SuccessURL        string
+	CancelURL         string
+	Metadata          map[string]string
+	SubMetadata       map[string]string
+	Paid              bool
+}
+
+type subscription struct {
+	Id                string
+	Metadata          map[string]string
+	Months            int
+	CurrentPeriodEnd  time.Time
+	CancelAtPeriodEnd bool
+}
+
+type server struct {
+	url           string
+	secretKey     string
+	webhookSecret string
+	webhookURL    string
+	amount        int64
+	currency      string
+	client        *http.Client
+
+	mu            sync.Mutex
+	sessions      map[string]*checkoutSession
+	subscriptions map[string]*subscription
+}
+
+var checkoutPage = template.Must(template.New("checkout").Parse(`<!doctype html>
+<title>Mock Stripe checkout</title>
+<p>{{.Mode}} {{.Price}} for user {{.ClientReferenceId}}</p>
+<form method="post" action="/checkout/{{.Id}}/pay"><button>Pay</button></form>
+<form method="post" action="/checkout/{{.Id}}/cancel"><button>Cancel</button></form>`))
+
+func main() {
+	addr := os.Getenv("MOCK_STRIPE_ADDR")
+	if addr == "" {
+		addr = ":12111"
+	}
+	publicURL := os.Getenv("MOCK_STRIPE_URL")
+	if publicURL == "" {
+		publicURL = "http://localhost:12111"
+	}
+	webhookURL := os.Getenv("MOCK_STRIPE_WEBHOOK_URL")
+	if webhookURL == "" {
+		webhookURL = "http://localhost:8080/api/payment/webhook"
+	}
+
+	var amount int64 = 499
+	if v := os.Getenv("MOCK_STRIPE_AMOUNT"); v != "" {
+		var err error
+		amount, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Fatal("MOCK_STRIPE_AMOUNT must be an amount in cents")
+		}
+	}
+
+	s := &server{
+		url:           strings.TrimRight(publicURL, "/"),
+		secretKey:     os.Getenv("STRIPE_SECRET_KEY"),
+		webhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+		webhookURL:    webhookURL,
+		amount:        amount,
+		currency:      "usd",
+		client:        &http.Client{Timeout: 15 * time.Second},
+		sessions:      make(map[string]*checkoutSession),
+		subscriptions: make(map[string]*subscription),
+	}
+	if s.webhookSecret == "" {
+		log.Fatal("STRIPE_WEBHOOK_SECRET is required to sign webhooks")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/checkout/sessions", s.requireKey(s.createSession))
+	mux.HandleFunc("POST /v1/subscriptions/{id}", s.requireKey(s.updateSubscription))
+	mux.HandleFunc("GET /checkout/{id}", s.checkoutForm)
+	mux.HandleFunc("POST /checkout/{id}/pay", s.pay)
+	mux.HandleFunc("POST /checkout/{id}/cancel", s.cancel)
+	mux.HandleFunc("POST /mock/subscriptions/{id}/{action}", s.trigger)
+
+	log.Println("Mock Stripe " + s.url + " on " + addr + ", webhooks to " + webhookURL)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+func newId(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"error": map[string]string{"message": message}})
+}
+
+// requireKey checks the secret key like Stripe does, when one is configured.
+func (s *server) requireKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.secretKey != "" && r.Header.Get("Authorization") != "Bearer "+s.secretKey {
+			writeAPIError(w, http.StatusUnauthorized, "Invalid API Key provided")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// prefixed collects form fields like metadata[user_id] into a map.
+func prefixed(form map[string][]string, prefix string) map[string]string {
+	result := make(map[string]string)
+	for key, values := range form {
+		if name, ok := strings.CutPrefix(key, prefix+"["); ok && strings.HasSuffix(name, "]") {
+			result[strings.TrimSuffix(name, "]")] = values[0]
+		}
+	}
+	return result
+}
+
+func (s *server) createSession(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mode := r.Form.Get("mode")
+	if mode != "payment" && mode != "subscription" {
+		writeAPIError(w, http.StatusBadRequest, "Invalid mode: "+mode)
+		return
+	}
+	if r.Form.Get("line_items[0][price]") == "" || r.Form.Get("success_url") == "" {
+		writeAPIError(w, http.StatusBadRequest, "Missing required param: line_items[0][price] or success_url")
+		return
+	}
+
+	session := &checkoutSession{
+		Id:                newId("cs_test_"),
+		Mode:              mode,
+		Price:             r.Form.Get("line_items[0][price]"),
+		ClientReferenceId: r.Form.Get("client_reference_id"),
+		SuccessURL:        r.Form.Get("success_url"),
+		CancelURL:         r.Form.Get("cancel_url"),
+		Metadata:          prefixed(r.Form, "metadata"),
+		SubMetadata:       prefixed(r.Form, "subscription_data[metadata]"),
+	}
+
+	s.mu.Lock()
+	s.sessions[session.Id] = session
+	s.mu.Unlock()
+
+	log.Printf("Checkout session %s (%s, %s)", session.Id, mode, session.Price)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":     session.Id,
+		"object": "checkout.session",
+		"mode":   mode,
+		"url":    s.url + "/checkout/" + session.Id,
+	})
+}
+
+func (s *server) getSession(r *http.Request) *checkoutSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[r.PathValue("id")]
+}
+
+func (s *server) checkoutForm(w http.ResponseWriter, r *http.Request) {
+	session := s.getSession(r)
+	if session == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	checkoutPage.Execute(w, session)
+}
+
+func (s *server) cancel(w http.ResponseWriter, r *http.Request) {
+	session := s.getSession(r)
+	if session == nil {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, session.CancelURL, http.StatusSeeOther)
+}
+
+// pay completes the checkout the way Stripe orders the events: the session
+// first, then the first invoice of a subscription.
+func (s *server) pay(w http.ResponseWriter, r *http.Request) {
+	session := s.getSession(r)
+	if session == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	alreadyPaid := session.Paid
+	session.Paid = true
+	s.mu.Unlock()
+	if alreadyPaid {
+		http.Redirect(w, r, session.SuccessURL, http.StatusSeeOther)
+		return
+	}
+
+	var sub *subscription
+	if session.Mode == "subscription" {
+		months := 1
+		if session.SubMetadata["plan_id"] == "year" {
+			months = 12
+		}
+		sub = &subscription{
+			Id:               newId("sub_"),
+			Metadata:         session.SubMetadata,
+			Months:           months,
+			CurrentPeriodEnd: time.Now().AddDate(0, months, 0),
+		}
+		s.mu.Lock()
+		s.subscriptions[sub.Id] = sub
+		s.mu.Unlock()
+	}
+
+	object := map[string]any{
+		"id":                  session.Id,
+		"object":              "checkout.session",
+		"mode":                session.Mode,
+		"client_reference_id": session.ClientReferenceId,
+		"amount_total":        s.amount,
+		"currency":            s.currency,
+		"payment_status":      "paid",
+		"metadata":            session.Metadata,
+	}
+	if sub != nil {
+		object["subscription"] = sub.Id
+	}
+
+	if err := s.send("checkout.session.completed", object); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if sub != nil {
+		if err := s.send("invoice.paid", s.invoice(sub, "subscription_create", s.amount, 0)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		log.Printf("Subscription %s created", sub.Id)
+	}
+
+	http.Redirect(w, r, session.SuccessURL, http.StatusSeeOther)
+}
+
+func (s *server) invoice(sub *subscription, billingReason string, paid, due int64) map[string]any {
+	return map[string]any{
+		"id":             newId("in_"),
+		"object":         "invoice",
+		"subscription":   sub.Id,
+		"billing_reason": billingReason,
+		"amount_paid":    paid,
+		"amount_due":     due,
+		"currency":       s.currency,
+		"subscription_details": map[string]any{
+			"metadata": sub.Metadata,
+		},
+		"lines": map[string]any{
+			"data": []map[string]any{{
+				"period": map[string]int64{"end": sub.CurrentPeriodEnd.Unix()},
+			}},
+		},
+	}
+}
+
+func (s *server) updateSubscription(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.subscriptions[r.PathValue("id")]
+	if sub == nil {
+		writeAPIError(w, http.StatusNotFound, "No such subscription: "+r.PathValue("id"))
+		return
+	}
+	if v := r.Form.Get("cancel_at_period_end"); v != "" {
+		sub.CancelAtPeriodEnd = v == "true"
+		log.Printf("Subscription %s cancel_at_period_end=%v", sub.Id, sub.CancelAtPeriodEnd)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":                   sub.Id,
+		"object":               "subscription",
+		"cancel_at_period_end": sub.CancelAtPeriodEnd,
+		"current_period_end":   sub.CurrentPeriodEnd.Unix(),
+		"metadata":             sub.Metadata,
+	})
+}
+
+// trigger sends the events Stripe would send later in a subscription's life.
+func (s *server) trigger(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	sub := s.subscriptions[r.PathValue("id")]
+	if sub != nil && r.PathValue("action") == "renew" {
+		sub.CurrentPeriodEnd = sub.CurrentPeriodEnd.AddDate(0, sub.Months, 0)
+	}
+	s.mu.Unlock()
+
+	if sub == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var err error
+	switch r.PathValue("action") {
+	case "renew":
+		err = s.send("invoice.paid", s.invoice(sub, "subscription_cycle", s.amount, 0))
+	case "fail":
+		err = s.send("invoice.payment_failed", s.invoice(sub, "subscription_cycle", 0, s.amount))
+	case "end":
+		err = s.send("customer.subscription.deleted", map[string]any{
+			"id":                 sub.Id,
+			"object":             "subscription",
+			"status":             "canceled",
+			"current_period_end": sub.CurrentPeriodEnd.Unix(),
+			"metadata":           sub.Metadata,
+		})
+	default:
+		http.Error(w, "action must be renew, fail or end", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// send posts an event signed like the Stripe-Signature header:
+// t=<unix time>,v1=<hex HMAC-SHA256 of "t.payload">.
+func (s *server) send(eventType string, object map[string]any) error {
+	payload, err := json.Marshal(map[string]any{
+		"id":      newId("evt_"),
+		"object":  "event",
+		"type":    eventType,
+		"created": time.Now().Unix(),
+		"data":    map[string]any{"object": object},
+	})
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+
+	req, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Stripe-Signature", "t="+timestamp+",v1="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", eventType, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	log.Printf("Webhook %s: %s %s", eventType, resp.Status, body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", eventType, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Command mockwebhook is a local webhook receiver for development. It checks
+// the signature of every delivery and logs it:
+//
+//	MOCK_WEBHOOK_SECRET=<webhook secret> go run ./cmd/mockwebhook
+//	WEBHOOK_ALLOW_PRIVATE_URLS=true go run ./cmd/main.go
+//
+// Register http://localhost:9091/hook as the webhook URL. Set
+// MOCK_WEBHOOK_STATUS=500 to answer with an error and watch the retries and
+// the automatic disabling.
+package main
+
+import (
+	"dimplom_harmonic/internal/webhook"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+func main() {
+	addr := os.Getenv("MOCK_WEBHOOK_ADDR")
+	if addr == "" {
+		addr = ":9091"
+	}
+	secret := os.Getenv("MOCK_WEBHOOK_SECRET")
+
+	status := http.StatusOK
+	if v := os.Getenv("MOCK_WEBHOOK_STATUS"); v != "" {
+		var err error
+		status, err = strconv.Atoi(v)
+		if err != nil {
+			log.Fatal("MOCK_WEBHOOK_STATUS must be an HTTP status code")
+		}
+	}
+
+	http.HandleFunc("POST /hook", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		event := r.Header.Get(webhook.EventHeader)
+		delivery := r.Header.Get(webhook.DeliveryHeader)
+
+		if secret != "" {
+			err := webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), body, 5*time.Minute)
+			if err != nil {
+				log.Printf("Delivery %s (%s): bad signature", delivery, event)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		log.Printf("Delivery %s (%s): %s", delivery, event, body)
+		w.WriteHeader(status)
+	})
+
+	log.Println("Mock webhook receiver on " + addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
@@ -0,0 +1,5 @@
+ALTER TABLE cards DROP COLUMN deleted_at;
+
+ALTER TABLE word_sets DROP COLUMN deleted_at;
+
+ALTER TABLE decks DROP COLUMN deleted_at;
@@ -0,0 +1,11 @@
+ALTER TABLE decks ADD COLUMN deleted_at TIMESTAMPTZ;
+
+ALTER TABLE word_sets ADD COLUMN deleted_at TIMESTAMPTZ;
+
+ALTER TABLE cards ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_decks_deleted_at ON decks(deleted_at);
+
+CREATE INDEX idx_word_sets_deleted_at ON word_sets(deleted_at);
+
+CREATE INDEX idx_cards_deleted_at ON cards(deleted_at);
@@ -0,0 +1,5 @@
+DROP TABLE payment_events;
+
+DROP TABLE subscriptions;
+
+DROP TABLE payments;
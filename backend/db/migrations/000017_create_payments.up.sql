@@ -0,0 +1,42 @@
+CREATE TABLE payments (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    external_id VARCHAR(255) UNIQUE NOT NULL,
+    subscription_id VARCHAR(255),
+    plan_id VARCHAR(32) NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(8),
+    status VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    paid_at TIMESTAMPTZ,
+
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    external_id VARCHAR(255) UNIQUE NOT NULL,
+    plan_id VARCHAR(32) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    current_period_end TIMESTAMPTZ,
+    canceled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE payment_events (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    event_id VARCHAR(255) UNIQUE NOT NULL,
+    "type" VARCHAR(64) NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_payments_user_id ON payments(user_id);
+
+CREATE INDEX idx_subscriptions_user_id ON subscriptions(user_id);
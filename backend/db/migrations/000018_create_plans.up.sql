@@ -0,0 +1,13 @@
+CREATE TABLE plans (
+    id VARCHAR(32) PRIMARY KEY,
+    "name" VARCHAR(64) NOT NULL,
+    decks_per_day INT,
+    cards_per_deck INT,
+    word_sets INT,
+    media_storage_mb INT
+);
+
+-- NULL means unlimited
+INSERT INTO plans (id, "name", decks_per_day, cards_per_deck, word_sets, media_storage_mb) VALUES
+    ('free', 'Free', 1, 7, NULL, 100),
+    ('premium', 'Premium', NULL, NULL, NULL, 5120);
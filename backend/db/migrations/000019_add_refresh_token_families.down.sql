@@ -0,0 +1,17 @@
+DROP INDEX idx_refresh_tokens_user_id;
+
+DROP INDEX idx_refresh_tokens_family_id;
+
+DROP INDEX idx_refresh_tokens_token;
+
+ALTER TABLE refresh_tokens DROP COLUMN revoked_at;
+
+ALTER TABLE refresh_tokens DROP COLUMN used_at;
+
+ALTER TABLE refresh_tokens DROP COLUMN created_at;
+
+ALTER TABLE refresh_tokens DROP COLUMN ip;
+
+ALTER TABLE refresh_tokens DROP COLUMN user_agent;
+
+ALTER TABLE refresh_tokens DROP COLUMN family_id;
@@ -0,0 +1,21 @@
+ALTER TABLE refresh_tokens ADD COLUMN family_id VARCHAR(64);
+
+UPDATE refresh_tokens SET family_id = md5(random()::text || id::text);
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE refresh_tokens ADD COLUMN ip VARCHAR(64) NOT NULL DEFAULT '';
+
+ALTER TABLE refresh_tokens ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE refresh_tokens ADD COLUMN used_at TIMESTAMPTZ;
+
+ALTER TABLE refresh_tokens ADD COLUMN revoked_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX idx_refresh_tokens_token ON refresh_tokens(token);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
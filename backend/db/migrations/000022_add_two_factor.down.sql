@@ -0,0 +1,9 @@
+DROP TABLE recovery_codes;
+
+ALTER TABLE user_tokens DROP COLUMN attempts;
+
+ALTER TABLE users DROP COLUMN totp_last_step;
+
+ALTER TABLE users DROP COLUMN totp_enabled_at;
+
+ALTER TABLE users DROP COLUMN totp_secret;
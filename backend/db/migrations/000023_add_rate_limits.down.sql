@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN locked_until;
+
+ALTER TABLE users DROP COLUMN failed_logins;
+
+DROP TABLE rate_limits;
@@ -0,0 +1,9 @@
+CREATE UNLOGGED TABLE rate_limits (
+    key VARCHAR(255) PRIMARY KEY,
+    count INT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE users ADD COLUMN failed_logins INT NOT NULL DEFAULT 0;
+
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMPTZ;
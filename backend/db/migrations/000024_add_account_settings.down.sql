@@ -0,0 +1,9 @@
+DELETE FROM word_sets WHERE user_id IS NULL;
+
+ALTER TABLE word_sets ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE word_sets DROP CONSTRAINT fk_source_word_set;
+
+ALTER TABLE word_sets DROP COLUMN source_word_set_id;
+
+ALTER TABLE users DROP COLUMN pending_email;
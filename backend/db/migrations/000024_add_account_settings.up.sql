@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN pending_email VARCHAR(64) NOT NULL DEFAULT '';
+
+ALTER TABLE word_sets ADD COLUMN source_word_set_id INT;
+
+ALTER TABLE word_sets ADD CONSTRAINT fk_source_word_set FOREIGN KEY(source_word_set_id) REFERENCES word_sets(id) ON DELETE SET NULL;
+
+-- Public word sets of deleted accounts stay available without an owner.
+ALTER TABLE word_sets ALTER COLUMN user_id DROP NOT NULL;
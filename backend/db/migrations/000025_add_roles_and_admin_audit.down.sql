@@ -0,0 +1,3 @@
+DROP TABLE admin_audit_logs;
+
+ALTER TABLE users DROP COLUMN "role";
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN "role" VARCHAR(16) NOT NULL DEFAULT 'user';
+
+CREATE TABLE admin_audit_logs (
+    id SERIAL PRIMARY KEY,
+    admin_id INT,
+    "action" VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id INT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_admin FOREIGN KEY(admin_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_admin_audit_logs_created_at ON admin_audit_logs(created_at);
+
+CREATE INDEX idx_admin_audit_logs_target ON admin_audit_logs(target_type, target_id);
@@ -0,0 +1,3 @@
+DROP TABLE content_reports;
+
+ALTER TABLE word_sets DROP COLUMN hidden_at;
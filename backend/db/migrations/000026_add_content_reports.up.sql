@@ -0,0 +1,25 @@
+ALTER TABLE word_sets ADD COLUMN hidden_at TIMESTAMPTZ;
+
+CREATE TABLE content_reports (
+    id SERIAL PRIMARY KEY,
+    reporter_id INT,
+    target_type VARCHAR(16) NOT NULL,
+    target_id INT NOT NULL,
+    word_set_id INT NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    resolved_by INT,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_reporter FOREIGN KEY(reporter_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_resolved_by FOREIGN KEY(resolved_by) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_word_set FOREIGN KEY(word_set_id) REFERENCES word_sets(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_content_reports_open_reporter ON content_reports(reporter_id, target_type, target_id) WHERE status = 'open';
+
+CREATE INDEX idx_content_reports_status ON content_reports(status, created_at);
+
+CREATE INDEX idx_content_reports_word_set_id ON content_reports(word_set_id);
@@ -0,0 +1,5 @@
+ALTER TABLE cards DROP COLUMN version;
+
+DROP TABLE word_set_activities;
+
+DROP TABLE word_set_members;
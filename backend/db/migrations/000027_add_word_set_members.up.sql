@@ -0,0 +1,34 @@
+CREATE TABLE word_set_members (
+    id SERIAL PRIMARY KEY,
+    word_set_id INT NOT NULL,
+    user_id INT NOT NULL,
+    "role" VARCHAR(16) NOT NULL,
+    invited_by INT,
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_word_set FOREIGN KEY(word_set_id) REFERENCES word_sets(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_invited_by FOREIGN KEY(invited_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX idx_word_set_members_word_set_user ON word_set_members(word_set_id, user_id);
+
+CREATE INDEX idx_word_set_members_user_id ON word_set_members(user_id);
+
+CREATE TABLE word_set_activities (
+    id SERIAL PRIMARY KEY,
+    word_set_id INT NOT NULL,
+    user_id INT,
+    "action" VARCHAR(32) NOT NULL,
+    card_id INT,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_word_set FOREIGN KEY(word_set_id) REFERENCES word_sets(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_word_set_activities_word_set_created ON word_set_activities(word_set_id, created_at);
+
+ALTER TABLE cards ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,7 @@
+ALTER TABLE decks DROP COLUMN assignment_id;
+
+DROP TABLE group_assignments;
+
+DROP TABLE group_members;
+
+DROP TABLE "groups";
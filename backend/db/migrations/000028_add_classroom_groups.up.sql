@@ -0,0 +1,48 @@
+CREATE TABLE "groups" (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    owner_id INT NOT NULL,
+    join_code VARCHAR(16) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_owner FOREIGN KEY(owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_groups_join_code ON "groups"(join_code);
+
+CREATE TABLE group_members (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL,
+    user_id INT NOT NULL,
+    "role" VARCHAR(16) NOT NULL,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_group FOREIGN KEY(group_id) REFERENCES "groups"(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_group_members_group_user ON group_members(group_id, user_id);
+
+CREATE INDEX idx_group_members_user_id ON group_members(user_id);
+
+CREATE TABLE group_assignments (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL,
+    word_set_id INT,
+    schedule_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    assigned_by INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_group FOREIGN KEY(group_id) REFERENCES "groups"(id) ON DELETE CASCADE,
+    CONSTRAINT fk_word_set FOREIGN KEY(word_set_id) REFERENCES word_sets(id) ON DELETE SET NULL,
+    CONSTRAINT fk_assigned_by FOREIGN KEY(assigned_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_group_assignments_group_id ON group_assignments(group_id);
+
+ALTER TABLE decks ADD COLUMN assignment_id INT;
+
+ALTER TABLE decks ADD CONSTRAINT fk_assignment FOREIGN KEY(assignment_id) REFERENCES group_assignments(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_decks_assignment_id ON decks(assignment_id, user_id);
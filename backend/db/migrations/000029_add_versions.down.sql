@@ -0,0 +1,5 @@
+ALTER TABLE deck_schedules DROP COLUMN version;
+
+ALTER TABLE word_sets DROP COLUMN version;
+
+ALTER TABLE decks DROP COLUMN version;
@@ -0,0 +1,5 @@
+ALTER TABLE decks ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE word_sets ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE deck_schedules ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,31 @@
+DROP TRIGGER word_set_members_sync_deletion ON word_set_members;
+DROP TRIGGER deck_schedules_sync_deletion ON deck_schedules;
+DROP TRIGGER word_sets_sync_deletion ON word_sets;
+DROP TRIGGER decks_sync_deletion ON decks;
+DROP FUNCTION record_sync_membership_end();
+DROP FUNCTION record_sync_deletion();
+
+DROP TABLE sync_deletions;
+
+DROP TRIGGER set_to_card_link_unlink ON set_to_card_link;
+DROP TRIGGER deck_cards_unlink ON deck_cards;
+DROP FUNCTION touch_word_set_on_unlink();
+DROP FUNCTION touch_deck_on_unlink();
+
+DROP TRIGGER word_set_members_sync_xid ON word_set_members;
+DROP TRIGGER set_to_card_link_sync_xid ON set_to_card_link;
+DROP TRIGGER deck_cards_sync_xid ON deck_cards;
+DROP TRIGGER deck_schedules_sync_xid ON deck_schedules;
+DROP TRIGGER word_sets_sync_xid ON word_sets;
+DROP TRIGGER cards_sync_xid ON cards;
+DROP TRIGGER decks_sync_xid ON decks;
+
+ALTER TABLE word_set_members DROP COLUMN sync_xid;
+ALTER TABLE set_to_card_link DROP COLUMN sync_xid;
+ALTER TABLE deck_cards DROP COLUMN sync_xid;
+ALTER TABLE deck_schedules DROP COLUMN sync_xid;
+ALTER TABLE word_sets DROP COLUMN sync_xid;
+ALTER TABLE cards DROP COLUMN sync_xid;
+ALTER TABLE decks DROP COLUMN sync_xid;
+
+DROP FUNCTION set_sync_xid();
@@ -0,0 +1,92 @@
+-- Every synced row remembers the transaction that last changed it. A client's
+-- cursor is the oldest transaction still running when it last synced, so rows
+-- committed later are never skipped (some may be sent twice).
+CREATE FUNCTION set_sync_xid() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.sync_xid := pg_current_xact_id()::text::bigint;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE decks ADD COLUMN sync_xid BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE cards ADD COLUMN sync_xid BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE word_sets ADD COLUMN sync_xid BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE deck_schedules ADD COLUMN sync_xid BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE deck_cards ADD COLUMN sync_xid BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE set_to_card_link ADD COLUMN sync_xid BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE word_set_members ADD COLUMN sync_xid BIGINT NOT NULL DEFAULT 0;
+
+CREATE TRIGGER decks_sync_xid BEFORE INSERT OR UPDATE ON decks
+    FOR EACH ROW EXECUTE FUNCTION set_sync_xid();
+CREATE TRIGGER cards_sync_xid BEFORE INSERT OR UPDATE ON cards
+    FOR EACH ROW EXECUTE FUNCTION set_sync_xid();
+CREATE TRIGGER word_sets_sync_xid BEFORE INSERT OR UPDATE ON word_sets
+    FOR EACH ROW EXECUTE FUNCTION set_sync_xid();
+CREATE TRIGGER deck_schedules_sync_xid BEFORE INSERT OR UPDATE ON deck_schedules
+    FOR EACH ROW EXECUTE FUNCTION set_sync_xid();
+CREATE TRIGGER deck_cards_sync_xid BEFORE INSERT OR UPDATE ON deck_cards
+    FOR EACH ROW EXECUTE FUNCTION set_sync_xid();
+CREATE TRIGGER set_to_card_link_sync_xid BEFORE INSERT OR UPDATE ON set_to_card_link
+    FOR EACH ROW EXECUTE FUNCTION set_sync_xid();
+CREATE TRIGGER word_set_members_sync_xid BEFORE INSERT OR UPDATE ON word_set_members
+    FOR EACH ROW EXECUTE FUNCTION set_sync_xid();
+
+-- Removing a card from a deck or a set changes the parent's card list. The
+-- no-op update is stamped by the triggers above.
+CREATE FUNCTION touch_deck_on_unlink() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE decks SET sync_xid = sync_xid WHERE id = OLD.deck_id;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION touch_word_set_on_unlink() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE word_sets SET sync_xid = sync_xid WHERE id = OLD.word_set_id;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER deck_cards_unlink AFTER DELETE ON deck_cards
+    FOR EACH ROW EXECUTE FUNCTION touch_deck_on_unlink();
+CREATE TRIGGER set_to_card_link_unlink AFTER DELETE ON set_to_card_link
+    FOR EACH ROW EXECUTE FUNCTION touch_word_set_on_unlink();
+
+-- Rows that are gone for good, or no longer visible to a user, are reported
+-- from here.
+CREATE TABLE sync_deletions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    entity VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    sync_xid BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_sync_deletions_user_id ON sync_deletions(user_id, sync_xid);
+CREATE INDEX idx_sync_deletions_created_at ON sync_deletions(created_at);
+
+CREATE FUNCTION record_sync_deletion() RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.user_id IS NOT NULL THEN
+        INSERT INTO sync_deletions (user_id, entity, entity_id) VALUES (OLD.user_id, TG_ARGV[0], OLD.id);
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION record_sync_membership_end() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO sync_deletions (user_id, entity, entity_id) VALUES (OLD.user_id, 'word_set', OLD.word_set_id);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER decks_sync_deletion AFTER DELETE ON decks
+    FOR EACH ROW EXECUTE FUNCTION record_sync_deletion('deck');
+CREATE TRIGGER word_sets_sync_deletion AFTER DELETE ON word_sets
+    FOR EACH ROW EXECUTE FUNCTION record_sync_deletion('word_set');
+CREATE TRIGGER deck_schedules_sync_deletion AFTER DELETE ON deck_schedules
+    FOR EACH ROW EXECUTE FUNCTION record_sync_deletion('schedule');
+CREATE TRIGGER word_set_members_sync_deletion AFTER DELETE ON word_set_members
+    FOR EACH ROW EXECUTE FUNCTION record_sync_membership_end();
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_decks_due;
+DROP TABLE IF EXISTS sent_notifications;
+DROP TABLE IF EXISTS notification_preferences;
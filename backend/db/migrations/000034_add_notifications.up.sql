@@ -0,0 +1,38 @@
+CREATE TABLE notification_preferences (
+    user_id INT PRIMARY KEY,
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    push_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    quiet_start_minute SMALLINT,
+    quiet_end_minute SMALLINT,
+    digest_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    digest_minute SMALLINT NOT NULL DEFAULT 540,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- One row per channel and deck for reminders, per channel and local day for
+-- digests; the unique index keeps a reminder from going out twice.
+CREATE TABLE sent_notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    channel VARCHAR(16) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    deck_id INT,
+    due_at TIMESTAMPTZ NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'sent',
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_deck FOREIGN KEY(deck_id) REFERENCES decks(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_sent_notifications_dedupe ON sent_notifications(user_id, kind, channel, deck_id, due_at) NULLS NOT DISTINCT;
+CREATE INDEX idx_sent_notifications_user_created ON sent_notifications(user_id, created_at DESC);
+CREATE INDEX idx_sent_notifications_deck_id ON sent_notifications(deck_id) WHERE deck_id IS NOT NULL;
+
+CREATE INDEX idx_decks_due ON decks(next_review_date) WHERE deleted_at IS NULL AND NOT is_archived;
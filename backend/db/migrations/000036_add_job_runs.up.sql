@@ -0,0 +1,14 @@
+CREATE TABLE job_runs (
+    id BIGSERIAL PRIMARY KEY,
+    job_name VARCHAR(64) NOT NULL,
+    instance VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    affected BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ,
+    duration_ms INT
+);
+
+CREATE INDEX idx_job_runs_job_started ON job_runs(job_name, started_at DESC);
+CREATE INDEX idx_job_runs_running ON job_runs(job_name) WHERE status = 'running';
@@ -0,0 +1,7 @@
+ALTER TABLE group_assignments DROP CONSTRAINT IF EXISTS fk_schedule;
+
+UPDATE group_assignments a SET schedule_id = s.id
+FROM "groups" g, deck_schedules s
+WHERE a.schedule_id IS NULL AND g.id = a.group_id AND s.user_id = g.owner_id AND s.is_default;
+
+ALTER TABLE group_assignments ALTER COLUMN schedule_id SET NOT NULL;
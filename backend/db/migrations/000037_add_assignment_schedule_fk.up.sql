@@ -0,0 +1,33 @@
+-- Student decks used to share the teacher's schedule. Give every student a
+-- copy of each foreign schedule their decks use.
+DO $$
+DECLARE
+    foreign_schedule RECORD;
+    new_id INT;
+BEGIN
+    FOR foreign_schedule IN
+        SELECT DISTINCT d.user_id, s.id, s.name
+        FROM decks d
+        JOIN deck_schedules s ON s.id = d.schedule_id
+        WHERE s.user_id <> d.user_id
+    LOOP
+        INSERT INTO deck_schedules (name, user_id, is_default)
+        VALUES (foreign_schedule.name, foreign_schedule.user_id, FALSE)
+        RETURNING id INTO new_id;
+
+        INSERT INTO schedule_steps (deck_schedule_id, "level", interval_minutes)
+        SELECT new_id, "level", interval_minutes
+        FROM schedule_steps
+        WHERE deck_schedule_id = foreign_schedule.id;
+
+        UPDATE decks SET schedule_id = new_id
+        WHERE user_id = foreign_schedule.user_id AND schedule_id = foreign_schedule.id;
+    END LOOP;
+END $$;
+
+ALTER TABLE group_assignments ALTER COLUMN schedule_id DROP NOT NULL;
+
+UPDATE group_assignments SET schedule_id = NULL
+WHERE schedule_id NOT IN (SELECT id FROM deck_schedules);
+
+ALTER TABLE group_assignments ADD CONSTRAINT fk_schedule FOREIGN KEY(schedule_id) REFERENCES deck_schedules(id) ON DELETE SET NULL;
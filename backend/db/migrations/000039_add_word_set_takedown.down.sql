@@ -0,0 +1,3 @@
+ALTER TABLE word_sets
+    DROP COLUMN unpublished_by,
+    DROP COLUMN unpublished_at;
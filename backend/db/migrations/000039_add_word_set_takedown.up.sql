@@ -0,0 +1,3 @@
+ALTER TABLE word_sets
+    ADD COLUMN unpublished_at TIMESTAMPTZ,
+    ADD COLUMN unpublished_by INT REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AdminAuditLog records an action taken through the admin API. AdminId is
+// cleared if the admin's account is deleted later.
+type AdminAuditLog struct {
+	Id         int
+	AdminId    *int
+	Action     string
+	TargetType string
+	TargetId   int
+	Details    string
+	CreatedAt  time.Time
+}
@@ -1,5 +1,7 @@
 package models
 
+import "gorm.io/gorm"
+
 type Card struct {
 	Id                 int
 	OriginalWord       string
@@ -7,6 +9,8 @@ type Card struct {
 	OriginalContext    string
 	TranslationContext string
 	IsLearning         bool `gorm:"<-:false"`
+	Version            int  `gorm:"default:1"`
+	DeletedAt          gorm.DeletedAt
 
 	Decks    []Deck    `gorm:"many2many:deck_cards"`
 	WordSets []WordSet `gorm:"many2many:set_to_card_link"`
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	ReportOpen      = "open"
+	ReportDismissed = "dismissed"
+	ReportActioned  = "actioned"
+)
+
+// ContentReport is a complaint about a public word set or one of its cards.
+// WordSetId is the reported set, or the public set the card was seen in.
+type ContentReport struct {
+	Id         int
+	ReporterId *int
+	TargetType string
+	TargetId   int
+	WordSetId  int
+	Reason     string
+	Comment    string
+	Status     string
+	ResolvedBy *int
+	ResolvedAt *time.Time
+	CreatedAt  time.Time
+}
@@ -1,16 +1,24 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Deck struct {
-	Id                   int       `json:"id" gorm:"primaryKey"`
-	UserId               int       `json:"userId" gorm:"column:user_id"`
-	Name                 string    `json:"name"`
-	CreatedAt            time.Time `json:"createdAt" gorm:"column:created_at"`
-	CurrentLevel         int       `json:"currentLevel" gorm:"column:current_level"`
-	IsArchived           bool      `json:"isArchived" gorm:"column:is_archived"`
-	NextReviewDate       time.Time `json:"nextReviewDate" gorm:"column:next_review_date"`
-	NextPrimaryDirection bool      `json:"nextPrimaryDirection" gorm:"column:next_primary_direction"`
+	Id                   int            `json:"id" gorm:"primaryKey"`
+	UserId               int            `json:"userId" gorm:"column:user_id"`
+	Name                 string         `json:"name"`
+	CreatedAt            time.Time      `json:"createdAt" gorm:"column:created_at"`
+	CurrentLevel         int            `json:"currentLevel" gorm:"column:current_level"`
+	IsArchived           bool           `json:"isArchived" gorm:"column:is_archived"`
+	NextReviewDate       time.Time      `json:"nextReviewDate" gorm:"column:next_review_date"`
+	NextPrimaryDirection bool           `json:"nextPrimaryDirection" gorm:"column:next_primary_direction"`
+	DeletedAt            gorm.DeletedAt `json:"-" gorm:"column:deleted_at"`
+	Version              int            `json:"version" gorm:"column:version;default:1"`
+
+	AssignmentId *int `json:"assignmentId,omitempty" gorm:"column:assignment_id"`
 
 	ScheduleId int          `json:"scheduleId" gorm:"column:schedule_id"`
 	Schedule   DeckSchedule `json:"schedule,omitempty" gorm:"foreignKey:ScheduleId"`
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	GroupTeacher = "teacher"
+	GroupStudent = "student"
+)
+
+// Group is a class. Students join with JoinCode; the owner is always one of
+// its teachers.
+type Group struct {
+	Id        int
+	Name      string
+	OwnerId   int
+	JoinCode  string
+	CreatedAt time.Time
+}
+
+type GroupMember struct {
+	Id       int
+	GroupId  int
+	UserId   int
+	Role     string
+	JoinedAt time.Time
+}
+
+// GroupAssignment is a word set handed out to every student of the group as
+// a deck of their own; the decks point back to it with AssignmentId. Each
+// deck gets a copy of ScheduleId, which is nil once the teacher's schedule is
+// gone.
+type GroupAssignment struct {
+	Id         int
+	GroupId    int
+	WordSetId  *int
+	ScheduleId *int
+	Name       string
+	AssignedBy *int
+	CreatedAt  time.Time
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// JobRun is one run of a background job, by whichever instance held the
+// job's lock. Affected is what the job reports, usually deleted rows.
+type JobRun struct {
+	Id         int64
+	JobName    string
+	Instance   string
+	Status     string
+	Affected   int64
+	Error      *string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+	DurationMs *int
+}
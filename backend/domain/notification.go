@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	NotificationSent   = "sent"
+	NotificationFailed = "failed"
+)
+
+// NotificationPreference holds how a user wants to be reminded. Times are
+// minutes since local midnight in Timezone; quiet hours may wrap midnight.
+// Users without a row get DefaultNotificationPreference.
+type NotificationPreference struct {
+	UserId           int `gorm:"primaryKey"`
+	EmailEnabled     bool
+	PushEnabled      bool
+	QuietStartMinute *int
+	QuietEndMinute   *int
+	DigestEnabled    bool
+	DigestMinute     int
+	Timezone         string
+	UpdatedAt        time.Time
+}
+
+func DefaultNotificationPreference(userId int) NotificationPreference {
+	return NotificationPreference{
+		UserId:       userId,
+		EmailEnabled: true,
+		DigestMinute: 9 * 60,
+		Timezone:     "UTC",
+	}
+}
+
+// SentNotification records a reminder or digest sent through one channel.
+// DeckId is set for reminders; DueAt is the deck's review date, or the start
+// of the local day for digests.
+type SentNotification struct {
+	Id        int64
+	UserId    int
+	Channel   string
+	Kind      string
+	DeckId    *int
+	DueAt     time.Time
+	Title     string
+	Body      string
+	Status    string
+	Error     *string
+	CreatedAt time.Time
+}
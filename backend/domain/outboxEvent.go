@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// OutboxEvent is a domain event saved in the same transaction as the change
+// it describes; the dispatcher hands it to subscribers afterwards.
+type OutboxEvent struct {
+	Id           int64
+	EventType    string
+	Payload      string
+	Attempts     int
+	LastError    *string
+	AvailableAt  time.Time
+	DispatchedAt *time.Time
+	CreatedAt    time.Time
+}
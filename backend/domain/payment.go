@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type Payment struct {
+	Id             int
+	UserId         int
+	Provider       string
+	Kind           string
+	ExternalId     string `gorm:"unique"`
+	SubscriptionId string
+	PlanId         string
+	Amount         int64
+	Currency       string
+	Status         string
+	CreatedAt      time.Time
+	PaidAt         *time.Time
+}
+
+type Subscription struct {
+	Id               int
+	UserId           int
+	Provider         string
+	ExternalId       string `gorm:"unique"`
+	PlanId           string
+	Status           string
+	CurrentPeriodEnd time.Time
+	CanceledAt       *time.Time
+	CreatedAt        time.Time
+}
+
+type PaymentEvent struct {
+	Id          int
+	Provider    string
+	EventId     string `gorm:"unique"`
+	Type        string
+	ProcessedAt time.Time
+}
@@ -0,0 +1,11 @@
+package models
+
+// Plan holds the limits of a subscription tier. A nil limit means unlimited.
+type Plan struct {
+	Id             string `gorm:"primaryKey"`
+	Name           string
+	DecksPerDay    *int
+	CardsPerDeck   *int
+	WordSets       *int
+	MediaStorageMb *int
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PushSubscription is one browser or device subscribed to Web Push. P256dh
+// and Auth are the keys its payloads are encrypted with, base64url encoded.
+type PushSubscription struct {
+	Id         int
+	UserId     int
+	Endpoint   string
+	P256dh     string `gorm:"column:p256dh"`
+	Auth       string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
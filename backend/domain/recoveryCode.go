@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost. Only its SHA-256 hash is stored.
+type RecoveryCode struct {
+	Id        int
+	UserId    int
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
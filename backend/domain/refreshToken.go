@@ -6,5 +6,11 @@ type RefreshToken struct {
 	Id        int
 	UserId    int
 	Token     string `gorm:"unique"`
+	FamilyId  string
+	UserAgent string
+	Ip        string
+	CreatedAt time.Time
 	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
 }
@@ -5,6 +5,7 @@ type DeckSchedule struct {
 	Name      string
 	UserId    int
 	IsDefault bool
+	Version   int `gorm:"default:1"`
 
 	// Это поле связывает с дочерней моделью
 	ScheduleSteps []ScheduleStep `gorm:"foreignKey:deck_schedule_id"`
@@ -6,5 +6,6 @@ type AppClaims struct {
 	UserID    int    `json:"userId"`
 	Login     string `json:"login"`
 	IsPremium bool   `json:"isPremium"`
+	Role      string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
@@ -7,7 +7,21 @@ type User struct {
 	Email            string `gorm:"unique"`
 	Login            string `gorm:"unique"`
 	PasswordHash     string
+	Role             string
 	PremiumExpiresAt time.Time
+	EmailVerifiedAt  *time.Time
+	PendingEmail     string
+	TotpSecret       string
+	TotpEnabledAt    *time.Time
+	TotpLastStep     int64
+	FailedLogins     int
+	LockedUntil      *time.Time
 
 	Decks []Deck `gorm:"foreignKey:UserId"`
 }
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
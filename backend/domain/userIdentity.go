@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external login provider.
+type UserIdentity struct {
+	Id        int
+	UserId    int
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+// OAuthState is a pending authorization request. UserId is set when a
+// logged in user links a provider instead of logging in with it.
+type OAuthState struct {
+	State        string `gorm:"primaryKey"`
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	UserId       *int
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserToken is a single-use token sent by email. Only its SHA-256 hash is
+// stored.
+type UserToken struct {
+	Id        int
+	UserId    int
+	Purpose   string
+	TokenHash string `gorm:"unique"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	Attempts  int
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook posts the user's domain events to their URL. EventTypes is a comma
+// separated list; the hook is switched off after too many failed attempts in
+// a row.
+type Webhook struct {
+	Id           int
+	UserId       int
+	URL          string `gorm:"column:url"`
+	Secret       string
+	EventTypes   string
+	IsActive     bool
+	FailureCount int
+	DisabledAt   *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// WebhookDelivery is one event sent to one webhook, with the result of its
+// last attempt.
+type WebhookDelivery struct {
+	Id             int64
+	WebhookId      int
+	EventId        int64
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int
+	ResponseStatus *int
+	ResponseBody   *string
+	LastError      *string
+	DurationMs     *int
+	NextAttemptAt  time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	WordSetEditor = "editor"
+	WordSetViewer = "viewer"
+)
+
+// WordSetMember gives another user access to a word set. The invite is
+// pending until AcceptedAt is set.
+type WordSetMember struct {
+	Id         int
+	WordSetId  int
+	UserId     int
+	Role       string
+	InvitedBy  *int
+	AcceptedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type WordSetActivity struct {
+	Id        int
+	WordSetId int
+	UserId    *int
+	Action    string
+	CardId    *int
+	Details   string
+	CreatedAt time.Time
+}
@@ -1,11 +1,28 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type WordSet struct {
 	Id        int `gorm:"prymaryKey"`
 	UserId    int
 	Name      string
 	IsPublic  bool
 	IsDefault bool
+	DeletedAt gorm.DeletedAt
+	Version   int `gorm:"default:1"`
+
+	SourceWordSetId *int
+	// HiddenAt is set when a public set collects too many reports; it stays
+	// out of the public list until a moderator looks at it.
+	HiddenAt *time.Time
+	// UnpublishedAt is set when a moderator takes the set down; the owner
+	// can't publish it again.
+	UnpublishedAt *time.Time
+	UnpublishedBy *int
 
 	Cards []Card `gorm:"many2many:set_to_card_link"`
 }
@@ -0,0 +1,85 @@
+package admin
+
+import (
+	models "dimplom_harmonic/domain"
+	"time"
+)
+
+type AdminUserDTO struct {
+	Id               int        `json:"id"`
+	Email            string     `json:"email"`
+	Login            string     `json:"login"`
+	Role             string     `json:"role"`
+	Status           string     `json:"status"`
+	PremiumExpiresAt time.Time  `json:"premiumExpiresAt"`
+	EmailVerified    bool       `json:"emailVerified"`
+	TwoFactorEnabled bool       `json:"twoFactorEnabled"`
+	LockedUntil      *time.Time `json:"lockedUntil"`
+}
+
+func UserModelTo(m *models.User, status string) AdminUserDTO {
+	return AdminUserDTO{
+		Id:               m.Id,
+		Email:            m.Email,
+		Login:            m.Login,
+		Role:             m.Role,
+		Status:           status,
+		PremiumExpiresAt: m.PremiumExpiresAt,
+		EmailVerified:    m.EmailVerifiedAt != nil,
+		TwoFactorEnabled: m.TotpEnabledAt != nil,
+		LockedUntil:      m.LockedUntil,
+	}
+}
+
+type UsersPageDTO struct {
+	Users []AdminUserDTO `json:"users"`
+	Total int64          `json:"total"`
+	Page  int            `json:"page"`
+}
+
+type SetRoleRequestDTO struct {
+	Role string `json:"role"`
+}
+
+type GrantPremiumRequestDTO struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type UnpublishRequestDTO struct {
+	Reason string `json:"reason"`
+}
+
+type AuditLogDTO struct {
+	Id         int       `json:"id"`
+	AdminId    *int      `json:"adminId"`
+	AdminLogin string    `json:"adminLogin"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"targetType"`
+	TargetId   int       `json:"targetId"`
+	Details    string    `json:"details"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type AuditLogPageDTO struct {
+	Entries []AuditLogDTO `json:"entries"`
+	Total   int64         `json:"total"`
+	Page    int           `json:"page"`
+}
+
+func AuditLogResultTo(r []AuditLogResult) []AuditLogDTO {
+	entries := make([]AuditLogDTO, 0, len(r))
+
+	for _, value := range r {
+		entries = append(entries, AuditLogDTO{
+			Id:         value.Id,
+			AdminId:    value.AdminId,
+			AdminLogin: value.AdminLogin,
+			Action:     value.Action,
+			TargetType: value.TargetType,
+			TargetId:   value.TargetId,
+			Details:    value.Details,
+			CreatedAt:  value.CreatedAt,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,57 @@
+package admin
+
+import (
+	models "dimplom_harmonic/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminService interface {
+	ListUsers(filter UserFilter) (*UsersPageDTO, error)
+	GetUser(userId int) (*AdminUserDTO, error)
+	SetRole(adminId, userId int, role string) error
+	GrantPremium(adminId, userId int, expiresAt time.Time) error
+	RevokePremium(adminId, userId int) error
+	UnpublishWordSet(adminId, wordSetId int, reason string) error
+	GetAuditLog(page, pageSize int) (*AuditLogPageDTO, error)
+}
+
+type AdminRepository interface {
+	ListUsers(filter UserFilter) ([]models.User, int64, error)
+	SetRole(userId int, role string) error
+	CreateAuditLog(entry *models.AdminAuditLog) error
+	GetAuditLogs(limit, offset int) ([]AuditLogResult, int64, error)
+	WithTx(tx *gorm.DB) AdminRepository
+}
+
+type UserFilter struct {
+	Query    string
+	Role     string
+	Page     int
+	PageSize int
+}
+
+type AuditLogResult struct {
+	models.AdminAuditLog
+	AdminLogin string
+}
+
+const (
+	ActionSetRole          = "set_role"
+	ActionGrantPremium     = "grant_premium"
+	ActionRevokePremium    = "revoke_premium"
+	ActionUnpublishWordSet = "unpublish_word_set"
+)
+
+const (
+	TargetUser    = "user"
+	TargetWordSet = "word_set"
+)
+
+var (
+	ErrInvalidRole      = errors.New("invalid_role")
+	ErrOwnRole          = errors.New("cant_change_own_role")
+	ErrWordSetNotPublic = errors.New("word_set_not_public")
+)
@@ -0,0 +1,26 @@
+package admin
+
+import (
+	models "dimplom_harmonic/domain"
+	"encoding/json"
+	"time"
+)
+
+// NewAuditLog builds an entry with the details stored as JSON. Callers write
+// it with the repository of the action's transaction, so an action is never
+// applied without its entry.
+func NewAuditLog(adminId int, action, targetType string, targetId int, details map[string]any) (*models.AdminAuditLog, error) {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AdminAuditLog{
+		AdminId:    &adminId,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Details:    string(detailsJSON),
+		CreatedAt:  time.Now(),
+	}, nil
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"dimplom_harmonic/internal/admin"
+	"dimplom_harmonic/internal/middleware"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type AdminHandler struct {
+	service admin.AdminService
+}
+
+func NewAdminHandler(service admin.AdminService) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+func pageParams(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	return page, min(pageSize, maxPageSize)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func (h *AdminHandler) HDListUsers(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := pageParams(r)
+
+	users, err := h.service.ListUsers(admin.UserFilter{
+		Query:    r.URL.Query().Get("q"),
+		Role:     r.URL.Query().Get("role"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+func (h *AdminHandler) HDGetUser(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.GetUser(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *AdminHandler) HDSetRole(w http.ResponseWriter, r *http.Request) {
+	adminId := r.Context().Value(middleware.UserIDKey).(int)
+	userId, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var input admin.SetRoleRequestDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.SetRole(adminId, userId, input.Role)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AdminHandler) HDGrantPremium(w http.ResponseWriter, r *http.Request) {
+	adminId := r.Context().Value(middleware.UserIDKey).(int)
+	userId, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var input admin.GrantPremiumRequestDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.GrantPremium(adminId, userId, input.ExpiresAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AdminHandler) HDRevokePremium(w http.ResponseWriter, r *http.Request) {
+	adminId := r.Context().Value(middleware.UserIDKey).(int)
+	userId, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.RevokePremium(adminId, userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AdminHandler) HDUnpublishWordSet(w http.ResponseWriter, r *http.Request) {
+	adminId := r.Context().Value(middleware.UserIDKey).(int)
+	wordSetId, err := strconv.Atoi(chi.URLParam(r, "wordSetID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var input admin.UnpublishRequestDTO
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&input)
+		if err != nil {
+			http.Error(w, "Wrong format json", http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = h.service.UnpublishWordSet(adminId, wordSetId, input.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AdminHandler) HDGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := pageParams(r)
+
+	entries, err := h.service.GetAuditLog(page, pageSize)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
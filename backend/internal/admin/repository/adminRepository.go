@@ -0,0 +1,89 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/admin"
+
+	"gorm.io/gorm"
+)
+
+type AdminRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminRepository(db *gorm.DB) *AdminRepository {
+	return &AdminRepository{db: db}
+}
+
+func (r *AdminRepository) ListUsers(filter admin.UserFilter) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	query := r.db.Model(&models.User{})
+
+	if filter.Query != "" {
+		pattern := "%" + filter.Query + "%"
+		query = query.Where("email ILIKE ? OR login ILIKE ?", pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.
+		Order("id").
+		Limit(filter.PageSize).
+		Offset((filter.Page - 1) * filter.PageSize).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *AdminRepository) SetRole(userId int, role string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userId).Update("role", role).Error
+}
+
+func (r *AdminRepository) CreateAuditLog(entry *models.AdminAuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *AdminRepository) GetAuditLogs(limit, offset int) ([]admin.AuditLogResult, int64, error) {
+	var entries []admin.AuditLogResult
+	var total int64
+
+	err := r.db.Model(&models.AdminAuditLog{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT 
+			l.*,
+			COALESCE(u.login, '') as admin_login
+		FROM 
+			admin_audit_logs l
+			LEFT JOIN users u ON u.id = l.admin_id
+		ORDER BY 
+			l.created_at DESC, l.id DESC
+		LIMIT ? OFFSET ?
+	`
+	err = r.db.Raw(query, limit, offset).Scan(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+func (r *AdminRepository) WithTx(tx *gorm.DB) admin.AdminRepository {
+	return &AdminRepository{
+		db: tx,
+	}
+}
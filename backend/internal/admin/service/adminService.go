@@ -0,0 +1,210 @@
+package service
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/admin"
+	"dimplom_harmonic/internal/auth"
+	"dimplom_harmonic/internal/entitlement"
+	"dimplom_harmonic/internal/mailer"
+	wordset "dimplom_harmonic/internal/wordSet"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminService struct {
+	adminRepo    admin.AdminRepository
+	userRepo     auth.UserRepository
+	wordSetRepo  wordset.WordSetRepository
+	entitlements entitlement.EntitlementService
+	mailer       mailer.Mailer
+	appURL       string
+	db           *gorm.DB
+}
+
+func NewAdminService(adminRepo admin.AdminRepository, userRepo auth.UserRepository, wordSetRepo wordset.WordSetRepository, entitlements entitlement.EntitlementService, mailer mailer.Mailer, appURL string, db *gorm.DB) *AdminService {
+	return &AdminService{adminRepo: adminRepo, userRepo: userRepo, wordSetRepo: wordSetRepo, entitlements: entitlements, mailer: mailer, appURL: appURL, db: db}
+}
+
+func audit(adminRepo admin.AdminRepository, adminId int, action, targetType string, targetId int, details map[string]any) error {
+	entry, err := admin.NewAuditLog(adminId, action, targetType, targetId, details)
+	if err != nil {
+		return err
+	}
+	return adminRepo.CreateAuditLog(entry)
+}
+
+func (s *AdminService) ListUsers(filter admin.UserFilter) (*admin.UsersPageDTO, error) {
+	users, total, err := s.adminRepo.ListUsers(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := admin.UsersPageDTO{
+		Users: make([]admin.AdminUserDTO, 0, len(users)),
+		Total: total,
+		Page:  filter.Page,
+	}
+	for i := range users {
+		page.Users = append(page.Users, admin.UserModelTo(&users[i], s.entitlements.Status(&users[i])))
+	}
+	return &page, nil
+}
+
+func (s *AdminService) GetUser(userId int) (*admin.AdminUserDTO, error) {
+	user, err := s.userRepo.GetByID(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := admin.UserModelTo(user, s.entitlements.Status(user))
+	return &result, nil
+}
+
+func (s *AdminService) SetRole(adminId, userId int, role string) error {
+	if role != models.RoleUser && role != models.RoleModerator && role != models.RoleAdmin {
+		return admin.ErrInvalidRole
+	}
+	if adminId == userId {
+		return admin.ErrOwnRole
+	}
+
+	user, err := s.userRepo.GetByID(userId)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txAdminRepo := s.adminRepo.WithTx(tx)
+
+		if err := txAdminRepo.SetRole(userId, role); err != nil {
+			return err
+		}
+		return audit(txAdminRepo, adminId, admin.ActionSetRole, admin.TargetUser, userId, map[string]any{
+			"from": user.Role,
+			"to":   role,
+		})
+	})
+}
+
+func (s *AdminService) GrantPremium(adminId, userId int, expiresAt time.Time) error {
+	if !expiresAt.After(time.Now()) {
+		return errors.New("expiresAt must be in the future")
+	}
+
+	user, err := s.userRepo.GetByID(userId)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txAdminRepo := s.adminRepo.WithTx(tx)
+
+		if err := s.userRepo.WithTx(tx).UpdatePremiumExpiresAt(userId, expiresAt); err != nil {
+			return err
+		}
+		return audit(txAdminRepo, adminId, admin.ActionGrantPremium, admin.TargetUser, userId, map[string]any{
+			"from": user.PremiumExpiresAt,
+			"to":   expiresAt,
+		})
+	})
+}
+
+func (s *AdminService) RevokePremium(adminId, userId int) error {
+	user, err := s.userRepo.GetByID(userId)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txAdminRepo := s.adminRepo.WithTx(tx)
+
+		if err := s.userRepo.WithTx(tx).UpdatePremiumExpiresAt(userId, time.Now()); err != nil {
+			return err
+		}
+		return audit(txAdminRepo, adminId, admin.ActionRevokePremium, admin.TargetUser, userId, map[string]any{
+			"from": user.PremiumExpiresAt,
+		})
+	})
+}
+
+func (s *AdminService) UnpublishWordSet(adminId, wordSetId int, reason string) error {
+	wordSet, err := s.wordSetRepo.GetWordSetByID(adminId, wordSetId)
+	if err != nil {
+		return err
+	}
+	if wordSet.Id == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if !wordSet.IsPublic {
+		return admin.ErrWordSetNotPublic
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txAdminRepo := s.adminRepo.WithTx(tx)
+
+		_, err := s.wordSetRepo.WithTx(tx).UpdateWordSet(wordSetId, 0, map[string]any{
+			"is_public":      false,
+			"hidden_at":      nil,
+			"unpublished_at": time.Now(),
+			"unpublished_by": adminId,
+		})
+		if err != nil {
+			return err
+		}
+		return audit(txAdminRepo, adminId, admin.ActionUnpublishWordSet, admin.TargetWordSet, wordSetId, map[string]any{
+			"name":    wordSet.Name,
+			"ownerId": wordSet.UserId,
+			"reason":  reason,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.notifyUnpublished(&wordSet.WordSet, reason); err != nil {
+		log.Printf("Can't notify user %d about unpublished word set %d: %v", wordSet.UserId, wordSetId, err)
+	}
+	return nil
+}
+
+// notifyUnpublished tells the owner why the set left the public list. Sets
+// of deleted accounts have no owner to tell.
+func (s *AdminService) notifyUnpublished(wordSet *models.WordSet, reason string) error {
+	if wordSet.UserId == 0 {
+		return nil
+	}
+
+	owner, err := s.userRepo.GetByID(wordSet.UserId)
+	if err != nil {
+		return err
+	}
+
+	body := "Hi " + owner.Login + ",\n\nYour word set \"" + wordSet.Name + "\" was removed from the public list by a moderator."
+	if reason != "" {
+		body += "\n\nReason: " + reason
+	}
+	body += "\n\nThe set and its cards are still yours: " + s.appURL + "/word-sets/" + strconv.Itoa(wordSet.Id)
+
+	return s.mailer.Send(mailer.Message{
+		To:      owner.Email,
+		Subject: "Your word set was unpublished",
+		Body:    body,
+	})
+}
+
+func (s *AdminService) GetAuditLog(page, pageSize int) (*admin.AuditLogPageDTO, error) {
+	entries, total, err := s.adminRepo.GetAuditLogs(pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &admin.AuditLogPageDTO{
+		Entries: admin.AuditLogResultTo(entries),
+		Total:   total,
+		Page:    page,
+	}, nil
+}
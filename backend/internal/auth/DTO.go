@@ -2,6 +2,7 @@ package auth
 
 import (
 	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/entitlement"
 	"time"
 )
 
@@ -41,18 +42,112 @@ type LoginUserRequestDTO struct {
 	Password string `json:"password"`
 }
 
+type EmailRequestDTO struct {
+	Email string `json:"email"`
+}
+
+type TokenRequestDTO struct {
+	Token string `json:"token"`
+}
+
+type ResetPasswordRequestDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type TwoFactorLoginRequestDTO struct {
+	Token string `json:"token"`
+	Code  string `json:"code"`
+}
+
+type TwoFactorCodeRequestDTO struct {
+	Code string `json:"code"`
+}
+
+type ReauthRequestDTO struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorStatusDTO struct {
+	Enabled           bool  `json:"enabled"`
+	RecoveryCodesLeft int64 `json:"recoveryCodesLeft"`
+}
+
+type TwoFactorSetupDTO struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
+type RecoveryCodesResponseDTO struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type ChangeLoginRequestDTO struct {
+	Login string `json:"login"`
+}
+
+type ChangePasswordRequestDTO struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
 type GetProfileUserResponseDTO struct {
-	User  Profile `json:"user"`
-	Stats Stats   `json:"stats"`
+	User  Profile           `json:"user"`
+	Stats Stats             `json:"stats"`
+	Quota entitlement.Quota `json:"quota"`
 }
 
-func GetProfileToResponse(p *Profile, s *Stats) GetProfileUserResponseDTO {
+func GetProfileToResponse(p *Profile, s *Stats, q *entitlement.Quota) GetProfileUserResponseDTO {
 	return GetProfileUserResponseDTO{
 		User:  *p,
 		Stats: *s,
+		Quota: *q,
 	}
 }
 
-type MockPaymentRequestDTO struct {
-	PlanId string `json:"planId"`
+type SessionDTO struct {
+	Id         string    `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	Ip         string    `json:"ip"`
+	StartedAt  time.Time `json:"startedAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+func SessionsResultTo(r []SessionResult, currentToken string) []SessionDTO {
+	sessions := make([]SessionDTO, 0, len(r))
+
+	for _, value := range r {
+		sessions = append(sessions, SessionDTO{
+			Id:         value.FamilyId,
+			UserAgent:  value.UserAgent,
+			Ip:         value.Ip,
+			StartedAt:  value.StartedAt,
+			LastUsedAt: value.LastUsedAt,
+			ExpiresAt:  value.ExpiresAt,
+			Current:    currentToken != "" && value.Token == currentToken,
+		})
+	}
+	return sessions
+}
+
+type IdentityDTO struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func IdentitiesModelTo(m []models.UserIdentity) []IdentityDTO {
+	identities := make([]IdentityDTO, 0, len(m))
+
+	for _, value := range m {
+		identities = append(identities, IdentityDTO{
+			Provider:  value.Provider,
+			Email:     value.Email,
+			CreatedAt: value.CreatedAt,
+		})
+	}
+	return identities
 }
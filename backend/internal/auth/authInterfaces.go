@@ -2,29 +2,83 @@ package auth
 
 import (
 	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/entitlement"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type UserService interface {
-	LoginUser(email, password string) (string, string, error)
+	LoginUser(email, password string, meta SessionMeta) (*LoginResult, error)
 	RegisterUser(models.User) (*models.User, error)
-	GetProfile(id int) (*Profile, *Stats, error)
-	MockPayment(userId int, period string) error
-	GeneratePairTokens(user *models.User) (string, string, error)
-	RefreshToken(oldToken string) (string, string, error)
+	GetProfile(id int) (*Profile, *Stats, *entitlement.Quota, error)
+	GeneratePairTokens(user *models.User, meta SessionMeta) (string, string, error)
+	RefreshToken(oldToken string, meta SessionMeta) (string, string, error)
 	DeleteRefreshToken(token string) error
+	GetSessions(userId int, currentToken string) ([]SessionDTO, error)
+	RevokeSession(userId int, sessionId string) error
+	RevokeAllSessions(userId int) error
+	SendEmailVerification(userId int) error
+	VerifyEmail(token string) error
+	RequestPasswordReset(email string) error
+	ResetPassword(token, password string) error
+	OAuthLogin(identity ExternalIdentity, meta SessionMeta) (*LoginResult, error)
+	LinkIdentity(userId int, identity ExternalIdentity) error
+	UnlinkIdentity(userId int, provider string) error
+	GetIdentities(userId int) ([]IdentityDTO, error)
+	VerifyTwoFactorLogin(challengeToken, code string, meta SessionMeta) (string, string, error)
+	GetTwoFactorStatus(userId int) (*TwoFactorStatusDTO, error)
+	SetupTwoFactor(userId int) (*TwoFactorSetupDTO, error)
+	EnableTwoFactor(userId int, code string) ([]string, error)
+	DisableTwoFactor(userId int, reauthToken string) error
+	RegenerateRecoveryCodes(userId int, reauthToken string) ([]string, error)
+	Reauthenticate(userId int, password, code string) (string, error)
+	CheckReauth(userId int, reauthToken string) error
+	RequestEmailChange(userId int, email, reauthToken string) error
+	ConfirmEmailChange(token string) error
+	ChangeLogin(userId int, login string) error
+	ChangePassword(userId int, currentPassword, newPassword string, meta SessionMeta) (string, string, error)
+	DeleteAccount(userId int, reauthToken string) error
 }
 
 type UserRepository interface {
 	CreateUser(user *models.User) error
 	GetByEmail(email string) (*models.User, error)
 	GetByID(id int) (*models.User, error)
-	MockPayment(userId int, expiresAt time.Time) error
-	SaveRefreshToken(userId int, refreshToken string, expiresAt time.Time) error
-	GetRefreshToken(token string) (*RefreshToken, error)
-	DeleteRefreshToken(oldRefreshToken string) error
+	UpdatePremiumExpiresAt(userId int, expiresAt time.Time) error
+	SaveRefreshToken(refreshToken *models.RefreshToken) error
+	GetRefreshToken(token string) (*models.RefreshToken, error)
+	MarkRefreshTokenUsed(tokenId int) (bool, error)
+	RevokeFamily(userId int, familyId string) (int64, error)
+	RevokeAllFamilies(userId int) error
+	GetActiveSessions(userId int) ([]SessionResult, error)
+	CreateUserToken(token *models.UserToken) error
+	GetUserToken(tokenHash, purpose string) (*models.UserToken, error)
+	UseUserToken(tokenId int) (bool, error)
+	InvalidateUserTokens(userId int, purpose string) error
+	SetEmailVerified(userId int, verifiedAt time.Time) error
+	UpdatePassword(userId int, passwordHash string) error
+	LoginExists(login string) (bool, error)
+	CreateIdentity(identity *models.UserIdentity) error
+	GetIdentity(provider, subject string) (*models.UserIdentity, error)
+	GetIdentities(userId int) ([]models.UserIdentity, error)
+	DeleteIdentity(userId int, provider string) (int64, error)
+	IncrementUserTokenAttempts(tokenId int) (int, error)
+	SetTotpSecret(userId int, secret string) error
+	EnableTotp(userId int, enabledAt time.Time) error
+	DisableTotp(userId int) error
+	UseTotpStep(userId int, step int64) (bool, error)
+	ReplaceRecoveryCodes(userId int, codeHashes []string) error
+	UseRecoveryCode(userId int, codeHash string) (bool, error)
+	CountRecoveryCodes(userId int) (int64, error)
+	RecordLoginFailure(userId int) (int, error)
+	LockUntil(userId int, lockedUntil time.Time) error
+	ResetLoginFailures(userId int) error
+	SetPendingEmail(userId int, email string) error
+	ApplyPendingEmail(userId int) error
+	UpdateLogin(userId int, login string) error
+	DeleteUser(userId int) error
 	WithTx(tx *gorm.DB) UserRepository
 }
 
@@ -34,6 +88,7 @@ type Profile struct {
 	Login            string    `json:"login" gorm:"unique"`
 	PremiumExpiresAt time.Time `json:"premiumExpiresAt"`
 	Status           string    `json:"status"`
+	EmailVerified    bool      `json:"emailVerified"`
 }
 
 type Stats struct {
@@ -57,8 +112,87 @@ type GetProfileResults struct {
 	TotalReviews       int       `json:"totalReviews"`
 }
 
-type RefreshToken struct {
-	UserId    int
-	Token     string
-	ExpiresAt time.Time
+// SessionMeta describes the device a refresh token family was issued to.
+// An empty FamilyId starts a new family.
+type SessionMeta struct {
+	FamilyId  string
+	UserAgent string
+	Ip        string
 }
+
+type SessionResult struct {
+	FamilyId   string
+	Token      string
+	UserAgent  string
+	Ip         string
+	StartedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+var ErrInvalidUserToken = errors.New("invalid or expired token")
+
+// ExternalIdentity is a user as reported by an external login provider.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// LoginResult holds the token pair, or only TwoFactorToken when the user has
+// 2FA enabled and the code still has to be checked by VerifyTwoFactorLogin.
+type LoginResult struct {
+	AccessToken    string
+	RefreshToken   string
+	TwoFactorToken string
+}
+
+var ErrAccountLocked = errors.New("account_locked")
+
+// LockedError is returned by LoginUser while the account is locked after too
+// many wrong passwords.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+var (
+	ErrTwoFactorCode      = errors.New("invalid_two_factor_code")
+	ErrTwoFactorEnabled   = errors.New("two_factor_already_enabled")
+	ErrTwoFactorNotSetup  = errors.New("two_factor_not_set_up")
+	ErrReauthRequired     = errors.New("reauth_required")
+	ErrReauthNotAvailable = errors.New("reauth_not_available")
+)
+
+var (
+	ErrEmailTaken    = errors.New("email_taken")
+	ErrLoginTaken    = errors.New("login_taken")
+	ErrInvalidEmail  = errors.New("invalid_email")
+	ErrInvalidLogin  = errors.New("invalid_login")
+	ErrWrongPassword = errors.New("wrong_password")
+)
+
+var (
+	ErrProviderEmailNotVerified = errors.New("provider_email_not_verified")
+	ErrIdentityLinked           = errors.New("identity_already_linked")
+	ErrLastLoginMethod          = errors.New("last_login_method")
+)
+
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+	TokenTwoFactor     = "two_factor_login"
+	TokenReauth        = "reauth"
+	TokenChangeEmail   = "change_email"
+)
@@ -4,10 +4,19 @@ import (
 	"dimplom_harmonic/internal/auth"
 	"dimplom_harmonic/internal/middleware"
 	"encoding/json"
+	"errors"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
+// ReauthHeader carries the token from /auth/reauth for sensitive actions.
+const ReauthHeader = "X-Reauth-Token"
+
 type UserHandler struct {
 	service auth.UserService
 }
@@ -28,6 +37,28 @@ func (h *UserHandler) SetRefreshCookie(w http.ResponseWriter, refreshToken strin
 	})
 }
 
+func (h *UserHandler) ClearRefreshCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+func SessionMeta(r *http.Request) auth.SessionMeta {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return auth.SessionMeta{
+		UserAgent: r.UserAgent(),
+		Ip:        ip,
+	}
+}
+
 func (h *UserHandler) HandlerRegisterUser(w http.ResponseWriter, r *http.Request) {
 	var input auth.RegisterUserRequestDTO
 	err := json.NewDecoder(r.Body).Decode(&input)
@@ -57,12 +88,50 @@ func (h *UserHandler) HandlerLoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	at, rt, err := h.service.LoginUser(userData.Email, userData.Password)
+	result, err := h.service.LoginUser(userData.Email, userData.Password, SessionMeta(r))
+	var locked *auth.LockedError
+	if errors.As(err, &locked) {
+		retryAfter := int(math.Ceil(time.Until(locked.Until).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if result.TwoFactorToken != "" {
+		json.NewEncoder(w).Encode(map[string]any{"twoFactorRequired": true, "twoFactorToken": result.TwoFactorToken})
+		return
+	}
+
+	h.SetRefreshCookie(w, result.RefreshToken)
+
+	json.NewEncoder(w).Encode(map[string]string{"accessToken": result.AccessToken})
+}
+
+func (h *UserHandler) HDLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var input auth.TwoFactorLoginRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	at, rt, err := h.service.VerifyTwoFactorLogin(input.Token, input.Code, SessionMeta(r))
+	var locked *auth.LockedError
+	if errors.As(err, &locked) {
+		retryAfter := int(math.Ceil(time.Until(locked.Until).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
 	h.SetRefreshCookie(w, rt)
 
 	json.NewEncoder(w).Encode(map[string]string{"accessToken": at})
@@ -70,57 +139,386 @@ func (h *UserHandler) HandlerLoginUser(w http.ResponseWriter, r *http.Request) {
 
 func (h *UserHandler) HandlerGetProfile(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(int)
-	profile, stats, err := h.service.GetProfile(userID)
+	profile, stats, quota, err := h.service.GetProfile(userID)
 	if err != nil {
 		http.Error(w, "We don't find this user", http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(auth.GetProfileToResponse(profile, stats, quota))
+}
+
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("refresh_token")
+	if err != nil {
+		http.Error(w, "No refresh token", http.StatusBadRequest)
+		return
+	}
+
+	aceessToken, refreshToken, err := h.service.RefreshToken(cookie.Value, SessionMeta(r))
+	if err != nil {
+		h.ClearRefreshCookie(w)
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	h.SetRefreshCookie(w, refreshToken)
+
+	json.NewEncoder(w).Encode(map[string]string{"accessToken": aceessToken})
+}
+
+func (h *UserHandler) HDLogoutUser(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("refresh_token")
+	if err != nil {
+		http.Error(w, "No refresh token", http.StatusBadRequest)
+		return
+	}
+
+	h.ClearRefreshCookie(w)
+
+	h.service.DeleteRefreshToken(cookie.Value)
+}
+
+func (h *UserHandler) HDGetSessions(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var currentToken string
+	if cookie, err := r.Cookie("refresh_token"); err == nil {
+		currentToken = cookie.Value
 	}
 
-	json.NewEncoder(w).Encode(auth.GetProfileToResponse(profile, stats))
+	sessions, err := h.service.GetSessions(userId, currentToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
 }
 
-func (h *UserHandler) HDMockPayment(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) HDRevokeSession(w http.ResponseWriter, r *http.Request) {
 	userId := r.Context().Value(middleware.UserIDKey).(int)
+	sessionId := chi.URLParam(r, "sessionID")
+
+	err := h.service.RevokeSession(userId, sessionId)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
 
-	var period auth.MockPaymentRequestDTO
+	w.WriteHeader(http.StatusOK)
+}
 
-	err := json.NewDecoder(r.Body).Decode(&period)
+func (h *UserHandler) HDRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
 
+	err := h.service.RevokeAllSessions(userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.ClearRefreshCookie(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UserHandler) HDVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var input auth.TokenRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
 		http.Error(w, "Wrong format json", http.StatusBadRequest)
 		return
+	}
 
+	err = h.service.VerifyEmail(input.Token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	h.service.MockPayment(userId, period.PlanId)
+
+	w.WriteHeader(http.StatusOK)
 }
 
-func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("refresh_token")
+func (h *UserHandler) HDResendVerification(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	err := h.service.SendEmailVerification(userId)
 	if err != nil {
-		http.Error(w, "No refresh token", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	aceessToken, refreshToken, err := h.service.RefreshToken(cookie.Value)
+	w.WriteHeader(http.StatusOK)
+}
 
-	h.SetRefreshCookie(w, refreshToken)
+func (h *UserHandler) HDForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input auth.EmailRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
 
-	json.NewEncoder(w).Encode(map[string]string{"accessToken": aceessToken})
+	err = h.service.RequestPasswordReset(input.Email)
+	if err != nil {
+		http.Error(w, "Can't send reset email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
 }
 
-func (h *UserHandler) HDLogoutUser(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("refresh_token")
+func (h *UserHandler) HDResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input auth.ResetPasswordRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
-		http.Error(w, "No refresh token", http.StatusBadRequest)
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-	})
+	err = h.service.ResetPassword(input.Token, input.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	h.service.DeleteRefreshToken(cookie.Value)
+	h.ClearRefreshCookie(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UserHandler) HDGetIdentities(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	identities, err := h.service.GetIdentities(userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(identities)
+}
+
+func (h *UserHandler) HDUnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	provider := chi.URLParam(r, "provider")
+
+	err := h.service.UnlinkIdentity(userId, provider)
+	if errors.Is(err, auth.ErrLastLoginMethod) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Identity not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeAuthError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrReauthRequired), errors.Is(err, auth.ErrTwoFactorCode), errors.Is(err, auth.ErrWrongPassword):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, auth.ErrTwoFactorEnabled), errors.Is(err, auth.ErrTwoFactorNotSetup), errors.Is(err, auth.ErrReauthNotAvailable),
+		errors.Is(err, auth.ErrEmailTaken), errors.Is(err, auth.ErrLoginTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func (h *UserHandler) HDGetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	status, err := h.service.GetTwoFactorStatus(userId)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+func (h *UserHandler) HDSetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	setup, err := h.service.SetupTwoFactor(userId)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(setup)
+}
+
+func (h *UserHandler) HDEnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input auth.TwoFactorCodeRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.service.EnableTwoFactor(userId, input.Code)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auth.RecoveryCodesResponseDTO{RecoveryCodes: codes})
+}
+
+func (h *UserHandler) HDDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	err := h.service.DisableTwoFactor(userId, r.Header.Get(ReauthHeader))
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UserHandler) HDRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	codes, err := h.service.RegenerateRecoveryCodes(userId, r.Header.Get(ReauthHeader))
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auth.RecoveryCodesResponseDTO{RecoveryCodes: codes})
+}
+
+func (h *UserHandler) HDReauthenticate(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input auth.ReauthRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.service.Reauthenticate(userId, input.Password, input.Code)
+	var locked *auth.LockedError
+	if errors.As(err, &locked) {
+		retryAfter := int(math.Ceil(time.Until(locked.Until).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"reauthToken": token})
+}
+
+func (h *UserHandler) HDChangeEmail(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input auth.EmailRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.RequestEmailChange(userId, input.Email, r.Header.Get(ReauthHeader))
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *UserHandler) HDConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var input auth.TokenRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.ConfirmEmailChange(input.Token)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UserHandler) HDChangeLogin(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input auth.ChangeLoginRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.ChangeLogin(userId, input.Login)
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UserHandler) HDChangePassword(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input auth.ChangePasswordRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	at, rt, err := h.service.ChangePassword(userId, input.CurrentPassword, input.NewPassword, SessionMeta(r))
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	h.SetRefreshCookie(w, rt)
+
+	json.NewEncoder(w).Encode(map[string]string{"accessToken": at})
+}
+
+func (h *UserHandler) HDDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	err := h.service.DeleteAccount(userId, r.Header.Get(ReauthHeader))
+	if err != nil {
+		writeAuthError(w, err)
+		return
+	}
+
+	h.ClearRefreshCookie(w)
+	w.WriteHeader(http.StatusNoContent)
 }
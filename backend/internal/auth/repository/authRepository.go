@@ -45,7 +45,7 @@ func (r *UserRepository) GetByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) MockPayment(userId int, expiresAt time.Time) error {
+func (r *UserRepository) UpdatePremiumExpiresAt(userId int, expiresAt time.Time) error {
 	query := `
 		UPDATE 
 			users
@@ -62,45 +62,354 @@ func (r *UserRepository) MockPayment(userId int, expiresAt time.Time) error {
 	return err
 }
 
-func (r *UserRepository) SaveRefreshToken(userId int, refreshToken string, expiresAt time.Time) error {
+func (r *UserRepository) SaveRefreshToken(refreshToken *models.RefreshToken) error {
+	return r.db.Create(refreshToken).Error
+}
+
+func (r *UserRepository) GetRefreshToken(token string) (*models.RefreshToken, error) {
+	var refreshToken models.RefreshToken
+
+	err := r.db.Where("token = ?", token).First(&refreshToken).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+// MarkRefreshTokenUsed rotates a token out. It returns false when the token
+// was already used or revoked, which happens when two requests race with the
+// same token.
+func (r *UserRepository) MarkRefreshTokenUsed(tokenId int) (bool, error) {
+	query := `
+		UPDATE 
+			refresh_tokens
+		SET 
+			used_at = NOW()
+		WHERE 
+			id = ? AND used_at IS NULL AND revoked_at IS NULL
+	`
+	result := r.db.Exec(query, tokenId)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected != 0, nil
+}
+
+func (r *UserRepository) RevokeFamily(userId int, familyId string) (int64, error) {
+	query := `
+		UPDATE 
+			refresh_tokens
+		SET 
+			revoked_at = NOW()
+		WHERE 
+			user_id = ? AND family_id = ? AND revoked_at IS NULL
+	`
+	result := r.db.Exec(query, userId, familyId)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *UserRepository) RevokeAllFamilies(userId int) error {
+	query := `
+		UPDATE 
+			refresh_tokens
+		SET 
+			revoked_at = NOW()
+		WHERE 
+			user_id = ? AND revoked_at IS NULL
+	`
+	return r.db.Exec(query, userId).Error
+}
+
+func (r *UserRepository) GetActiveSessions(userId int) ([]auth.SessionResult, error) {
+	var sessions []auth.SessionResult
+
+	query := `
+		SELECT 
+			r.family_id,
+			r.token,
+			r.user_agent,
+			r.ip,
+			(SELECT MIN(r2.created_at) FROM refresh_tokens r2 WHERE r2.family_id = r.family_id) as started_at,
+			r.created_at as last_used_at,
+			r.expires_at
+		FROM 
+			refresh_tokens r
+		WHERE 
+			r.user_id = ? 
+			AND r.used_at IS NULL 
+			AND r.revoked_at IS NULL 
+			AND r.expires_at > NOW()
+		ORDER BY 
+			r.created_at DESC
+	`
+	err := r.db.Raw(query, userId).Scan(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *UserRepository) CreateUserToken(token *models.UserToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *UserRepository) GetUserToken(tokenHash, purpose string) (*models.UserToken, error) {
+	var token models.UserToken
+
+	err := r.db.Where("token_hash = ? AND purpose = ?", tokenHash, purpose).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UseUserToken marks the token as used. It returns false when it was already
+// used or has expired.
+func (r *UserRepository) UseUserToken(tokenId int) (bool, error) {
+	query := `
+		UPDATE 
+			user_tokens
+		SET 
+			used_at = NOW()
+		WHERE 
+			id = ? AND used_at IS NULL AND expires_at > NOW()
+	`
+	result := r.db.Exec(query, tokenId)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected != 0, nil
+}
+
+func (r *UserRepository) InvalidateUserTokens(userId int, purpose string) error {
+	query := `
+		UPDATE 
+			user_tokens
+		SET 
+			used_at = NOW()
+		WHERE 
+			user_id = ? AND purpose = ? AND used_at IS NULL
+	`
+	return r.db.Exec(query, userId, purpose).Error
+}
+
+func (r *UserRepository) SetEmailVerified(userId int, verifiedAt time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userId).Update("email_verified_at", verifiedAt).Error
+}
+
+func (r *UserRepository) UpdatePassword(userId int, passwordHash string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userId).Update("password_hash", passwordHash).Error
+}
+
+func (r *UserRepository) LoginExists(login string) (bool, error) {
+	var count int64
+
+	err := r.db.Model(&models.User{}).Where("login = ?", login).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count != 0, nil
+}
+
+func (r *UserRepository) CreateIdentity(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *UserRepository) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *UserRepository) GetIdentities(userId int) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+
+	err := r.db.Where("user_id = ?", userId).Order("created_at").Find(&identities).Error
+	if err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *UserRepository) DeleteIdentity(userId int, provider string) (int64, error) {
+	result := r.db.Where("user_id = ? AND provider = ?", userId, provider).Delete(&models.UserIdentity{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *UserRepository) IncrementUserTokenAttempts(tokenId int) (int, error) {
+	var attempts int
+
 	query := `
-		INSERT INTO 
-			refresh_tokens (user_id, token, expires_at)
-		VALUES
-			(?,?,?)
+		UPDATE 
+			user_tokens
+		SET 
+			attempts = attempts + 1
+		WHERE 
+			id = ?
+		RETURNING attempts
 	`
-	err := r.db.Exec(query, userId, refreshToken, expiresAt).Error
+	err := r.db.Raw(query, tokenId).Scan(&attempts).Error
+	if err != nil {
+		return 0, err
+	}
+	return attempts, nil
+}
+
+func (r *UserRepository) SetTotpSecret(userId int, secret string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userId).Update("totp_secret", secret).Error
+}
+
+func (r *UserRepository) EnableTotp(userId int, enabledAt time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userId).Update("totp_enabled_at", enabledAt).Error
+}
+
+func (r *UserRepository) DisableTotp(userId int) error {
+	err := r.db.Model(&models.User{}).Where("id = ?", userId).Updates(map[string]any{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
 	if err != nil {
 		return err
 	}
-	return nil
+
+	return r.db.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error
 }
 
-func (r *UserRepository) DeleteRefreshToken(oldRefreshToken string) error {
+// UseTotpStep records the step a code was accepted for. It returns false when
+// this or a later step was already used, so a code can't be replayed.
+func (r *UserRepository) UseTotpStep(userId int, step int64) (bool, error) {
 	query := `
-		DELETE 
-		FROM 
-			refresh_tokens r 
+		UPDATE 
+			users
+		SET 
+			totp_last_step = ?
 		WHERE 
-			r.token = ?
+			id = ? AND totp_last_step < ?
 	`
+	result := r.db.Exec(query, step, userId, step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected != 0, nil
+}
 
-	err := r.db.Exec(query, oldRefreshToken).Error
+func (r *UserRepository) ReplaceRecoveryCodes(userId int, codeHashes []string) error {
+	err := r.db.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error
 	if err != nil {
 		return err
 	}
-	return nil
+
+	codes := make([]models.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, models.RecoveryCode{
+			UserId:    userId,
+			CodeHash:  hash,
+			CreatedAt: time.Now(),
+		})
+	}
+	return r.db.Create(&codes).Error
 }
 
-func (r *UserRepository) GetRefreshToken(token string) (*auth.RefreshToken, error) {
-	var refreshToken auth.RefreshToken
+func (r *UserRepository) UseRecoveryCode(userId int, codeHash string) (bool, error) {
+	query := `
+		UPDATE 
+			recovery_codes
+		SET 
+			used_at = NOW()
+		WHERE 
+			user_id = ? AND code_hash = ? AND used_at IS NULL
+	`
+	result := r.db.Exec(query, userId, codeHash)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected != 0, nil
+}
 
-	err := r.db.Where("token = ?", token).First(&refreshToken).Error
+func (r *UserRepository) CountRecoveryCodes(userId int) (int64, error) {
+	var count int64
 
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userId).Count(&count).Error
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	return &refreshToken, nil
+	return count, nil
+}
+
+func (r *UserRepository) RecordLoginFailure(userId int) (int, error) {
+	var failures int
+
+	query := `
+		UPDATE 
+			users
+		SET 
+			failed_logins = failed_logins + 1
+		WHERE 
+			id = ?
+		RETURNING failed_logins
+	`
+	err := r.db.Raw(query, userId).Scan(&failures).Error
+	if err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+func (r *UserRepository) LockUntil(userId int, lockedUntil time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userId).Update("locked_until", lockedUntil).Error
+}
+
+func (r *UserRepository) ResetLoginFailures(userId int) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userId).Updates(map[string]any{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	}).Error
+}
+
+func (r *UserRepository) SetPendingEmail(userId int, email string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userId).Update("pending_email", email).Error
+}
+
+// ApplyPendingEmail makes the confirmed pending email the account's email.
+func (r *UserRepository) ApplyPendingEmail(userId int) error {
+	query := `
+		UPDATE 
+			users
+		SET 
+			email = pending_email,
+			pending_email = '',
+			email_verified_at = NOW()
+		WHERE 
+			id = ? AND pending_email != ''
+	`
+	result := r.db.Exec(query, userId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return auth.ErrInvalidUserToken
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateLogin(userId int, login string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userId).Update("login", login).Error
+}
+
+func (r *UserRepository) DeleteUser(userId int) error {
+	return r.db.Delete(&models.User{}, userId).Error
 }
 
 func (r *UserRepository) WithTx(tx *gorm.DB) auth.UserRepository {
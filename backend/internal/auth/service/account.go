@@ -0,0 +1,193 @@
+package service
+
+import (
+	"dimplom_harmonic/internal/auth"
+	"dimplom_harmonic/internal/mailer"
+	"errors"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// RequestEmailChange mails a confirmation link to the new address. The email
+// only changes once the link is opened, so a typo can't lock the user out.
+func (s *UserServiceImpl) RequestEmailChange(userId int, email, reauthToken string) error {
+	email = strings.TrimSpace(email)
+	if _, err := mail.ParseAddress(email); err != nil || len(email) > 64 {
+		return auth.ErrInvalidEmail
+	}
+
+	if err := s.CheckReauth(userId, reauthToken); err != nil {
+		return err
+	}
+
+	user, err := s.authRepo.GetByID(userId)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.authRepo.GetByEmail(email)
+	if err == nil {
+		return auth.ErrEmailTaken
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if err := s.authRepo.SetPendingEmail(userId, email); err != nil {
+		return err
+	}
+
+	token, err := s.issueUserToken(userId, auth.TokenChangeEmail, changeEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.appURL + "/confirm-email?token=" + url.QueryEscape(token)
+
+	err = s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Confirm your new email",
+		Body:    "Hi " + user.Login + ",\n\nConfirm your new email by opening this link:\n" + link + "\n\nThe link is valid for 24 hours.",
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your email is being changed",
+		Body:    "Hi " + user.Login + ",\n\nSomeone asked to change the email of your account to " + email + ". If it wasn't you, change your password.",
+	})
+	if err != nil {
+		log.Printf("Can't notify user %d about email change: %v", userId, err)
+	}
+	return nil
+}
+
+func (s *UserServiceImpl) ConfirmEmailChange(token string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.authRepo.WithTx(tx)
+
+		userId, err := s.useUserToken(txUserRepo, token, auth.TokenChangeEmail)
+		if err != nil {
+			return err
+		}
+
+		user, err := txUserRepo.GetByID(userId)
+		if err != nil {
+			return err
+		}
+
+		_, err = txUserRepo.GetByEmail(user.PendingEmail)
+		if err == nil {
+			return auth.ErrEmailTaken
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		return txUserRepo.ApplyPendingEmail(userId)
+	})
+}
+
+func (s *UserServiceImpl) ChangeLogin(userId int, login string) error {
+	login = strings.TrimSpace(login)
+	if len(login) < 3 || len(login) > 64 {
+		return auth.ErrInvalidLogin
+	}
+
+	exists, err := s.authRepo.LoginExists(login)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return auth.ErrLoginTaken
+	}
+
+	return s.authRepo.UpdateLogin(userId, login)
+}
+
+// ChangePassword sets a new password and revokes every session. The caller's
+// device gets a new session right away.
+func (s *UserServiceImpl) ChangePassword(userId int, currentPassword, newPassword string, meta auth.SessionMeta) (string, string, error) {
+	user, err := s.authRepo.GetByID(userId)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(strings.TrimSpace(currentPassword)))
+	if err != nil {
+		return "", "", auth.ErrWrongPassword
+	}
+
+	cleanPassword := strings.TrimSpace(newPassword)
+	if cleanPassword == "" {
+		return "", "", errors.New("password can't be empty")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(cleanPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.authRepo.WithTx(tx)
+
+		if err := txUserRepo.UpdatePassword(userId, string(hashedPassword)); err != nil {
+			return err
+		}
+		return txUserRepo.RevokeAllFamilies(userId)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	meta.FamilyId = ""
+	return s.GeneratePairTokens(user, meta)
+}
+
+// DeleteAccount removes the user with their decks, cards, word sets, schedules
+// and histories. Public word sets that others copied are kept without an
+// owner, and an active subscription is cancelled first.
+func (s *UserServiceImpl) DeleteAccount(userId int, reauthToken string) error {
+	if err := s.CheckReauth(userId, reauthToken); err != nil {
+		return err
+	}
+
+	err := s.payments.CancelSubscription(userId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.authRepo.WithTx(tx)
+		txWordSetRepo := s.wordSetRepo.WithTx(tx)
+		txDeckRepo := s.deckRepo.WithTx(tx)
+		txCardRepo := s.cardRepo.WithTx(tx)
+
+		cardIds, err := txCardRepo.GetAllUserCardIds(userId)
+		if err != nil {
+			return err
+		}
+
+		if _, err := txWordSetRepo.AnonymizeCopied(userId); err != nil {
+			return err
+		}
+		if err := txDeckRepo.PurgeUserDecks(userId); err != nil {
+			return err
+		}
+		if err := txWordSetRepo.PurgeUserWordSets(userId); err != nil {
+			return err
+		}
+		if err := txCardRepo.PurgeOrphanCards(cardIds); err != nil {
+			return err
+		}
+
+		return txUserRepo.DeleteUser(userId)
+	})
+}
@@ -2,14 +2,25 @@ package service
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	models "dimplom_harmonic/domain"
 	"dimplom_harmonic/internal/auth"
 	"dimplom_harmonic/internal/card"
 	"dimplom_harmonic/internal/deck"
+	"dimplom_harmonic/internal/entitlement"
+	"dimplom_harmonic/internal/events"
+	"dimplom_harmonic/internal/jwtkeys"
+	"dimplom_harmonic/internal/mailer"
+	"dimplom_harmonic/internal/payment"
 	"dimplom_harmonic/internal/schedule"
 	wordset "dimplom_harmonic/internal/wordSet"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
 	"strings"
 	"time"
 
@@ -24,14 +35,31 @@ type UserServiceImpl struct {
 	scheduleRepo schedule.ScheduleRepository
 	deckRepo     deck.DeckRepository
 	cardRepo     card.CardRepository
-	jwtKey       []byte
+	entitlements entitlement.EntitlementService
+	payments     payment.PaymentService
+	outboxRepo   events.OutboxRepository
+	mailer       mailer.Mailer
+	appURL       string
+	jwtKeys      *jwtkeys.KeySet
 	db           *gorm.DB
 }
 
-func NewUserService(authRepo auth.UserRepository, wordSetRepo wordset.WordSetRepository, scheduleRepo schedule.ScheduleRepository, deckRepo deck.DeckRepository, cardRepo card.CardRepository, jwtKey []byte, db *gorm.DB) auth.UserService {
-	return &UserServiceImpl{authRepo: authRepo, wordSetRepo: wordSetRepo, scheduleRepo: scheduleRepo, deckRepo: deckRepo, cardRepo: cardRepo, jwtKey: jwtKey, db: db}
+func NewUserService(authRepo auth.UserRepository, wordSetRepo wordset.WordSetRepository, scheduleRepo schedule.ScheduleRepository, deckRepo deck.DeckRepository, cardRepo card.CardRepository, entitlements entitlement.EntitlementService, payments payment.PaymentService, outboxRepo events.OutboxRepository, mailer mailer.Mailer, appURL string, jwtKeys *jwtkeys.KeySet, db *gorm.DB) auth.UserService {
+	return &UserServiceImpl{authRepo: authRepo, wordSetRepo: wordSetRepo, scheduleRepo: scheduleRepo, deckRepo: deckRepo, cardRepo: cardRepo, entitlements: entitlements, payments: payments, outboxRepo: outboxRepo, mailer: mailer, appURL: appURL, jwtKeys: jwtKeys, db: db}
 }
 
+const (
+	verifyEmailTTL    = 24 * time.Hour
+	resetPasswordTTL  = 1 * time.Hour
+	twoFactorLoginTTL = 5 * time.Minute
+	reauthTTL         = 5 * time.Minute
+	changeEmailTTL    = 24 * time.Hour
+
+	lockoutThreshold = 5
+	lockoutBase      = 1 * time.Minute
+	lockoutMax       = 1 * time.Hour
+)
+
 func GenerateRefreshToken() (string, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
@@ -40,18 +68,31 @@ func GenerateRefreshToken() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-func (s *UserServiceImpl) GeneratePairTokens(user *models.User) (string, string, error) {
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func GenerateFamilyId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *UserServiceImpl) GeneratePairTokens(user *models.User, meta auth.SessionMeta) (string, string, error) {
 
 	claims := models.AppClaims{
 		UserID:    user.Id,
 		Login:     user.Login,
-		IsPremium: user.PremiumExpiresAt.After(time.Now()),
+		IsPremium: s.entitlements.IsPremium(user),
+		Role:      user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Minute)),
 		},
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := accessToken.SignedString(s.jwtKey)
+	tokenString, err := s.jwtKeys.Sign(claims)
 	if err != nil {
 		return "", "", err
 	}
@@ -61,26 +102,101 @@ func (s *UserServiceImpl) GeneratePairTokens(user *models.User) (string, string,
 		return "", "", err
 	}
 
-	expireAt := time.Now().Add(30 * 24 * time.Hour)
-	s.authRepo.SaveRefreshToken(user.Id, refreshToken, expireAt)
+	if meta.FamilyId == "" {
+		meta.FamilyId, err = GenerateFamilyId()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	err = s.authRepo.SaveRefreshToken(&models.RefreshToken{
+		UserId:    user.Id,
+		Token:     refreshToken,
+		FamilyId:  meta.FamilyId,
+		UserAgent: meta.UserAgent,
+		Ip:        meta.Ip,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(30 * 24 * time.Hour),
+	})
+	if err != nil {
+		return "", "", err
+	}
 
 	return tokenString, refreshToken, nil
 }
 
-func (s *UserServiceImpl) LoginUser(email, password string) (string, string, error) {
+func (s *UserServiceImpl) LoginUser(email, password string, meta auth.SessionMeta) (*auth.LoginResult, error) {
 
 	user, err := s.authRepo.GetByEmail(email)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		return nil, &auth.LockedError{Until: *user.LockedUntil}
 	}
 
 	cleanPassword := strings.TrimSpace(password)
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(cleanPassword))
 	if err != nil {
-		return "", "", err
+		if lockErr := s.recordLoginFailure(user.Id); lockErr != nil {
+			return nil, lockErr
+		}
+		return nil, err
 	}
 
-	return s.GeneratePairTokens(user)
+	// With 2FA the failures are reset once the second factor is checked, so
+	// a known password doesn't buy unlimited code guesses.
+	if user.FailedLogins != 0 && user.TotpEnabledAt == nil {
+		if err := s.authRepo.ResetLoginFailures(user.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.completeLogin(user, meta)
+}
+
+// recordLoginFailure counts a wrong password or second factor. From lockoutThreshold failures
+// on, the account is locked for lockoutBase, doubling with every further
+// failure up to lockoutMax.
+func (s *UserServiceImpl) recordLoginFailure(userId int) error {
+	failures, err := s.authRepo.RecordLoginFailure(userId)
+	if err != nil {
+		return err
+	}
+	if failures < lockoutThreshold {
+		return nil
+	}
+
+	lockFor := lockoutMax
+	if shift := failures - lockoutThreshold; shift < 10 {
+		lockFor = min(lockoutBase<<shift, lockoutMax)
+	}
+
+	lockedUntil := time.Now().Add(lockFor)
+	if err := s.authRepo.LockUntil(userId, lockedUntil); err != nil {
+		return err
+	}
+	return &auth.LockedError{Until: lockedUntil}
+}
+
+// completeLogin issues the token pair once the first factor is checked, or a
+// short-lived challenge token when the user still has to enter a 2FA code.
+func (s *UserServiceImpl) completeLogin(user *models.User, meta auth.SessionMeta) (*auth.LoginResult, error) {
+	if user.TotpEnabledAt != nil {
+		challenge, err := s.issueUserToken(user.Id, auth.TokenTwoFactor, twoFactorLoginTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &auth.LoginResult{TwoFactorToken: challenge}, nil
+	}
+
+	meta.FamilyId = ""
+	accessToken, refreshToken, err := s.GeneratePairTokens(user, meta)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.LoginResult{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
 func (s *UserServiceImpl) RegisterUser(input models.User) (*models.User, error) {
@@ -102,7 +218,24 @@ func (s *UserServiceImpl) RegisterUser(input models.User) (*models.User, error)
 		PasswordHash: string(hashedPassowrd),
 	}
 
-	err = s.db.Transaction(func(tx *gorm.DB) error {
+	err = s.createAccount(newUser, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.SendEmailVerification(newUser.Id); err != nil {
+		log.Printf("Can't send verification email to user %d: %v", newUser.Id, err)
+	}
+
+	return newUser, nil
+}
+
+// createAccount creates the user with the default word set and schedule, and
+// links the external identity when the account comes from a login provider.
+func (s *UserServiceImpl) createAccount(newUser *models.User, identity *auth.ExternalIdentity) error {
+	newUser.Role = models.RoleUser
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
 		txUserRepo := s.authRepo.WithTx(tx)
 		txWordSetRepo := s.wordSetRepo.WithTx(tx)
 		txSceduleRepo := s.scheduleRepo.WithTx(tx)
@@ -111,13 +244,26 @@ func (s *UserServiceImpl) RegisterUser(input models.User) (*models.User, error)
 			return err
 		}
 
+		if identity != nil {
+			err := txUserRepo.CreateIdentity(&models.UserIdentity{
+				UserId:    newUser.Id,
+				Provider:  identity.Provider,
+				Subject:   identity.Subject,
+				Email:     identity.Email,
+				CreatedAt: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
 		defaultWordSet := models.WordSet{
 			UserId:    newUser.Id,
 			Name:      "Difficult words",
 			IsPublic:  false,
 			IsDefault: true,
 		}
-		if err = txWordSetRepo.CreateWordSet(&defaultWordSet); err != nil {
+		if err := txWordSetRepo.CreateWordSet(&defaultWordSet); err != nil {
 			return err
 		}
 
@@ -127,7 +273,7 @@ func (s *UserServiceImpl) RegisterUser(input models.User) (*models.User, error)
 			IsDefault: true,
 		}
 
-		if err = txSceduleRepo.CreateSchedule(&defaultSchedule); err != nil {
+		if err := txSceduleRepo.CreateSchedule(&defaultSchedule); err != nil {
 			return err
 		}
 
@@ -144,44 +290,43 @@ func (s *UserServiceImpl) RegisterUser(input models.User) (*models.User, error)
 			standartIntervals = append(standartIntervals, interval)
 		}
 
-		err = txSceduleRepo.CreateScheduleInterval(standartIntervals)
-		if err != nil {
+		if err := txSceduleRepo.CreateScheduleInterval(standartIntervals); err != nil {
 			return err
 		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
-	}
 
-	return newUser, nil
+		return events.Record(s.outboxRepo.WithTx(tx), events.UserRegistered{
+			UserId:       newUser.Id,
+			Login:        newUser.Login,
+			Email:        newUser.Email,
+			RegisteredAt: time.Now(),
+		})
+	})
 }
 
-func (s *UserServiceImpl) GetProfile(id int) (*auth.Profile, *auth.Stats, error) {
+func (s *UserServiceImpl) GetProfile(id int) (*auth.Profile, *auth.Stats, *entitlement.Quota, error) {
 
 	user, err := s.authRepo.GetByID(id)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	deckStats, err := s.deckRepo.GetDeckStatsForUser(user.Id)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	cardStats, err := s.cardRepo.GetUserCardStats(user.Id)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	var status string
-	if user.PremiumExpiresAt.After(time.Now().Add(time.Hour * 24 * 365 * 100)) {
-		status = "lifetime"
-	} else if user.PremiumExpiresAt.After(time.Now()) {
-		status = "premium"
-	} else {
-		status = "free"
+	quota, err := s.entitlements.GetQuota(user.Id)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+
+	status := s.entitlements.Status(user)
+
 	var profile auth.Profile
 	var stats auth.Stats
 
@@ -190,6 +335,7 @@ func (s *UserServiceImpl) GetProfile(id int) (*auth.Profile, *auth.Stats, error)
 	profile.Login = user.Login
 	profile.PremiumExpiresAt = user.PremiumExpiresAt
 	profile.Status = status
+	profile.EmailVerified = user.EmailVerifiedAt != nil
 
 	stats.ActiveDecksCount = deckStats.ActiveDecks
 	stats.ArchivedDecksCount = deckStats.ArchivedDecks
@@ -197,58 +343,412 @@ func (s *UserServiceImpl) GetProfile(id int) (*auth.Profile, *auth.Stats, error)
 	stats.TotalWordsLearning = cardStats.Learning
 	stats.TotalWordsMastered = cardStats.Mastered
 
-	return &profile, &stats, nil
+	return &profile, &stats, quota, nil
 }
 
-func (s *UserServiceImpl) MockPayment(userId int, period string) error {
-	user, err := s.authRepo.GetByID(userId)
+// RefreshToken rotates a refresh token within its family. Presenting a token
+// that was already rotated or revoked means it leaked, so the whole family is
+// revoked and the device has to log in again.
+func (s *UserServiceImpl) RefreshToken(oldToken string, meta auth.SessionMeta) (string, string, error) {
+	rt, err := s.authRepo.GetRefreshToken(oldToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	if rt.UsedAt != nil || rt.RevokedAt != nil {
+		return "", "", s.revokeReusedFamily(rt)
+	}
+
+	if rt.ExpiresAt.Before(time.Now()) {
+		return "", "", errors.New("refresh token expired")
+	}
+
+	rotated, err := s.authRepo.MarkRefreshTokenUsed(rt.Id)
+	if err != nil {
+		return "", "", err
+	}
+	if !rotated {
+		return "", "", s.revokeReusedFamily(rt)
+	}
+
+	user, err := s.authRepo.GetByID(rt.UserId)
+	if err != nil {
+		return "", "", err
+	}
+
+	meta.FamilyId = rt.FamilyId
+	return s.GeneratePairTokens(user, meta)
+}
+
+func (s *UserServiceImpl) revokeReusedFamily(rt *models.RefreshToken) error {
+	log.Printf("Refresh token reuse for user %d, revoking session %s", rt.UserId, rt.FamilyId)
+
+	_, err := s.authRepo.RevokeFamily(rt.UserId, rt.FamilyId)
 	if err != nil {
 		return err
 	}
+	return auth.ErrRefreshTokenReused
+}
 
-	now := time.Now()
-	if user.PremiumExpiresAt.After(now) {
-		now = user.PremiumExpiresAt
+// DeleteRefreshToken logs out the session the token belongs to.
+func (s *UserServiceImpl) DeleteRefreshToken(token string) error {
+	rt, err := s.authRepo.GetRefreshToken(token)
+	if err != nil {
+		return err
 	}
 
-	var newTime time.Time
+	_, err = s.authRepo.RevokeFamily(rt.UserId, rt.FamilyId)
+	return err
+}
 
-	switch period {
-	case "month":
-		newTime = now.AddDate(0, 1, 0)
-	case "year":
-		newTime = now.AddDate(1, 0, 0)
-	case "lifetime":
-		newTime = now.AddDate(1000, 0, 0)
+func (s *UserServiceImpl) GetSessions(userId int, currentToken string) ([]auth.SessionDTO, error) {
+	sessions, err := s.authRepo.GetActiveSessions(userId)
+	if err != nil {
+		return nil, err
 	}
 
-	err = s.authRepo.MockPayment(userId, newTime)
+	return auth.SessionsResultTo(sessions, currentToken), nil
+}
 
+func (s *UserServiceImpl) RevokeSession(userId int, sessionId string) error {
+	revoked, err := s.authRepo.RevokeFamily(userId, sessionId)
 	if err != nil {
 		return err
 	}
+	if revoked == 0 {
+		return gorm.ErrRecordNotFound
+	}
 	return nil
 }
 
-func (s *UserServiceImpl) RefreshToken(oldToken string) (string, string, error) {
-	rt, err := s.authRepo.GetRefreshToken(oldToken)
+func (s *UserServiceImpl) RevokeAllSessions(userId int) error {
+	return s.authRepo.RevokeAllFamilies(userId)
+}
+
+// issueUserToken creates a single-use token for the purpose, invalidating the
+// ones issued before. Only the hash is stored; the plain token goes by email.
+func (s *UserServiceImpl) issueUserToken(userId int, purpose string, ttl time.Duration) (string, error) {
+	token, err := GenerateRefreshToken()
 	if err != nil {
-		return "", "", err
+		return "", err
 	}
-	user, err := s.authRepo.GetByID(rt.UserId)
 
-	err = s.authRepo.DeleteRefreshToken(oldToken)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.authRepo.WithTx(tx)
+
+		if err := txUserRepo.InvalidateUserTokens(userId, purpose); err != nil {
+			return err
+		}
+
+		return txUserRepo.CreateUserToken(&models.UserToken{
+			UserId:    userId,
+			Purpose:   purpose,
+			TokenHash: HashToken(token),
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	})
 	if err != nil {
-		return "", "", err
+		return "", err
 	}
 
-	return s.GeneratePairTokens(user)
+	return token, nil
 }
 
-func (s *UserServiceImpl) DeleteRefreshToken(token string) error {
-	err := s.authRepo.DeleteRefreshToken(token)
+// useUserToken consumes a token and returns the user it was issued to.
+func (s *UserServiceImpl) useUserToken(authRepo auth.UserRepository, token, purpose string) (int, error) {
+	userToken, err := authRepo.GetUserToken(HashToken(token), purpose)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, auth.ErrInvalidUserToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	used, err := authRepo.UseUserToken(userToken.Id)
+	if err != nil {
+		return 0, err
+	}
+	if !used {
+		return 0, auth.ErrInvalidUserToken
+	}
+
+	return userToken.UserId, nil
+}
+
+func (s *UserServiceImpl) SendEmailVerification(userId int) error {
+	user, err := s.authRepo.GetByID(userId)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return errors.New("email is already verified")
+	}
+
+	token, err := s.issueUserToken(user.Id, auth.TokenVerifyEmail, verifyEmailTTL)
 	if err != nil {
 		return err
 	}
+
+	link := s.appURL + "/verify-email?token=" + url.QueryEscape(token)
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body:    "Hi " + user.Login + ",\n\nConfirm your email by opening this link:\n" + link + "\n\nThe link is valid for 24 hours.",
+	})
+}
+
+func (s *UserServiceImpl) VerifyEmail(token string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.authRepo.WithTx(tx)
+
+		userId, err := s.useUserToken(txUserRepo, token, auth.TokenVerifyEmail)
+		if err != nil {
+			return err
+		}
+
+		return txUserRepo.SetEmailVerified(userId, time.Now())
+	})
+}
+
+// RequestPasswordReset mails a reset link. Unknown emails are ignored without
+// an error, so the endpoint doesn't reveal who is registered.
+func (s *UserServiceImpl) RequestPasswordReset(email string) error {
+	user, err := s.authRepo.GetByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.issueUserToken(user.Id, auth.TokenResetPassword, resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.appURL + "/reset-password?token=" + url.QueryEscape(token)
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    "Hi " + user.Login + ",\n\nSet a new password by opening this link:\n" + link + "\n\nThe link is valid for 1 hour. If you didn't ask for it, ignore this email.",
+	})
+}
+
+// ResetPassword sets a new password and logs out every session.
+func (s *UserServiceImpl) ResetPassword(token, password string) error {
+	cleanPassword := strings.TrimSpace(password)
+	if cleanPassword == "" {
+		return errors.New("password can't be empty")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(cleanPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.authRepo.WithTx(tx)
+
+		userId, err := s.useUserToken(txUserRepo, token, auth.TokenResetPassword)
+		if err != nil {
+			return err
+		}
+
+		if err := txUserRepo.UpdatePassword(userId, string(hashedPassword)); err != nil {
+			return err
+		}
+
+		if err := txUserRepo.ResetLoginFailures(userId); err != nil {
+			return err
+		}
+
+		return txUserRepo.RevokeAllFamilies(userId)
+	})
+}
+
+// OAuthLogin logs in with an external identity. An unknown identity is
+// linked to the account with the same email, or a new account is created.
+// Both need an email the provider has verified.
+func (s *UserServiceImpl) OAuthLogin(identity auth.ExternalIdentity, meta auth.SessionMeta) (*auth.LoginResult, error) {
+	var user *models.User
+
+	linked, err := s.authRepo.GetIdentity(identity.Provider, identity.Subject)
+	switch {
+	case err == nil:
+		user, err = s.authRepo.GetByID(linked.UserId)
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if identity.Email == "" || !identity.EmailVerified {
+			return nil, auth.ErrProviderEmailNotVerified
+		}
+
+		user, err = s.authRepo.GetByEmail(identity.Email)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user, err = s.registerFromIdentity(identity)
+		} else if err == nil {
+			err = s.linkByEmail(user, identity)
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	return s.completeLogin(user, meta)
+}
+
+func (s *UserServiceImpl) registerFromIdentity(identity auth.ExternalIdentity) (*models.User, error) {
+	login, err := s.freeLogin(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	verifiedAt := time.Now()
+	newUser := &models.User{
+		Email:           identity.Email,
+		Login:           login,
+		EmailVerifiedAt: &verifiedAt,
+	}
+
+	err = s.createAccount(newUser, &identity)
+	if err != nil {
+		return nil, err
+	}
+	return newUser, nil
+}
+
+// linkByEmail links the identity to an existing account. If the account's
+// email was never verified, whoever registered it may not own the address;
+// the provider has just proved that the caller does, so the password is
+// dropped and the existing sessions are revoked.
+func (s *UserServiceImpl) linkByEmail(user *models.User, identity auth.ExternalIdentity) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.authRepo.WithTx(tx)
+
+		if user.EmailVerifiedAt == nil {
+			if err := txUserRepo.UpdatePassword(user.Id, ""); err != nil {
+				return err
+			}
+			if err := txUserRepo.RevokeAllFamilies(user.Id); err != nil {
+				return err
+			}
+			if err := txUserRepo.SetEmailVerified(user.Id, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		return txUserRepo.CreateIdentity(&models.UserIdentity{
+			UserId:    user.Id,
+			Provider:  identity.Provider,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: time.Now(),
+		})
+	})
+}
+
+// freeLogin picks a login for a new account from the email's local part,
+// adding a number when it is taken.
+func (s *UserServiceImpl) freeLogin(identity auth.ExternalIdentity) (string, error) {
+	base := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, strings.Split(identity.Email, "@")[0])
+	if base == "" {
+		base = "user"
+	}
+
+	login := base
+	for i := 0; i < 10; i++ {
+		exists, err := s.authRepo.LoginExists(login)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return login, nil
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		login = fmt.Sprintf("%s%04d", base, n.Int64())
+	}
+	return "", errors.New("can't pick a free login")
+}
+
+func (s *UserServiceImpl) LinkIdentity(userId int, identity auth.ExternalIdentity) error {
+	linked, err := s.authRepo.GetIdentity(identity.Provider, identity.Subject)
+	if err == nil {
+		if linked.UserId == userId {
+			return nil
+		}
+		return auth.ErrIdentityLinked
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	identities, err := s.authRepo.GetIdentities(userId)
+	if err != nil {
+		return err
+	}
+	for _, value := range identities {
+		if value.Provider == identity.Provider {
+			return auth.ErrIdentityLinked
+		}
+	}
+
+	return s.authRepo.CreateIdentity(&models.UserIdentity{
+		UserId:    userId,
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: time.Now(),
+	})
+}
+
+// UnlinkIdentity refuses to remove the only way the user can log in. Accounts
+// created through a provider have no password until one is set with a reset.
+func (s *UserServiceImpl) UnlinkIdentity(userId int, provider string) error {
+	user, err := s.authRepo.GetByID(userId)
+	if err != nil {
+		return err
+	}
+
+	identities, err := s.authRepo.GetIdentities(userId)
+	if err != nil {
+		return err
+	}
+
+	if user.PasswordHash == "" && len(identities) <= 1 {
+		return auth.ErrLastLoginMethod
+	}
+
+	deleted, err := s.authRepo.DeleteIdentity(userId, provider)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return gorm.ErrRecordNotFound
+	}
 	return nil
 }
+
+func (s *UserServiceImpl) GetIdentities(userId int) ([]auth.IdentityDTO, error) {
+	identities, err := s.authRepo.GetIdentities(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.IdentitiesModelTo(identities), nil
+}
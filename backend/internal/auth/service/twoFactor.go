@@ -0,0 +1,338 @@
+package service
+
+import (
+	"crypto/rand"
+	"dimplom_harmonic/internal/auth"
+	"dimplom_harmonic/internal/totp"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer = "Memofold"
+
+	recoveryCodesCount = 10
+
+	// twoFactorMaxAttempts is how many wrong codes a login challenge takes
+	// before it is burned and the password has to be entered again.
+	twoFactorMaxAttempts = 5
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns codes like "abcd-efgh-ijkl-mnop" (80 random
+// bits each) with their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+		hashes = append(hashes, HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// checkSecondFactor accepts a current TOTP code or an unused recovery code.
+func (s *UserServiceImpl) checkSecondFactor(authRepo auth.UserRepository, userId int, code string) error {
+	user, err := authRepo.GetByID(userId)
+	if err != nil {
+		return err
+	}
+	if user.TotpEnabledAt == nil {
+		return auth.ErrTwoFactorNotSetup
+	}
+
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(user.TotpSecret, code, time.Now()); ok {
+		used, err := authRepo.UseTotpStep(user.Id, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return auth.ErrTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := authRepo.UseRecoveryCode(user.Id, HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return auth.ErrTwoFactorCode
+	}
+	return nil
+}
+
+// VerifyTwoFactorLogin finishes a login started by LoginUser or OAuthLogin.
+// Wrong codes count towards the account lockout like wrong passwords.
+func (s *UserServiceImpl) VerifyTwoFactorLogin(challengeToken, code string, meta auth.SessionMeta) (string, string, error) {
+	challenge, err := s.authRepo.GetUserToken(HashToken(challengeToken), auth.TokenTwoFactor)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", "", auth.ErrInvalidUserToken
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if challenge.UsedAt != nil || challenge.ExpiresAt.Before(time.Now()) {
+		return "", "", auth.ErrInvalidUserToken
+	}
+
+	user, err := s.authRepo.GetByID(challenge.UserId)
+	if err != nil {
+		return "", "", err
+	}
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		return "", "", &auth.LockedError{Until: *user.LockedUntil}
+	}
+
+	err = s.checkSecondFactor(s.authRepo, user.Id, code)
+	if errors.Is(err, auth.ErrTwoFactorCode) {
+		if lockErr := s.recordLoginFailure(user.Id); lockErr != nil {
+			return "", "", lockErr
+		}
+		attempts, incErr := s.authRepo.IncrementUserTokenAttempts(challenge.Id)
+		if incErr != nil {
+			return "", "", incErr
+		}
+		if attempts >= twoFactorMaxAttempts {
+			if _, useErr := s.authRepo.UseUserToken(challenge.Id); useErr != nil {
+				return "", "", useErr
+			}
+		}
+		return "", "", err
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	used, err := s.authRepo.UseUserToken(challenge.Id)
+	if err != nil {
+		return "", "", err
+	}
+	if !used {
+		return "", "", auth.ErrInvalidUserToken
+	}
+
+	if user.FailedLogins != 0 {
+		if err := s.authRepo.ResetLoginFailures(user.Id); err != nil {
+			return "", "", err
+		}
+	}
+
+	meta.FamilyId = ""
+	return s.GeneratePairTokens(user, meta)
+}
+
+func (s *UserServiceImpl) GetTwoFactorStatus(userId int) (*auth.TwoFactorStatusDTO, error) {
+	user, err := s.authRepo.GetByID(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var status auth.TwoFactorStatusDTO
+	if user.TotpEnabledAt == nil {
+		return &status, nil
+	}
+
+	left, err := s.authRepo.CountRecoveryCodes(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Enabled = true
+	status.RecoveryCodesLeft = left
+	return &status, nil
+}
+
+// SetupTwoFactor generates a new secret. 2FA stays off until EnableTwoFactor
+// confirms the authenticator produces valid codes for it.
+func (s *UserServiceImpl) SetupTwoFactor(userId int) (*auth.TwoFactorSetupDTO, error) {
+	user, err := s.authRepo.GetByID(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TotpEnabledAt != nil {
+		return nil, auth.ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authRepo.SetTotpSecret(userId, secret); err != nil {
+		return nil, err
+	}
+
+	return &auth.TwoFactorSetupDTO{
+		Secret:     secret,
+		OtpauthURI: totp.URI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// EnableTwoFactor turns 2FA on and returns the recovery codes. They are shown
+// only this once.
+func (s *UserServiceImpl) EnableTwoFactor(userId int, code string) ([]string, error) {
+	user, err := s.authRepo.GetByID(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TotpEnabledAt != nil {
+		return nil, auth.ErrTwoFactorEnabled
+	}
+	if user.TotpSecret == "" {
+		return nil, auth.ErrTwoFactorNotSetup
+	}
+
+	step, ok := totp.Validate(user.TotpSecret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, auth.ErrTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.authRepo.WithTx(tx)
+
+		if _, err := txUserRepo.UseTotpStep(userId, step); err != nil {
+			return err
+		}
+		if err := txUserRepo.ReplaceRecoveryCodes(userId, hashes); err != nil {
+			return err
+		}
+		return txUserRepo.EnableTotp(userId, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *UserServiceImpl) DisableTwoFactor(userId int, reauthToken string) error {
+	if err := s.CheckReauth(userId, reauthToken); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.authRepo.WithTx(tx).DisableTotp(userId)
+	})
+}
+
+func (s *UserServiceImpl) RegenerateRecoveryCodes(userId int, reauthToken string) ([]string, error) {
+	if err := s.CheckReauth(userId, reauthToken); err != nil {
+		return nil, err
+	}
+
+	user, err := s.authRepo.GetByID(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TotpEnabledAt == nil {
+		return nil, auth.ErrTwoFactorNotSetup
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return s.authRepo.WithTx(tx).ReplaceRecoveryCodes(userId, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reauthenticate checks the password and, with 2FA on, a code again, and
+// returns a short-lived single-use token for one sensitive action. Accounts
+// with neither (created through a login provider) have to set a password
+// first. Wrong passwords and codes count toward the login lockout, so a stolen
+// access token can't be used to guess them.
+func (s *UserServiceImpl) Reauthenticate(userId int, password, code string) (string, error) {
+	user, err := s.authRepo.GetByID(userId)
+	if err != nil {
+		return "", err
+	}
+
+	if user.PasswordHash == "" && user.TotpEnabledAt == nil {
+		return "", auth.ErrReauthNotAvailable
+	}
+
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		return "", &auth.LockedError{Until: *user.LockedUntil}
+	}
+
+	if user.PasswordHash != "" {
+		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(strings.TrimSpace(password)))
+		if err != nil {
+			if lockErr := s.recordLoginFailure(userId); lockErr != nil {
+				return "", lockErr
+			}
+			return "", auth.ErrReauthRequired
+		}
+	}
+
+	if user.TotpEnabledAt != nil {
+		err := s.checkSecondFactor(s.authRepo, userId, code)
+		if errors.Is(err, auth.ErrTwoFactorCode) {
+			if lockErr := s.recordLoginFailure(userId); lockErr != nil {
+				return "", lockErr
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if user.FailedLogins != 0 {
+		if err := s.authRepo.ResetLoginFailures(userId); err != nil {
+			return "", err
+		}
+	}
+
+	return s.issueUserToken(userId, auth.TokenReauth, reauthTTL)
+}
+
+// CheckReauth consumes a token from Reauthenticate. Services call it before
+// sensitive actions.
+func (s *UserServiceImpl) CheckReauth(userId int, reauthToken string) error {
+	if reauthToken == "" {
+		return auth.ErrReauthRequired
+	}
+
+	tokenUserId, err := s.useUserToken(s.authRepo, reauthToken, auth.TokenReauth)
+	if errors.Is(err, auth.ErrInvalidUserToken) {
+		return auth.ErrReauthRequired
+	}
+	if err != nil {
+		return err
+	}
+	if tokenUserId != userId {
+		return auth.ErrReauthRequired
+	}
+	return nil
+}
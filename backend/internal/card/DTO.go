@@ -90,6 +90,7 @@ type UpdateCardDTO struct {
 	OriginalContext    string `json:"originalContext"`
 	TranslationContext string `json:"translationContext"`
 	IsLearning         bool   `json:"isLearning"`
+	Version            int    `json:"version"`
 }
 
 func UpdateCardToModel(c *UpdateCardDTO) models.Card {
@@ -99,6 +100,7 @@ func UpdateCardToModel(c *UpdateCardDTO) models.Card {
 		Translation:        c.Translation,
 		OriginalContext:    c.OriginalContext,
 		TranslationContext: c.TranslationContext,
+		Version:            c.Version,
 	}
 }
 
@@ -110,6 +112,7 @@ func UpdateCardModelTo(m *models.Card) UpdateCardDTO {
 		OriginalContext:    m.OriginalContext,
 		TranslationContext: m.TranslationContext,
 		IsLearning:         m.IsLearning,
+		Version:            m.Version,
 	}
 }
 
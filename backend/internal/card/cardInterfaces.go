@@ -9,7 +9,7 @@ import (
 type CardService interface {
 	CreateCard(card models.Card, userId int) (*models.Card, error)
 	DeleteCard(deleteCard DeleteCardParam) error
-	UpdateCard(input models.Card) (*models.Card, error)
+	UpdateCard(userId int, input models.Card) (*models.Card, error)
 	CreateHardCards(ids CreateHardWordsDTO, userId int) error
 }
 
@@ -23,14 +23,21 @@ type CardRepository interface {
 	DeleteCard(cardId int) error
 
 	DeleteHistories(userId, deckId int) error
-	UpdateCard(cardId int, changeCard map[string]any) error
+	MoveHistories(fromDeckId, toDeckId int, cardIds []int) error
+	UpdateCard(cardId, version int, changeCard map[string]any) (int, error)
 
 	GetUserCardStats(userId int) (*GetUserCardStats, error)
+	GetOwnedCardIds(userId int, cardIds []int) ([]int, error)
+	GetDeleted(userId int) ([]models.Card, error)
+	Restore(userId, cardId int) error
 	DeleteCardFromDefaultWordSet(wordSetId int, cards []int) error
+	GetAllUserCardIds(userId int) ([]int, error)
+	PurgeOrphanCards(cardIds []int) error
 	WithTx(tx *gorm.DB) CardRepository
 }
 
 type DeleteCardParam struct {
+	UserId    int
 	Id        int
 	DeckId    *int
 	WordSetId *int
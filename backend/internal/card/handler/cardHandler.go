@@ -2,12 +2,16 @@ package handler
 
 import (
 	"dimplom_harmonic/internal/card"
+	"dimplom_harmonic/internal/concurrency"
 	"dimplom_harmonic/internal/middleware"
+	wordset "dimplom_harmonic/internal/wordSet"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
 type CardHandler struct {
@@ -18,6 +22,19 @@ func NewCardHandler(service card.CardService) *CardHandler {
 	return &CardHandler{service: service}
 }
 
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, wordset.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, concurrency.ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
 func (h *CardHandler) HDCreateCard(w http.ResponseWriter, r *http.Request) {
 	userId := r.Context().Value(middleware.UserIDKey).(int)
 	var input card.CreateCardRequestDTO
@@ -29,7 +46,7 @@ func (h *CardHandler) HDCreateCard(w http.ResponseWriter, r *http.Request) {
 	createCard, err := h.service.CreateCard(card.CreateCardToModel(&input), userId)
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err)
 		return
 
 	}
@@ -39,6 +56,7 @@ func (h *CardHandler) HDCreateCard(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *CardHandler) HDDeleteCard(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
 	cardIdStr := chi.URLParam(r, "cardID")
 	deckIdStr := r.URL.Query().Get("deckID")
 	wordSetIdStr := r.URL.Query().Get("wordSetID")
@@ -50,6 +68,7 @@ func (h *CardHandler) HDDeleteCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	deleteCard.UserId = userId
 	deleteCard.Id = cardId
 	if deckIdStr != "" {
 		deckId, err := strconv.Atoi(deckIdStr)
@@ -69,11 +88,16 @@ func (h *CardHandler) HDDeleteCard(w http.ResponseWriter, r *http.Request) {
 		deleteCard.WordSetId = &wordSetId
 	}
 	err = h.service.DeleteCard(deleteCard)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
 func (h *CardHandler) HDUpdateCard(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
 	var input card.UpdateCardDTO
 	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
@@ -81,12 +105,24 @@ func (h *CardHandler) HDUpdateCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	changedCard, err := h.service.UpdateCard(card.UpdateCardToModel(&input))
+	version, fromHeader, err := concurrency.ExpectedVersion(r, input.Version)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	input.Version = version
+
+	changedCard, err := h.service.UpdateCard(userId, card.UpdateCardToModel(&input))
+	if errors.Is(err, concurrency.ErrVersionConflict) {
+		concurrency.WriteConflict(w, fromHeader)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
+	concurrency.SetETag(w, changedCard.Version)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(card.UpdateCardModelTo(changedCard))
 }
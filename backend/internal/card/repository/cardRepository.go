@@ -3,9 +3,11 @@ package repository
 import (
 	models "dimplom_harmonic/domain"
 	"dimplom_harmonic/internal/card"
+	"dimplom_harmonic/internal/concurrency"
 	"log"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CardRepository struct {
@@ -85,8 +87,42 @@ func (r *CardRepository) DeleteHistories(userId, deckId int) error {
 	return nil
 }
 
-func (r *CardRepository) UpdateCard(cardId int, changeCard map[string]any) error {
-	return r.db.Model(&models.Card{}).Where("id = ?", cardId).Updates(changeCard).Error
+func (r *CardRepository) MoveHistories(fromDeckId, toDeckId int, cardIds []int) error {
+	query := `
+		UPDATE 
+			card_histories
+		SET 
+			deck_id = ?
+		WHERE 
+			deck_id = ? AND card_id IN ?
+	`
+	return r.db.Exec(query, toDeckId, fromDeckId, cardIds).Error
+}
+
+// UpdateCard bumps the card's version and returns the new one. A non-zero
+// version makes the update conditional on the card still having it.
+func (r *CardRepository) UpdateCard(cardId, version int, changeCard map[string]any) (int, error) {
+	var updated models.Card
+
+	query := r.db.Model(&updated).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "version"}}}).
+		Where("id = ?", cardId)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	changeCard["version"] = gorm.Expr("version + 1")
+	result := query.Updates(changeCard)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if version != 0 {
+			return 0, concurrency.ErrVersionConflict
+		}
+		return 0, gorm.ErrRecordNotFound
+	}
+	return updated.Version, nil
 }
 
 func (r *CardRepository) GetUserCardStats(userId int) (*card.GetUserCardStats, error) {
@@ -100,8 +136,11 @@ func (r *CardRepository) GetUserCardStats(userId int) (*card.GetUserCardStats, e
 			decks d 
 		JOIN 
 			deck_cards dc ON dc.deck_id = d.id
+		JOIN 
+			cards c ON c.id = dc.card_id
 		WHERE 
 			d.id = dc.deck_id AND d.user_id = ?
+			AND d.deleted_at IS NULL AND c.deleted_at IS NULL
 
 	`
 	err := r.db.Raw(query, userId).Scan(&res).Error
@@ -113,6 +152,102 @@ func (r *CardRepository) GetUserCardStats(userId int) (*card.GetUserCardStats, e
 	return &res, nil
 }
 
+func (r *CardRepository) GetOwnedCardIds(userId int, cardIds []int) ([]int, error) {
+	var ids []int
+
+	query := `
+		SELECT 
+			c.id
+		FROM 
+			cards c
+		WHERE 
+			c.id IN ?
+			AND c.deleted_at IS NULL
+			AND (
+				EXISTS (
+					SELECT 1 FROM set_to_card_link l 
+					JOIN word_sets w ON w.id = l.word_set_id 
+					WHERE l.card_id = c.id AND w.user_id = ? AND w.deleted_at IS NULL
+				)
+				OR EXISTS (
+					SELECT 1 FROM deck_cards dc 
+					JOIN decks d ON d.id = dc.deck_id 
+					WHERE dc.card_id = c.id AND d.user_id = ? AND d.deleted_at IS NULL
+				)
+			)
+	`
+	err := r.db.Raw(query, cardIds, userId, userId).Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *CardRepository) GetDeleted(userId int) ([]models.Card, error) {
+	var cards []models.Card
+
+	query := `
+		SELECT 
+			c.*
+		FROM 
+			cards c
+		WHERE 
+			c.deleted_at IS NOT NULL
+			AND (
+				EXISTS (
+					SELECT 1 FROM set_to_card_link l 
+					JOIN word_sets w ON w.id = l.word_set_id 
+					WHERE l.card_id = c.id AND w.user_id = ?
+				)
+				OR EXISTS (
+					SELECT 1 FROM deck_cards dc 
+					JOIN decks d ON d.id = dc.deck_id 
+					WHERE dc.card_id = c.id AND d.user_id = ?
+				)
+			)
+		ORDER BY 
+			c.deleted_at DESC
+	`
+	err := r.db.Raw(query, userId, userId).Scan(&cards).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return cards, nil
+}
+
+func (r *CardRepository) Restore(userId, cardId int) error {
+	query := `
+		UPDATE 
+			cards c
+		SET 
+			deleted_at = NULL
+		WHERE 
+			c.id = ? AND c.deleted_at IS NOT NULL
+			AND (
+				EXISTS (
+					SELECT 1 FROM set_to_card_link l 
+					JOIN word_sets w ON w.id = l.word_set_id 
+					WHERE l.card_id = c.id AND w.user_id = ?
+				)
+				OR EXISTS (
+					SELECT 1 FROM deck_cards dc 
+					JOIN decks d ON d.id = dc.deck_id 
+					WHERE dc.card_id = c.id AND d.user_id = ?
+				)
+			)
+	`
+	result := r.db.Exec(query, cardId, userId, userId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func (r *CardRepository) DeleteCardFromDefaultWordSet(wordSetId int, cards []int) error {
 	query := `
 		DELETE 
@@ -129,6 +264,53 @@ func (r *CardRepository) DeleteCardFromDefaultWordSet(wordSetId int, cards []int
 	return nil
 }
 
+// GetAllUserCardIds returns every card in the user's decks and word sets,
+// including trashed ones.
+func (r *CardRepository) GetAllUserCardIds(userId int) ([]int, error) {
+	var ids []int
+
+	query := `
+		SELECT 
+			l.card_id
+		FROM 
+			set_to_card_link l
+			JOIN word_sets w ON w.id = l.word_set_id
+		WHERE 
+			w.user_id = ?
+		UNION
+		SELECT 
+			dc.card_id
+		FROM 
+			deck_cards dc
+			JOIN decks d ON d.id = dc.deck_id
+		WHERE 
+			d.user_id = ?
+	`
+	err := r.db.Raw(query, userId, userId).Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// PurgeOrphanCards hard-deletes the cards no deck or word set links to any
+// more.
+func (r *CardRepository) PurgeOrphanCards(cardIds []int) error {
+	if len(cardIds) == 0 {
+		return nil
+	}
+
+	query := `
+		DELETE FROM 
+			cards c
+		WHERE 
+			c.id IN ?
+			AND NOT EXISTS (SELECT 1 FROM set_to_card_link l WHERE l.card_id = c.id)
+			AND NOT EXISTS (SELECT 1 FROM deck_cards dc WHERE dc.card_id = c.id)
+	`
+	return r.db.Exec(query, cardIds).Error
+}
+
 func (r *CardRepository) WithTx(tx *gorm.DB) card.CardRepository {
 	return &CardRepository{
 		db: tx,
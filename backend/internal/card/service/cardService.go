@@ -3,21 +3,34 @@ package service
 import (
 	models "dimplom_harmonic/domain"
 	"dimplom_harmonic/internal/card"
+	"dimplom_harmonic/internal/entitlement"
+	"dimplom_harmonic/internal/events"
+	"dimplom_harmonic/internal/realtime"
 	wordset "dimplom_harmonic/internal/wordSet"
 	"errors"
-	"log"
+	"slices"
 
 	"gorm.io/gorm"
 )
 
 type CardService struct {
-	cardRepo    card.CardRepository
-	wordSetRepo wordset.WordSetRepository
-	db          *gorm.DB
+	cardRepo     card.CardRepository
+	wordSetRepo  wordset.WordSetRepository
+	entitlements entitlement.EntitlementService
+	outboxRepo   events.OutboxRepository
+	publisher    realtime.Publisher
+	db           *gorm.DB
 }
 
-func NewCardService(cardRepo card.CardRepository, wordSetRepo wordset.WordSetRepository, db *gorm.DB) *CardService {
-	return &CardService{cardRepo: cardRepo, wordSetRepo: wordSetRepo, db: db}
+func NewCardService(cardRepo card.CardRepository, wordSetRepo wordset.WordSetRepository, entitlements entitlement.EntitlementService, outboxRepo events.OutboxRepository, publisher realtime.Publisher, db *gorm.DB) *CardService {
+	return &CardService{cardRepo: cardRepo, wordSetRepo: wordSetRepo, entitlements: entitlements, outboxRepo: outboxRepo, publisher: publisher, db: db}
+}
+
+// publish tells the user and everyone who sees the card's word sets about a
+// change to it.
+func (s *CardService) publish(eventType string, userId int, wordSets []models.WordSet, data map[string]any) {
+	userIds := wordset.Audience(s.wordSetRepo, userId, wordset.WordSetIds(wordSets))
+	s.publisher.Publish(realtime.NewEvent(eventType, userIds, data))
 }
 
 func (s *CardService) CreateCard(input models.Card, userId int) (*models.Card, error) {
@@ -26,65 +39,199 @@ func (s *CardService) CreateCard(input models.Card, userId int) (*models.Card, e
 		return nil, errors.New("Original word wasn't be empty")
 	}
 
-	var cards []models.Card
-	cards = append(cards, input)
-	if err := s.cardRepo.CreateCard(cards, userId); err != nil {
+	for _, value := range input.WordSets {
+		if _, err := wordset.RequireAccess(s.wordSetRepo, userId, value.Id, true); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, value := range input.Decks {
+		err := s.entitlements.CheckCardsAddToDeck(userId, value.Id, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var cards []models.Card
+		cards = append(cards, input)
+		if err := s.cardRepo.WithTx(tx).CreateCard(cards, userId); err != nil {
+			return err
+		}
+		input.Id = cards[0].Id
+
+		if err := events.Record(s.outboxRepo.WithTx(tx), events.CardsCreated(userId, cards)...); err != nil {
+			return err
+		}
+		return s.recordActivity(tx, userId, wordset.ActivityCardAdded, &input, input.WordSets, nil)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	s.publish(realtime.EventCardCreated, userId, input.WordSets, map[string]any{
+		"card":       card.UpdateCardModelTo(&input),
+		"wordSetIds": wordset.WordSetIds(input.WordSets),
+		"deckIds":    deckIds(input.Decks),
+	})
 	return &input, nil
 }
 
-func (s *CardService) DeleteCard(deleteCard card.DeleteCardParam) error {
-
-	delCard := models.Card{Id: deleteCard.Id}
+// requireEdit checks that the card is in one of the user's decks or in a
+// word set the user may edit.
+func (s *CardService) requireEdit(userId int, c *models.Card) error {
+	for _, value := range c.Decks {
+		if value.UserId == userId {
+			return nil
+		}
+	}
 
-	if deleteCard.DeckId != nil {
-		deck := models.Deck{Id: *deleteCard.DeckId}
-		err := s.cardRepo.DeleteCardFromDeck(&delCard, &deck)
-		if err != nil {
-			return err
+	visible := false
+	for _, value := range c.WordSets {
+		access, err := s.wordSetRepo.GetAccess(userId, value.Id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
 		}
-	} else if deleteCard.WordSetId != nil {
-		wordSet := models.WordSet{Id: *deleteCard.WordSetId}
-		err := s.cardRepo.DeleteCardFromWordSet(&delCard, &wordSet)
 		if err != nil {
 			return err
 		}
+		if access.CanEdit() {
+			return nil
+		}
+		visible = visible || access.CanView()
+	}
+
+	if visible {
+		return wordset.ErrForbidden
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// recordActivity adds an entry about the card to the feed of each word set.
+func (s *CardService) recordActivity(tx *gorm.DB, userId int, action string, c *models.Card, wordSets []models.WordSet, details map[string]any) error {
+	if details == nil {
+		details = map[string]any{}
 	}
+	details["originalWord"] = c.OriginalWord
+	details["translation"] = c.Translation
 
-	card, err := s.cardRepo.GetCardById(delCard.Id)
+	activities := make([]models.WordSetActivity, 0, len(wordSets))
+	for _, value := range wordSets {
+		activities = append(activities, wordset.NewActivity(value.Id, userId, action, &c.Id, details))
+	}
+	return s.wordSetRepo.WithTx(tx).CreateActivities(activities)
+}
+
+func (s *CardService) DeleteCard(deleteCard card.DeleteCardParam) error {
+
+	card, err := s.cardRepo.GetCardById(deleteCard.Id)
 	if err != nil {
 		return err
 	}
 
-	count := len(card.Decks) + len(card.WordSets)
-	log.Println(card)
+	// The word sets whose feed gets the removal.
+	var fromWordSets []models.WordSet
 
-	if count == 0 {
-		err = s.cardRepo.DeleteCard(card.Id)
+	if deleteCard.WordSetId != nil {
+		_, err := wordset.RequireAccess(s.wordSetRepo, deleteCard.UserId, *deleteCard.WordSetId, true)
 		if err != nil {
 			return err
 		}
+		inWordSet := slices.ContainsFunc(card.WordSets, func(w models.WordSet) bool {
+			return w.Id == *deleteCard.WordSetId
+		})
+		if !inWordSet {
+			return gorm.ErrRecordNotFound
+		}
+		fromWordSets = []models.WordSet{{Id: *deleteCard.WordSetId}}
+	} else if deleteCard.DeckId != nil {
+		inDeck := slices.ContainsFunc(card.Decks, func(d models.Deck) bool {
+			return d.Id == *deleteCard.DeckId && d.UserId == deleteCard.UserId
+		})
+		if !inDeck {
+			return gorm.ErrRecordNotFound
+		}
+	} else {
+		if err := s.requireEdit(deleteCard.UserId, card); err != nil {
+			return err
+		}
+		fromWordSets = card.WordSets
 	}
 
+	count := len(card.Decks) + len(card.WordSets)
+	unlink := deleteCard.DeckId != nil || deleteCard.WordSetId != nil
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txCardRepo := s.cardRepo.WithTx(tx)
+
+		err := s.recordActivity(tx, deleteCard.UserId, wordset.ActivityCardRemoved, card, fromWordSets, nil)
+		if err != nil {
+			return err
+		}
+
+		// Removing a card from its last deck or word set moves it to the trash.
+		// The link is kept, so restoring puts the card back where it was.
+		if count == 0 || (count == 1 && unlink) {
+			return txCardRepo.DeleteCard(card.Id)
+		}
+
+		delCard := models.Card{Id: deleteCard.Id}
+
+		if deleteCard.DeckId != nil {
+			deck := models.Deck{Id: *deleteCard.DeckId}
+			err := txCardRepo.DeleteCardFromDeck(&delCard, &deck)
+			if err != nil {
+				return err
+			}
+		} else if deleteCard.WordSetId != nil {
+			wordSet := models.WordSet{Id: *deleteCard.WordSetId}
+			err := txCardRepo.DeleteCardFromWordSet(&delCard, &wordSet)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
+	s.publish(realtime.EventCardDeleted, deleteCard.UserId, fromWordSets, map[string]any{
+		"cardId":    card.Id,
+		"wordSetId": deleteCard.WordSetId,
+		"deckId":    deleteCard.DeckId,
+	})
 	return nil
 }
-func (s *CardService) UpdateCard(input models.Card) (*models.Card, error) {
+
+func deckIds(decks []models.Deck) []int {
+	ids := make([]int, 0, len(decks))
+	for _, value := range decks {
+		ids = append(ids, value.Id)
+	}
+	return ids
+}
+
+// UpdateCard saves the card only if input.Version is still current, so two
+// editors of a shared word set can't silently overwrite each other. Clients
+// that don't send a version get the last write.
+func (s *CardService) UpdateCard(userId int, input models.Card) (*models.Card, error) {
+
+	current, err := s.cardRepo.GetCardById(input.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireEdit(userId, current); err != nil {
+		return nil, err
+	}
+
 	var changeCard = make(map[string]any)
 	changeCard["OriginalWord"] = input.OriginalWord
 	changeCard["Translation"] = input.Translation
 	changeCard["OriginalContext"] = input.OriginalContext
 	changeCard["TranslationContext"] = input.TranslationContext
 
-	err := s.cardRepo.UpdateCard(input.Id, changeCard)
-	if err != nil {
-		return nil, err
-	}
 	changedCard := models.Card{
 		Id:                 input.Id,
 		OriginalWord:       input.OriginalWord,
@@ -93,7 +240,26 @@ func (s *CardService) UpdateCard(input models.Card) (*models.Card, error) {
 		TranslationContext: input.TranslationContext,
 	}
 
-	return &changedCard, err
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		version, err := s.cardRepo.WithTx(tx).UpdateCard(input.Id, input.Version, changeCard)
+		if err != nil {
+			return err
+		}
+		changedCard.Version = version
+
+		return s.recordActivity(tx, userId, wordset.ActivityCardUpdated, &changedCard, current.WordSets, map[string]any{
+			"previousWord":        current.OriginalWord,
+			"previousTranslation": current.Translation,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(realtime.EventCardUpdated, userId, current.WordSets, map[string]any{
+		"card": card.UpdateCardModelTo(&changedCard),
+	})
+	return &changedCard, nil
 }
 
 func (s *CardService) CreateHardCards(cards card.CreateHardWordsDTO, userId int) error {
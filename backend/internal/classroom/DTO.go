@@ -0,0 +1,152 @@
+package classroom
+
+import (
+	models "dimplom_harmonic/domain"
+	"time"
+)
+
+type CreateGroupDTO struct {
+	Name string `json:"name"`
+}
+
+type JoinGroupDTO struct {
+	Code string `json:"code"`
+}
+
+type UpdateMemberDTO struct {
+	Role string `json:"role"`
+}
+
+type GroupDTO struct {
+	Id            int       `json:"id"`
+	Name          string    `json:"name"`
+	OwnerId       int       `json:"ownerId"`
+	Role          string    `json:"role"`
+	JoinCode      string    `json:"joinCode,omitempty"`
+	StudentsCount int       `json:"studentsCount"`
+	CreatedAt     time.Time `json:"createdAt"`
+
+	// SkippedAssignmentIds are past assignments the joining student got no
+	// deck for because it would exceed their plan limits.
+	SkippedAssignmentIds []int `json:"skippedAssignmentIds,omitempty"`
+}
+
+// GroupModelTo shows the join code to teachers only.
+func GroupModelTo(m *models.Group, role string, studentsCount int) GroupDTO {
+	group := GroupDTO{
+		Id:            m.Id,
+		Name:          m.Name,
+		OwnerId:       m.OwnerId,
+		Role:          role,
+		StudentsCount: studentsCount,
+		CreatedAt:     m.CreatedAt,
+	}
+	if role == models.GroupTeacher {
+		group.JoinCode = m.JoinCode
+	}
+	return group
+}
+
+type MemberDTO struct {
+	UserId   int       `json:"userId"`
+	Login    string    `json:"login"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+type GroupDetailsDTO struct {
+	GroupDTO
+	Members     []MemberDTO     `json:"members"`
+	Assignments []AssignmentDTO `json:"assignments"`
+}
+
+type CreateAssignmentDTO struct {
+	WordSetId      int        `json:"wordSetId"`
+	ScheduleId     int        `json:"scheduleId"`
+	Name           string     `json:"name"`
+	NextReviewDate *time.Time `json:"nextReviewDate"`
+}
+
+type AssignmentDTO struct {
+	Id         int       `json:"id"`
+	Name       string    `json:"name"`
+	WordSetId  *int      `json:"wordSetId"`
+	ScheduleId *int      `json:"scheduleId"`
+	CreatedAt  time.Time `json:"createdAt"`
+
+	// SkippedStudentIds are students who got no deck because it would exceed
+	// their plan limits.
+	SkippedStudentIds []int `json:"skippedStudentIds,omitempty"`
+}
+
+func AssignmentModelTo(m *models.GroupAssignment) AssignmentDTO {
+	return AssignmentDTO{
+		Id:         m.Id,
+		Name:       m.Name,
+		WordSetId:  m.WordSetId,
+		ScheduleId: m.ScheduleId,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+type StudentProgressDTO struct {
+	UserId          int        `json:"userId"`
+	Login           string     `json:"login"`
+	DeckId          *int       `json:"deckId"`
+	DeckDeleted     bool       `json:"deckDeleted"`
+	CurrentLevel    int        `json:"currentLevel"`
+	IsArchived      bool       `json:"isArchived"`
+	NextReviewDate  *time.Time `json:"nextReviewDate"`
+	Reviews         int        `json:"reviews"`
+	LastReviewDate  *time.Time `json:"lastReviewDate"`
+	AverageAccuracy int        `json:"averageAccuracy"`
+	Answers         int        `json:"answers"`
+	CorrectAnswers  int        `json:"correctAnswers"`
+}
+
+func StudentProgressResultTo(r []StudentProgressResult) []StudentProgressDTO {
+	progress := make([]StudentProgressDTO, 0, len(r))
+
+	for _, value := range r {
+		progress = append(progress, StudentProgressDTO{
+			UserId:          value.UserId,
+			Login:           value.Login,
+			DeckId:          value.DeckId,
+			DeckDeleted:     value.DeckDeleted,
+			CurrentLevel:    value.CurrentLevel,
+			IsArchived:      value.IsArchived,
+			NextReviewDate:  value.NextReviewDate,
+			Reviews:         value.Reviews,
+			LastReviewDate:  value.LastReviewDate,
+			AverageAccuracy: value.AverageAccuracy,
+			Answers:         value.Answers,
+			CorrectAnswers:  value.CorrectAnswers,
+		})
+	}
+	return progress
+}
+
+type CardProgressDTO struct {
+	CardId         int        `json:"cardId"`
+	OriginalWord   string     `json:"originalWord"`
+	Translation    string     `json:"translation"`
+	Answers        int        `json:"answers"`
+	CorrectAnswers int        `json:"correctAnswers"`
+	LastReviewDate *time.Time `json:"lastReviewDate"`
+}
+
+func CardProgressResultTo(r []CardProgressResult) []CardProgressDTO {
+	progress := make([]CardProgressDTO, 0, len(r))
+
+	for _, value := range r {
+		progress = append(progress, CardProgressDTO{
+			CardId:         value.CardId,
+			OriginalWord:   value.OriginalWord,
+			Translation:    value.Translation,
+			Answers:        value.Answers,
+			CorrectAnswers: value.CorrectAnswers,
+			LastReviewDate: value.LastReviewDate,
+		})
+	}
+	return progress
+}
@@ -0,0 +1,93 @@
+package classroom
+
+import (
+	models "dimplom_harmonic/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ClassroomService interface {
+	CreateGroup(userId int, name string) (*GroupDTO, error)
+	GetGroups(userId int) ([]GroupDTO, error)
+	GetGroup(userId, groupId int) (*GroupDetailsDTO, error)
+	DeleteGroup(userId, groupId int) error
+	RegenerateJoinCode(userId, groupId int) (*GroupDTO, error)
+	JoinGroup(userId int, code string) (*GroupDTO, error)
+	UpdateMember(userId, groupId, memberId int, role string) error
+	RemoveMember(userId, groupId, memberId int) error
+	CreateAssignment(userId, groupId int, input CreateAssignmentDTO) (*AssignmentDTO, error)
+	GetAssignments(userId, groupId int) ([]AssignmentDTO, error)
+	GetAssignmentProgress(userId, groupId, assignmentId int) ([]StudentProgressDTO, error)
+	GetStudentProgress(userId, groupId, assignmentId, studentId int) ([]CardProgressDTO, error)
+}
+
+type ClassroomRepository interface {
+	CreateGroup(group *models.Group) error
+	GetGroups(userId int) ([]GroupResult, error)
+	GetGroup(groupId int) (*models.Group, error)
+	GetGroupByCode(code string) (*models.Group, error)
+	UpdateJoinCode(groupId int, code string) error
+	DeleteGroup(groupId int) error
+
+	GetMemberRole(groupId, userId int) (string, error)
+	AddMember(member *models.GroupMember) (bool, error)
+	GetMembers(groupId int) ([]MemberResult, error)
+	UpdateMemberRole(groupId, userId int, role string) error
+	DeleteMember(groupId, userId int) error
+
+	CreateAssignment(assignment *models.GroupAssignment) error
+	GetAssignments(groupId int) ([]models.GroupAssignment, error)
+	GetAssignment(groupId, assignmentId int) (*models.GroupAssignment, error)
+	GetAssignmentProgress(groupId, assignmentId int) ([]StudentProgressResult, error)
+	GetStudentProgress(assignmentId, userId int) ([]CardProgressResult, error)
+
+	WithTx(tx *gorm.DB) ClassroomRepository
+}
+
+type GroupResult struct {
+	models.Group
+	Role          string
+	StudentsCount int
+}
+
+type MemberResult struct {
+	models.GroupMember
+	Login string
+}
+
+type StudentProgressResult struct {
+	UserId          int
+	Login           string
+	DeckId          *int
+	CurrentLevel    int
+	IsArchived      bool
+	DeckDeleted     bool
+	NextReviewDate  *time.Time
+	Reviews         int
+	LastReviewDate  *time.Time
+	AverageAccuracy int
+	Answers         int
+	CorrectAnswers  int
+}
+
+type CardProgressResult struct {
+	CardId         int
+	OriginalWord   string
+	Translation    string
+	Answers        int
+	CorrectAnswers int
+	LastReviewDate *time.Time
+}
+
+var (
+	ErrNotTeacher       = errors.New("not_a_teacher")
+	ErrInvalidRole      = errors.New("invalid_group_role")
+	ErrInvalidCode      = errors.New("invalid_join_code")
+	ErrAlreadyMember    = errors.New("already_group_member")
+	ErrOwnerRole        = errors.New("cant_change_group_owner")
+	ErrNotStudent       = errors.New("not_a_student")
+	ErrScheduleNotOwned = errors.New("schedule_not_owned")
+	ErrEmptyWordSet     = errors.New("word_set_is_empty")
+)
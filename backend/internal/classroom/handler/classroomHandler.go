@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"dimplom_harmonic/internal/classroom"
+	"dimplom_harmonic/internal/entitlement"
+	"dimplom_harmonic/internal/middleware"
+	wordset "dimplom_harmonic/internal/wordSet"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type ClassroomHandler struct {
+	service classroom.ClassroomService
+}
+
+func NewClassroomHandler(service classroom.ClassroomService) *ClassroomHandler {
+	return &ClassroomHandler{service: service}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, classroom.ErrNotTeacher), errors.Is(err, classroom.ErrOwnerRole), errors.Is(err, wordset.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, classroom.ErrAlreadyMember):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, entitlement.ErrDecksPerDayExceeded), errors.Is(err, entitlement.ErrCardsPerDeckExceeded):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// urlInts parses the named URL params, writing 400 on the first bad one.
+func urlInts(w http.ResponseWriter, r *http.Request, names ...string) ([]int, bool) {
+	values := make([]int, 0, len(names))
+	for _, name := range names {
+		value, err := strconv.Atoi(chi.URLParam(r, name))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	return values, true
+}
+
+func (h *ClassroomHandler) HDCreateGroup(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input classroom.CreateGroupDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.service.CreateGroup(userId, input.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(group)
+}
+
+func (h *ClassroomHandler) HDGetGroups(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	groups, err := h.service.GetGroups(userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(groups)
+}
+
+func (h *ClassroomHandler) HDGetGroup(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	ids, ok := urlInts(w, r, "groupID")
+	if !ok {
+		return
+	}
+
+	group, err := h.service.GetGroup(userId, ids[0])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(group)
+}
+
+func (h *ClassroomHandler) HDDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	ids, ok := urlInts(w, r, "groupID")
+	if !ok {
+		return
+	}
+
+	err := h.service.DeleteGroup(userId, ids[0])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *ClassroomHandler) HDRegenerateJoinCode(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	ids, ok := urlInts(w, r, "groupID")
+	if !ok {
+		return
+	}
+
+	group, err := h.service.RegenerateJoinCode(userId, ids[0])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(group)
+}
+
+func (h *ClassroomHandler) HDJoinGroup(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input classroom.JoinGroupDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.service.JoinGroup(userId, input.Code)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(group)
+}
+
+func (h *ClassroomHandler) HDUpdateMember(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	ids, ok := urlInts(w, r, "groupID", "userID")
+	if !ok {
+		return
+	}
+
+	var input classroom.UpdateMemberDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.UpdateMember(userId, ids[0], ids[1], input.Role)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *ClassroomHandler) HDRemoveMember(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	ids, ok := urlInts(w, r, "groupID", "userID")
+	if !ok {
+		return
+	}
+
+	err := h.service.RemoveMember(userId, ids[0], ids[1])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *ClassroomHandler) HDCreateAssignment(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	ids, ok := urlInts(w, r, "groupID")
+	if !ok {
+		return
+	}
+
+	var input classroom.CreateAssignmentDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	assignment, err := h.service.CreateAssignment(userId, ids[0], input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(assignment)
+}
+
+func (h *ClassroomHandler) HDGetAssignments(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	ids, ok := urlInts(w, r, "groupID")
+	if !ok {
+		return
+	}
+
+	assignments, err := h.service.GetAssignments(userId, ids[0])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(assignments)
+}
+
+func (h *ClassroomHandler) HDGetAssignmentProgress(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	ids, ok := urlInts(w, r, "groupID", "assignmentID")
+	if !ok {
+		return
+	}
+
+	progress, err := h.service.GetAssignmentProgress(userId, ids[0], ids[1])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(progress)
+}
+
+func (h *ClassroomHandler) HDGetStudentProgress(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	ids, ok := urlInts(w, r, "groupID", "assignmentID", "userID")
+	if !ok {
+		return
+	}
+
+	progress, err := h.service.GetStudentProgress(userId, ids[0], ids[1], ids[2])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(progress)
+}
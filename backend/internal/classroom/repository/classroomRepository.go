@@ -0,0 +1,235 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/classroom"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ClassroomRepository struct {
+	db *gorm.DB
+}
+
+func NewClassroomRepository(db *gorm.DB) *ClassroomRepository {
+	return &ClassroomRepository{db: db}
+}
+
+func (r *ClassroomRepository) CreateGroup(group *models.Group) error {
+	return r.db.Create(group).Error
+}
+
+func (r *ClassroomRepository) GetGroups(userId int) ([]classroom.GroupResult, error) {
+	var groups []classroom.GroupResult
+
+	query := `
+		SELECT 
+			g.*,
+			m.role,
+			(SELECT COUNT(*) FROM group_members s WHERE s.group_id = g.id AND s.role = 'student') as students_count
+		FROM 
+			"groups" g
+			JOIN group_members m ON m.group_id = g.id
+		WHERE 
+			m.user_id = ?
+		ORDER BY 
+			g.created_at DESC
+	`
+	err := r.db.Raw(query, userId).Scan(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *ClassroomRepository) GetGroup(groupId int) (*models.Group, error) {
+	var group models.Group
+	err := r.db.First(&group, groupId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *ClassroomRepository) GetGroupByCode(code string) (*models.Group, error) {
+	var group models.Group
+	err := r.db.Where("join_code = ?", code).First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *ClassroomRepository) UpdateJoinCode(groupId int, code string) error {
+	return r.db.Model(&models.Group{}).Where("id = ?", groupId).Update("join_code", code).Error
+}
+
+func (r *ClassroomRepository) DeleteGroup(groupId int) error {
+	return r.db.Delete(&models.Group{}, groupId).Error
+}
+
+// GetMemberRole returns an empty role for users outside the group.
+func (r *ClassroomRepository) GetMemberRole(groupId, userId int) (string, error) {
+	var member models.GroupMember
+	err := r.db.Where("group_id = ? AND user_id = ?", groupId, userId).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// AddMember returns false when the user is already in the group.
+func (r *ClassroomRepository) AddMember(member *models.GroupMember) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(member)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *ClassroomRepository) GetMembers(groupId int) ([]classroom.MemberResult, error) {
+	var members []classroom.MemberResult
+
+	query := `
+		SELECT 
+			m.*,
+			u.login
+		FROM 
+			group_members m
+			JOIN users u ON u.id = m.user_id
+		WHERE 
+			m.group_id = ?
+		ORDER BY 
+			m.role DESC, u.login
+	`
+	err := r.db.Raw(query, groupId).Scan(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *ClassroomRepository) UpdateMemberRole(groupId, userId int, role string) error {
+	result := r.db.Model(&models.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", groupId, userId).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *ClassroomRepository) DeleteMember(groupId, userId int) error {
+	result := r.db.Where("group_id = ? AND user_id = ?", groupId, userId).Delete(&models.GroupMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *ClassroomRepository) CreateAssignment(assignment *models.GroupAssignment) error {
+	return r.db.Create(assignment).Error
+}
+
+func (r *ClassroomRepository) GetAssignments(groupId int) ([]models.GroupAssignment, error) {
+	var assignments []models.GroupAssignment
+	err := r.db.Where("group_id = ?", groupId).Order("created_at DESC").Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func (r *ClassroomRepository) GetAssignment(groupId, assignmentId int) (*models.GroupAssignment, error) {
+	var assignment models.GroupAssignment
+	err := r.db.Where("group_id = ? AND id = ?", groupId, assignmentId).First(&assignment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// GetAssignmentProgress summarises the latest deck of every student for the
+// assignment. Students who deleted the deck are still listed.
+func (r *ClassroomRepository) GetAssignmentProgress(groupId, assignmentId int) ([]classroom.StudentProgressResult, error) {
+	var progress []classroom.StudentProgressResult
+
+	query := `
+		SELECT 
+			m.user_id,
+			u.login,
+			d.id as deck_id,
+			COALESCE(d.current_level, 0) as current_level,
+			COALESCE(d.is_archived, false) as is_archived,
+			d.deleted_at IS NOT NULL as deck_deleted,
+			d.next_review_date,
+			(SELECT COUNT(*) FROM deck_histories dh WHERE dh.deck_id = d.id) as reviews,
+			(SELECT MAX(dh.review_date) FROM deck_histories dh WHERE dh.deck_id = d.id) as last_review_date,
+			(SELECT COALESCE(ROUND(AVG(dh.accuracy)), 0) FROM deck_histories dh WHERE dh.deck_id = d.id) as average_accuracy,
+			(SELECT COUNT(*) FROM card_histories ch WHERE ch.deck_id = d.id) as answers,
+			(SELECT COUNT(*) FROM card_histories ch WHERE ch.deck_id = d.id AND ch.is_correct) as correct_answers
+		FROM 
+			group_members m
+			JOIN users u ON u.id = m.user_id
+			LEFT JOIN LATERAL (
+				SELECT * FROM decks 
+				WHERE assignment_id = ? AND user_id = m.user_id 
+				ORDER BY id DESC LIMIT 1
+			) d ON TRUE
+		WHERE 
+			m.group_id = ? AND m.role = 'student'
+		ORDER BY 
+			u.login
+	`
+	err := r.db.Raw(query, assignmentId, groupId).Scan(&progress).Error
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (r *ClassroomRepository) GetStudentProgress(assignmentId, userId int) ([]classroom.CardProgressResult, error) {
+	var progress []classroom.CardProgressResult
+
+	query := `
+		SELECT 
+			c.id as card_id,
+			c.original_word,
+			c.translation,
+			COUNT(ch.id) as answers,
+			COUNT(ch.id) FILTER (WHERE ch.is_correct) as correct_answers,
+			MAX(ch.review_date) as last_review_date
+		FROM 
+			deck_cards dc
+			JOIN cards c ON c.id = dc.card_id
+			LEFT JOIN card_histories ch ON ch.deck_id = dc.deck_id AND ch.card_id = c.id
+		WHERE 
+			dc.deck_id = (SELECT MAX(id) FROM decks WHERE assignment_id = ? AND user_id = ?)
+		GROUP BY 
+			c.id
+		ORDER BY 
+			c.id
+	`
+	err := r.db.Raw(query, assignmentId, userId).Scan(&progress).Error
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (r *ClassroomRepository) WithTx(tx *gorm.DB) classroom.ClassroomRepository {
+	return &ClassroomRepository{
+		db: tx,
+	}
+}
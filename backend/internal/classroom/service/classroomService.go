@@ -0,0 +1,600 @@
+package service
+
+import (
+	"crypto/rand"
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/card"
+	"dimplom_harmonic/internal/classroom"
+	"dimplom_harmonic/internal/deck"
+	"dimplom_harmonic/internal/entitlement"
+	"dimplom_harmonic/internal/events"
+	"dimplom_harmonic/internal/schedule"
+	wordset "dimplom_harmonic/internal/wordSet"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// joinCodeAlphabet leaves out characters that are easy to mix up when the
+// code is read out in class.
+const (
+	joinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	joinCodeLength   = 8
+)
+
+type ClassroomService struct {
+	classroomRepo classroom.ClassroomRepository
+	deckRepo      deck.DeckRepository
+	cardRepo      card.CardRepository
+	wordSetRepo   wordset.WordSetRepository
+	scheduleRepo  schedule.ScheduleRepository
+	entitlements  entitlement.EntitlementService
+	outboxRepo    events.OutboxRepository
+	db            *gorm.DB
+}
+
+func NewClassroomService(classroomRepo classroom.ClassroomRepository, deckRepo deck.DeckRepository, cardRepo card.CardRepository, wordSetRepo wordset.WordSetRepository, scheduleRepo schedule.ScheduleRepository, entitlements entitlement.EntitlementService, outboxRepo events.OutboxRepository, db *gorm.DB) *ClassroomService {
+	return &ClassroomService{
+		classroomRepo: classroomRepo,
+		deckRepo:      deckRepo,
+		cardRepo:      cardRepo,
+		wordSetRepo:   wordSetRepo,
+		scheduleRepo:  scheduleRepo,
+		entitlements:  entitlements,
+		outboxRepo:    outboxRepo,
+		db:            db,
+	}
+}
+
+func generateJoinCode() (string, error) {
+	b := make([]byte, joinCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = joinCodeAlphabet[int(b[i])%len(joinCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// requireMember returns the user's role in the group. Groups the user is not
+// in look missing.
+func (s *ClassroomService) requireMember(userId, groupId int) (string, error) {
+	role, err := s.classroomRepo.GetMemberRole(groupId, userId)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", gorm.ErrRecordNotFound
+	}
+	return role, nil
+}
+
+func (s *ClassroomService) requireTeacher(userId, groupId int) error {
+	role, err := s.requireMember(userId, groupId)
+	if err != nil {
+		return err
+	}
+	if role != models.GroupTeacher {
+		return classroom.ErrNotTeacher
+	}
+	return nil
+}
+
+func (s *ClassroomService) CreateGroup(userId int, name string) (*classroom.GroupDTO, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	code, err := generateJoinCode()
+	if err != nil {
+		return nil, err
+	}
+
+	group := models.Group{
+		Name:      name,
+		OwnerId:   userId,
+		JoinCode:  code,
+		CreatedAt: time.Now(),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txClassroomRepo := s.classroomRepo.WithTx(tx)
+
+		if err := txClassroomRepo.CreateGroup(&group); err != nil {
+			return err
+		}
+		_, err := txClassroomRepo.AddMember(&models.GroupMember{
+			GroupId:  group.Id,
+			UserId:   userId,
+			Role:     models.GroupTeacher,
+			JoinedAt: group.CreatedAt,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := classroom.GroupModelTo(&group, models.GroupTeacher, 0)
+	return &result, nil
+}
+
+func (s *ClassroomService) GetGroups(userId int) ([]classroom.GroupDTO, error) {
+	groups, err := s.classroomRepo.GetGroups(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]classroom.GroupDTO, 0, len(groups))
+	for i := range groups {
+		result = append(result, classroom.GroupModelTo(&groups[i].Group, groups[i].Role, groups[i].StudentsCount))
+	}
+	return result, nil
+}
+
+// GetGroup lists the members to teachers only; students see the group and
+// its assignments.
+func (s *ClassroomService) GetGroup(userId, groupId int) (*classroom.GroupDetailsDTO, error) {
+	role, err := s.requireMember(userId, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.classroomRepo.GetGroup(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.classroomRepo.GetMembers(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	studentsCount := 0
+	memberDTOs := make([]classroom.MemberDTO, 0, len(members))
+	for _, value := range members {
+		if value.Role == models.GroupStudent {
+			studentsCount++
+		}
+		if role == models.GroupTeacher {
+			memberDTOs = append(memberDTOs, classroom.MemberDTO{
+				UserId:   value.UserId,
+				Login:    value.Login,
+				Role:     value.Role,
+				JoinedAt: value.JoinedAt,
+			})
+		}
+	}
+
+	assignments, err := s.GetAssignments(userId, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &classroom.GroupDetailsDTO{
+		GroupDTO:    classroom.GroupModelTo(group, role, studentsCount),
+		Members:     memberDTOs,
+		Assignments: assignments,
+	}, nil
+}
+
+func (s *ClassroomService) DeleteGroup(userId, groupId int) error {
+	group, err := s.classroomRepo.GetGroup(groupId)
+	if err != nil {
+		return err
+	}
+	if group.OwnerId != userId {
+		if err := s.requireTeacher(userId, groupId); err != nil {
+			return err
+		}
+		return classroom.ErrOwnerRole
+	}
+
+	return s.classroomRepo.DeleteGroup(groupId)
+}
+
+func (s *ClassroomService) RegenerateJoinCode(userId, groupId int) (*classroom.GroupDTO, error) {
+	if err := s.requireTeacher(userId, groupId); err != nil {
+		return nil, err
+	}
+
+	code, err := generateJoinCode()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.classroomRepo.UpdateJoinCode(groupId, code); err != nil {
+		return nil, err
+	}
+
+	group, err := s.classroomRepo.GetGroup(groupId)
+	if err != nil {
+		return nil, err
+	}
+	result := classroom.GroupModelTo(group, models.GroupTeacher, 0)
+	return &result, nil
+}
+
+// JoinGroup adds the user as a student and hands out the decks of the
+// assignments made before they joined.
+func (s *ClassroomService) JoinGroup(userId int, code string) (*classroom.GroupDTO, error) {
+	group, err := s.classroomRepo.GetGroupByCode(strings.ToUpper(strings.TrimSpace(code)))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, classroom.ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := s.classroomRepo.GetAssignments(group.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The decks of past assignments count toward the student's own limits,
+	// like decks they create themselves. Assignments over the limits are
+	// skipped instead of blocking the join.
+	type pastAssignment struct {
+		assignment *models.GroupAssignment
+		cards      []models.Card
+		schedule   *models.DeckSchedule
+	}
+	var past []pastAssignment
+	var skippedIds []int
+	for i := range assignments {
+		if assignments[i].WordSetId == nil {
+			continue
+		}
+		cards, err := s.assignmentCards(*assignments[i].WordSetId)
+		if err != nil {
+			return nil, err
+		}
+		if len(cards) == 0 {
+			continue
+		}
+		err = s.checkDeckLimits(userId, len(cards), len(past)+1)
+		if errors.Is(err, entitlement.ErrCardsPerDeckExceeded) || errors.Is(err, entitlement.ErrDecksPerDayExceeded) {
+			skippedIds = append(skippedIds, assignments[i].Id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		source, err := s.assignmentSchedule(&assignments[i])
+		if err != nil {
+			return nil, err
+		}
+		past = append(past, pastAssignment{assignment: &assignments[i], cards: cards, schedule: source})
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		added, err := s.classroomRepo.WithTx(tx).AddMember(&models.GroupMember{
+			GroupId:  group.Id,
+			UserId:   userId,
+			Role:     models.GroupStudent,
+			JoinedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if !added {
+			return classroom.ErrAlreadyMember
+		}
+
+		for _, value := range past {
+			err := s.createStudentDecks(tx, value.assignment, value.schedule, value.cards, []int{userId}, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := classroom.GroupModelTo(group, models.GroupStudent, 0)
+	result.SkippedAssignmentIds = skippedIds
+	return &result, nil
+}
+
+// UpdateMember promotes students to teachers and back. The owner stays a
+// teacher.
+func (s *ClassroomService) UpdateMember(userId, groupId, memberId int, role string) error {
+	if role != models.GroupTeacher && role != models.GroupStudent {
+		return classroom.ErrInvalidRole
+	}
+
+	if err := s.requireTeacher(userId, groupId); err != nil {
+		return err
+	}
+
+	group, err := s.classroomRepo.GetGroup(groupId)
+	if err != nil {
+		return err
+	}
+	if group.OwnerId == memberId {
+		return classroom.ErrOwnerRole
+	}
+
+	return s.classroomRepo.UpdateMemberRole(groupId, memberId, role)
+}
+
+// RemoveMember is used by teachers to remove anyone but the owner and by
+// members to leave. Decks already handed out stay with the student.
+func (s *ClassroomService) RemoveMember(userId, groupId, memberId int) error {
+	group, err := s.classroomRepo.GetGroup(groupId)
+	if err != nil {
+		return err
+	}
+	if group.OwnerId == memberId {
+		return classroom.ErrOwnerRole
+	}
+
+	if userId != memberId {
+		if err := s.requireTeacher(userId, groupId); err != nil {
+			return err
+		}
+	}
+
+	return s.classroomRepo.DeleteMember(groupId, memberId)
+}
+
+// assignmentCards returns the current cards of the assignment's word set,
+// or none once the set is in the trash.
+func (s *ClassroomService) assignmentCards(wordSetId int) ([]models.Card, error) {
+	wordSet, err := s.wordSetRepo.GetWordSetByID(0, wordSetId)
+	if err != nil {
+		return nil, err
+	}
+	if wordSet.Id == 0 {
+		return nil, nil
+	}
+	return wordSet.Cards, nil
+}
+
+// assignmentSchedule returns the teacher's schedule the students' copies are
+// made from, or nil once it was deleted.
+func (s *ClassroomService) assignmentSchedule(assignment *models.GroupAssignment) (*models.DeckSchedule, error) {
+	if assignment.ScheduleId == nil {
+		return nil, nil
+	}
+	deckSchedule, err := s.scheduleRepo.GetSchedule(*assignment.ScheduleId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return deckSchedule, err
+}
+
+// checkDeckLimits checks the student's plan like DeckService does for decks
+// the student creates.
+func (s *ClassroomService) checkDeckLimits(userId int, cardsCount int, newDecks int) error {
+	if err := s.entitlements.CheckCardsPerDeck(userId, cardsCount); err != nil {
+		return err
+	}
+	return s.entitlements.CheckDecksPerDay(userId, newDecks)
+}
+
+// studentSchedule copies the teacher's schedule for the student, so later
+// edits of the original don't change the student's intervals. Without a
+// schedule to copy, the student's default one is used.
+func (s *ClassroomService) studentSchedule(txScheduleRepo schedule.ScheduleRepository, studentId int, source *models.DeckSchedule, name string) (int, error) {
+	if source == nil {
+		defaultSchedule, err := txScheduleRepo.GetDefaultSchedule(studentId)
+		if err != nil {
+			return 0, err
+		}
+		return defaultSchedule.Id, nil
+	}
+
+	scheduleCopy := models.DeckSchedule{Name: name, UserId: studentId}
+	if err := txScheduleRepo.CreateSchedule(&scheduleCopy); err != nil {
+		return 0, err
+	}
+
+	if len(source.ScheduleSteps) != 0 {
+		steps := make([]models.ScheduleStep, 0, len(source.ScheduleSteps))
+		for _, value := range source.ScheduleSteps {
+			steps = append(steps, models.ScheduleStep{
+				DeckScheduleId:  scheduleCopy.Id,
+				Level:           value.Level,
+				IntervalMinutes: value.IntervalMinutes,
+			})
+		}
+		if err := txScheduleRepo.CreateScheduleInterval(steps); err != nil {
+			return 0, err
+		}
+	}
+	return scheduleCopy.Id, nil
+}
+
+// createStudentDecks gives each student a deck with copies of the cards and
+// of the schedule, so a student's edits stay in their deck and the teacher's
+// edits stay with the teacher.
+func (s *ClassroomService) createStudentDecks(tx *gorm.DB, assignment *models.GroupAssignment, source *models.DeckSchedule, cards []models.Card, studentIds []int, nextReviewDate time.Time) error {
+	txDeckRepo := s.deckRepo.WithTx(tx)
+	txCardRepo := s.cardRepo.WithTx(tx)
+	txScheduleRepo := s.scheduleRepo.WithTx(tx)
+	txOutboxRepo := s.outboxRepo.WithTx(tx)
+
+	for _, studentId := range studentIds {
+		scheduleId, err := s.studentSchedule(txScheduleRepo, studentId, source, assignment.Name)
+		if err != nil {
+			return err
+		}
+
+		newDeck := models.Deck{
+			UserId:               studentId,
+			Name:                 assignment.Name,
+			CreatedAt:            time.Now(),
+			NextReviewDate:       nextReviewDate,
+			NextPrimaryDirection: true,
+			ScheduleId:           scheduleId,
+			AssignmentId:         &assignment.Id,
+		}
+		if err := txDeckRepo.CreateDeck(&newDeck, studentId); err != nil {
+			return err
+		}
+
+		if len(cards) == 0 {
+			continue
+		}
+		newCards := make([]models.Card, 0, len(cards))
+		for _, value := range cards {
+			newCards = append(newCards, models.Card{
+				OriginalWord:       value.OriginalWord,
+				Translation:        value.Translation,
+				OriginalContext:    value.OriginalContext,
+				TranslationContext: value.TranslationContext,
+				Decks:              []models.Deck{{Id: newDeck.Id}},
+			})
+		}
+		if err := txCardRepo.CreateCard(newCards, studentId); err != nil {
+			return err
+		}
+		if err := events.Record(txOutboxRepo, events.CardsCreated(studentId, newCards)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ClassroomService) CreateAssignment(userId, groupId int, input classroom.CreateAssignmentDTO) (*classroom.AssignmentDTO, error) {
+	if err := s.requireTeacher(userId, groupId); err != nil {
+		return nil, err
+	}
+
+	if _, err := wordset.RequireAccess(s.wordSetRepo, userId, input.WordSetId, false); err != nil {
+		return nil, err
+	}
+
+	deckSchedule, err := s.scheduleRepo.GetSchedule(input.ScheduleId)
+	if err != nil {
+		return nil, err
+	}
+	if deckSchedule.UserId != userId {
+		return nil, classroom.ErrScheduleNotOwned
+	}
+
+	wordSet, err := s.wordSetRepo.GetWordSetByID(userId, input.WordSetId)
+	if err != nil {
+		return nil, err
+	}
+	if len(wordSet.Cards) == 0 {
+		return nil, classroom.ErrEmptyWordSet
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = wordSet.Name
+	}
+	nextReviewDate := time.Now()
+	if input.NextReviewDate != nil {
+		nextReviewDate = *input.NextReviewDate
+	}
+
+	members, err := s.classroomRepo.GetMembers(groupId)
+	if err != nil {
+		return nil, err
+	}
+	// A student over their plan limits gets no deck instead of blocking the
+	// assignment for the whole group.
+	var studentIds, skippedIds []int
+	for _, value := range members {
+		if value.Role != models.GroupStudent {
+			continue
+		}
+		err := s.checkDeckLimits(value.UserId, len(wordSet.Cards), 1)
+		if errors.Is(err, entitlement.ErrCardsPerDeckExceeded) || errors.Is(err, entitlement.ErrDecksPerDayExceeded) {
+			skippedIds = append(skippedIds, value.UserId)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		studentIds = append(studentIds, value.UserId)
+	}
+
+	assignment := models.GroupAssignment{
+		GroupId:    groupId,
+		WordSetId:  &input.WordSetId,
+		ScheduleId: &input.ScheduleId,
+		Name:       name,
+		AssignedBy: &userId,
+		CreatedAt:  time.Now(),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.classroomRepo.WithTx(tx).CreateAssignment(&assignment); err != nil {
+			return err
+		}
+		return s.createStudentDecks(tx, &assignment, deckSchedule, wordSet.Cards, studentIds, nextReviewDate)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := classroom.AssignmentModelTo(&assignment)
+	result.SkippedStudentIds = skippedIds
+	return &result, nil
+}
+
+func (s *ClassroomService) GetAssignments(userId, groupId int) ([]classroom.AssignmentDTO, error) {
+	if _, err := s.requireMember(userId, groupId); err != nil {
+		return nil, err
+	}
+
+	assignments, err := s.classroomRepo.GetAssignments(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]classroom.AssignmentDTO, 0, len(assignments))
+	for i := range assignments {
+		result = append(result, classroom.AssignmentModelTo(&assignments[i]))
+	}
+	return result, nil
+}
+
+func (s *ClassroomService) GetAssignmentProgress(userId, groupId, assignmentId int) ([]classroom.StudentProgressDTO, error) {
+	if err := s.requireTeacher(userId, groupId); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.classroomRepo.GetAssignment(groupId, assignmentId); err != nil {
+		return nil, err
+	}
+
+	progress, err := s.classroomRepo.GetAssignmentProgress(groupId, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+	return classroom.StudentProgressResultTo(progress), nil
+}
+
+func (s *ClassroomService) GetStudentProgress(userId, groupId, assignmentId, studentId int) ([]classroom.CardProgressDTO, error) {
+	if err := s.requireTeacher(userId, groupId); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.classroomRepo.GetAssignment(groupId, assignmentId); err != nil {
+		return nil, err
+	}
+
+	role, err := s.classroomRepo.GetMemberRole(groupId, studentId)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.GroupStudent {
+		return nil, classroom.ErrNotStudent
+	}
+
+	progress, err := s.classroomRepo.GetStudentProgress(assignmentId, studentId)
+	if err != nil {
+		return nil, err
+	}
+	return classroom.CardProgressResultTo(progress), nil
+}
@@ -0,0 +1,73 @@
+// Package concurrency holds the optimistic locking shared by the editable
+// resources: every update bumps the row's version, and a client may make an
+// update conditional on the version it last read, either with If-Match or
+// with a version field in the body.
+package concurrency
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrVersionConflict means the row was changed since the client read it.
+var ErrVersionConflict = errors.New("version_conflict")
+
+var ErrInvalidETag = errors.New("invalid If-Match header")
+
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func SetETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", ETag(version))
+}
+
+// NotModified answers 304 when If-None-Match already has the current
+// version.
+func NotModified(w http.ResponseWriter, r *http.Request, version int) bool {
+	match := r.Header.Get("If-None-Match")
+	if match == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(match, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == ETag(version) {
+			SetETag(w, version)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectedVersion returns the version the update must still find. If-Match
+// wins over the body's version; 0 means the update is unconditional.
+func ExpectedVersion(r *http.Request, bodyVersion int) (version int, fromHeader bool, err error) {
+	match := strings.TrimSpace(r.Header.Get("If-Match"))
+	if match == "" || match == "*" {
+		return bodyVersion, false, nil
+	}
+
+	tag := strings.TrimPrefix(match, "W/")
+	if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false, ErrInvalidETag
+	}
+	version, err = strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version <= 0 {
+		return 0, false, ErrInvalidETag
+	}
+	return version, true, nil
+}
+
+// WriteConflict answers a failed If-Match with 412 and a stale body version
+// with 409.
+func WriteConflict(w http.ResponseWriter, fromHeader bool) {
+	if fromHeader {
+		http.Error(w, ErrVersionConflict.Error(), http.StatusPreconditionFailed)
+		return
+	}
+	http.Error(w, ErrVersionConflict.Error(), http.StatusConflict)
+}
@@ -23,6 +23,7 @@ type CreateDeckResponseDTO struct {
 
 type ReviewResultsDTO struct {
 	Results []SubmitReviewDTO `json:"results"`
+	Version int               `json:"version"`
 }
 
 type SubmitReviewDTO struct {
@@ -43,12 +44,14 @@ type UpdateDeckRequestDTO struct {
 	Name           string    `json:"name"`
 	ScheduleId     int       `json:"scheduleId"`
 	NextReviewDate time.Time `json:"nextReviewDate"`
+	Version        int       `json:"version"`
 }
 
 type UpdateDecResposnsekDTO struct {
 	Name           string    `json:"name"`
 	ScheduleId     int       `json:"scheduleId"`
 	NextReviewDate time.Time `json:"nextReviewDate"`
+	Version        int       `json:"version"`
 }
 
 type GetDeckByIdResponseDTO struct {
@@ -59,6 +62,7 @@ type GetDeckByIdResponseDTO struct {
 	IsArchived           bool      `json:"isArchived"`
 	NextReviewDate       time.Time `json:"nextReviewDate"`
 	NextPrimaryDirection bool      `json:"nextPrimaryDirection"`
+	Version              int       `json:"version"`
 
 	ScheduleId int                  `json:"scheduleId"`
 	Schedule   schedule.ScheduleDTO `json:"schedule,omitempty"`
@@ -78,6 +82,7 @@ func DeckByIdModetlTo(m *models.Deck) GetDeckByIdResponseDTO {
 		IsArchived:           m.IsArchived,
 		NextReviewDate:       m.NextReviewDate,
 		NextPrimaryDirection: m.NextPrimaryDirection,
+		Version:              m.Version,
 		ScheduleId:           m.ScheduleId,
 		Schedule:             schedule.ScheduleModelTo(&m.Schedule),
 		Cards:                card.GetCardsModelTo(m.Cards),
@@ -108,3 +113,35 @@ func DeckHistoryModelTo(m []models.DeckHistory) []DeckHistoriesDTO {
 	}
 	return deckHistoryG
 }
+
+type DuplicateDeckRequestDTO struct {
+	Name string `json:"name"`
+}
+
+type MergeDecksRequestDTO struct {
+	SourceDeckId int `json:"sourceDeckId"`
+}
+
+type SplitDeckPartDTO struct {
+	Name    string `json:"name"`
+	CardIds []int  `json:"cardIds"`
+}
+
+type SplitDeckRequestDTO struct {
+	Parts []SplitDeckPartDTO `json:"parts"`
+}
+
+type DeckCardsRequestDTO struct {
+	CardIds []int `json:"cardIds"`
+}
+
+func DeckModelToListItem(m *models.Deck) GetAllDecksResponseDTO {
+	return GetAllDecksResponseDTO{
+		Id:             m.Id,
+		Name:           m.Name,
+		CurrentLevel:   m.CurrentLevel,
+		NextReviewDate: m.NextReviewDate,
+		CardsCount:     len(m.Cards),
+		IsArchived:     m.IsArchived,
+	}
+}
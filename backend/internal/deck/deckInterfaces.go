@@ -2,6 +2,7 @@ package deck
 
 import (
 	models "dimplom_harmonic/domain"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,10 +12,15 @@ type DeckService interface {
 	CreateDeck(deck CreateDeckRequestDTO, userId int) (*CreateDeckResponseDTO, error)
 	GetDecks(userID int, typeArch bool) ([]GetAllDecksResponseDTO, error)
 	GetDeckByID(userID, deckID int) (*models.Deck, error)
-	Review(scheduleId int, level int, domainResults []models.CardReveiewResult) (*ResponseReviewResult, error)
-	UpdateDeck(userId int, deckId int, input UpdateDeckRequestDTO) (*UpdateDecResposnsekDTO, error)
+	Review(userId, deckId, version int, reviewedAt time.Time, results []models.CardReveiewResult) (*ResponseReviewResult, error)
+	UpdateDeck(userId int, deckId int, version int, input UpdateDeckRequestDTO) (*UpdateDecResposnsekDTO, error)
 	RestartProgressDeck(userId, deckId int) error
 	DeleteDeck(deckId int, userId int) error
+	DuplicateDeck(userId, deckId int, name string) (*GetAllDecksResponseDTO, error)
+	MergeDecks(userId, targetDeckId, sourceDeckId int) (*GetAllDecksResponseDTO, error)
+	SplitDeck(userId, deckId int, input SplitDeckRequestDTO) ([]GetAllDecksResponseDTO, error)
+	AddCardsToDeck(userId, deckId int, cardIds []int) (*GetAllDecksResponseDTO, error)
+	RemoveCardsFromDeck(userId, deckId int, cardIds []int) (*GetAllDecksResponseDTO, error)
 }
 
 type DeckRepository interface {
@@ -22,19 +28,37 @@ type DeckRepository interface {
 	GetDecks(userID int, typeArch bool) ([]DeckGetAllResult, error)
 	GetByID(userID, deckID int) (*models.Deck, error)
 	CreateHistory(deckHistory *models.DeckHistory) error
-	Update(userId int, deckId int, deck map[string]any) error
+	Update(userId int, deckId int, version int, deck map[string]any) (int, error)
 	DeleteHistories(deckId int) error
 	DeleteDeck(deckId int, userId int) error
 	AddConection(deck *models.Deck, cards []models.Card) error
+	RemoveConection(deck *models.Deck, cards []models.Card) error
+	GetDeleted(userId int) ([]models.Deck, error)
+	Restore(userId, deckId int) error
+	CopyHistories(fromDeckId, toDeckId int) error
 	GetDeckStatsForUser(userId int) (*GetUserStatsResult, error)
 	GetCountDeck(userId int, currentDate string) (*int, error)
+	CountCards(deckId int) (int, error)
+	PurgeUserDecks(userId int) error
 	WithTx(tx *gorm.DB) DeckRepository
 }
 
+var (
+	ErrMergeSameDeck      = errors.New("cant_merge_deck_with_itself")
+	ErrSchedulesMismatch  = errors.New("decks_schedules_mismatch")
+	ErrNoSplitParts       = errors.New("no_split_parts")
+	ErrEmptySplitPart     = errors.New("empty_split_part")
+	ErrCardNotInDeck      = errors.New("card_not_in_deck")
+	ErrCardSelectedTwice  = errors.New("card_selected_twice")
+	ErrSplitLeavesNoCards = errors.New("split_leaves_deck_empty")
+	ErrNoCards            = errors.New("no_cards")
+)
+
 type ResponseReviewResult struct {
 	Accuracy       int
 	Level          int
 	NextReviewDate time.Time
+	Version        int
 }
 
 type DeckHistory struct {
@@ -2,13 +2,18 @@ package handler
 
 import (
 	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/concurrency"
 	"dimplom_harmonic/internal/deck"
+	"dimplom_harmonic/internal/entitlement"
 	"dimplom_harmonic/internal/middleware"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
 type DeckHandler struct {
@@ -78,7 +83,11 @@ func (h *DeckHandler) HDGetDeckByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "This deck not found", http.StatusNotFound)
 		return
 	}
+	if concurrency.NotModified(w, r, deckG.Version) {
+		return
+	}
 
+	concurrency.SetETag(w, deckG.Version)
 	w.Header().Set("Content-Type", "Application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(deck.DeckByIdModetlTo(deckG))
@@ -96,6 +105,17 @@ func (h *DeckHandler) HDReview(w http.ResponseWriter, r *http.Request) {
 
 	var input deck.ReviewResultsDTO
 	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format JSON", http.StatusBadRequest)
+		return
+	}
+
+	version, fromHeader, err := concurrency.ExpectedVersion(r, input.Version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	domainResults := make([]models.CardReveiewResult, len(input.Results))
 
 	for i, value := range input.Results {
@@ -105,12 +125,17 @@ func (h *DeckHandler) HDReview(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	responseData, err := h.service.Review(userId, deckId, domainResults)
+	responseData, err := h.service.Review(userId, deckId, version, time.Now(), domainResults)
+	if errors.Is(err, concurrency.ErrVersionConflict) {
+		concurrency.WriteConflict(w, fromHeader)
+		return
+	}
 	if err != nil {
 		http.Error(w, "This deck not found", http.StatusNotFound)
 		return
 	}
 
+	concurrency.SetETag(w, responseData.Version)
 	w.Header().Set("Content-Type", "Application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(responseData)
@@ -132,8 +157,28 @@ func (h *DeckHandler) HDUpdateDeck(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Wrong Json Format", http.StatusBadRequest)
 		return
 	}
-	updatedDecks, err := h.service.UpdateDeck(userId, deckId, input)
 
+	version, fromHeader, err := concurrency.ExpectedVersion(r, input.Version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedDecks, err := h.service.UpdateDeck(userId, deckId, version, input)
+	if errors.Is(err, concurrency.ErrVersionConflict) {
+		concurrency.WriteConflict(w, fromHeader)
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "This deck not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	concurrency.SetETag(w, updatedDecks.Version)
 	w.Header().Set("Content-Type", "Application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(&updatedDecks)
@@ -176,3 +221,156 @@ func (h *DeckHandler) HDDeleteDeck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "Application/json")
 	w.WriteHeader(http.StatusOK)
 }
+
+func (h *DeckHandler) HDDuplicateDeck(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	deckId, err := strconv.Atoi(chi.URLParam(r, "deckID"))
+	if err != nil {
+		http.Error(w, "Invalid deck_id", http.StatusBadRequest)
+		return
+	}
+
+	var input deck.DuplicateDeckRequestDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format JSON", http.StatusBadRequest)
+		return
+	}
+
+	newDeck, err := h.service.DuplicateDeck(userId, deckId, input.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newDeck)
+}
+
+func (h *DeckHandler) HDMergeDecks(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	deckId, err := strconv.Atoi(chi.URLParam(r, "deckID"))
+	if err != nil {
+		http.Error(w, "Invalid deck_id", http.StatusBadRequest)
+		return
+	}
+
+	var input deck.MergeDecksRequestDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format JSON", http.StatusBadRequest)
+		return
+	}
+
+	mergedDeck, err := h.service.MergeDecks(userId, deckId, input.SourceDeckId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mergedDeck)
+}
+
+func (h *DeckHandler) HDSplitDeck(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	deckId, err := strconv.Atoi(chi.URLParam(r, "deckID"))
+	if err != nil {
+		http.Error(w, "Invalid deck_id", http.StatusBadRequest)
+		return
+	}
+
+	var input deck.SplitDeckRequestDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format JSON", http.StatusBadRequest)
+		return
+	}
+
+	newDecks, err := h.service.SplitDeck(userId, deckId, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newDecks)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, entitlement.ErrDecksPerDayExceeded), errors.Is(err, entitlement.ErrCardsPerDeckExceeded):
+		status = http.StatusPaymentRequired
+	case errors.Is(err, entitlement.ErrEmailNotVerified):
+		status = http.StatusForbidden
+	case errors.Is(err, deck.ErrMergeSameDeck), errors.Is(err, deck.ErrSchedulesMismatch),
+		errors.Is(err, deck.ErrNoSplitParts), errors.Is(err, deck.ErrEmptySplitPart),
+		errors.Is(err, deck.ErrCardNotInDeck), errors.Is(err, deck.ErrCardSelectedTwice),
+		errors.Is(err, deck.ErrSplitLeavesNoCards), errors.Is(err, deck.ErrNoCards):
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": err.Error(),
+	})
+}
+
+func (h *DeckHandler) HDAddCardsToDeck(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	deckId, err := strconv.Atoi(chi.URLParam(r, "deckID"))
+	if err != nil {
+		http.Error(w, "Invalid deck_id", http.StatusBadRequest)
+		return
+	}
+
+	var input deck.DeckCardsRequestDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format JSON", http.StatusBadRequest)
+		return
+	}
+
+	updatedDeck, err := h.service.AddCardsToDeck(userId, deckId, input.CardIds)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedDeck)
+}
+
+func (h *DeckHandler) HDRemoveCardsFromDeck(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	deckId, err := strconv.Atoi(chi.URLParam(r, "deckID"))
+	if err != nil {
+		http.Error(w, "Invalid deck_id", http.StatusBadRequest)
+		return
+	}
+
+	var input deck.DeckCardsRequestDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format JSON", http.StatusBadRequest)
+		return
+	}
+
+	updatedDeck, err := h.service.RemoveCardsFromDeck(userId, deckId, input.CardIds)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedDeck)
+}
@@ -2,9 +2,11 @@ package repository
 
 import (
 	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/concurrency"
 	"dimplom_harmonic/internal/deck"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DeckRepository struct {
@@ -33,8 +35,9 @@ func (r *DeckRepository) GetDecks(userId int, typeArch bool) ([]deck.DeckGetAllR
 	var decks []deck.DeckGetAllResult
 
 	err := r.db.Model(&models.Deck{}).
-		Select("decks.*, COUNT(deck_cards.card_id) as cards_count").
+		Select("decks.*, COUNT(cards.id) as cards_count").
 		Joins("LEFT JOIN deck_cards ON deck_cards.deck_id = decks.id").
+		Joins("LEFT JOIN cards ON cards.id = deck_cards.card_id AND cards.deleted_at IS NULL").
 		Where("decks.user_id = ?", userId).
 		Where("is_archived = ?", typeArch).
 		Group("decks.id").
@@ -65,12 +68,30 @@ func (r *DeckRepository) CreateHistory(deckHistory *models.DeckHistory) error {
 	return nil
 }
 
-func (r *DeckRepository) Update(userId int, deckId int, deck map[string]any) error {
-	err := r.db.Model(&models.Deck{}).Where("id = ? AND user_id = ?", deckId, userId).Updates(deck).Error
-	if err != nil {
-		return err
+// Update bumps the deck's version and returns the new one. A non-zero
+// version makes the update conditional on the deck still having it.
+func (r *DeckRepository) Update(userId int, deckId int, version int, deck map[string]any) (int, error) {
+	var updated models.Deck
+
+	query := r.db.Model(&updated).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "version"}}}).
+		Where("id = ? AND user_id = ?", deckId, userId)
+	if version != 0 {
+		query = query.Where("version = ?", version)
 	}
-	return nil
+
+	deck["version"] = gorm.Expr("version + 1")
+	result := query.Updates(deck)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if version != 0 {
+			return 0, concurrency.ErrVersionConflict
+		}
+		return 0, gorm.ErrRecordNotFound
+	}
+	return updated.Version, nil
 }
 
 func (r *DeckRepository) DeleteHistories(deckId int) error {
@@ -90,6 +111,49 @@ func (r *DeckRepository) AddConection(deck *models.Deck, cards []models.Card) er
 	return r.db.Model(deck).Association("Cards").Append(cards)
 }
 
+func (r *DeckRepository) GetDeleted(userId int) ([]models.Deck, error) {
+	var decks []models.Deck
+	err := r.db.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userId).
+		Order("deleted_at DESC").
+		Find(&decks).Error
+	if err != nil {
+		return nil, err
+	}
+	return decks, nil
+}
+
+func (r *DeckRepository) Restore(userId, deckId int) error {
+	result := r.db.Unscoped().Model(&models.Deck{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", deckId, userId).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *DeckRepository) RemoveConection(deck *models.Deck, cards []models.Card) error {
+	return r.db.Model(deck).Association("Cards").Delete(cards)
+}
+
+func (r *DeckRepository) CopyHistories(fromDeckId, toDeckId int) error {
+	query := `
+		INSERT INTO 
+			deck_histories (deck_id, review_date, accuracy)
+		SELECT 
+			?, dh.review_date, dh.accuracy
+		FROM 
+			deck_histories dh
+		WHERE 
+			dh.deck_id = ?
+	`
+	return r.db.Exec(query, toDeckId, fromDeckId).Error
+}
+
 func (r *DeckRepository) GetDeckStatsForUser(userId int) (*deck.GetUserStatsResult, error) {
 	var res deck.GetUserStatsResult
 
@@ -97,11 +161,11 @@ func (r *DeckRepository) GetDeckStatsForUser(userId int) (*deck.GetUserStatsResu
 		SELECT 
 			COUNT(DISTINCT CASE WHEN d.is_archived = false THEN 1 END) as active_decks,
 			COUNT(DISTINCT CASE WHEN d.is_archived = true THEN 1 END) as archived_decks,
-			(SELECT COUNT(*) FROM deck_histories dh JOIN decks d2 ON d2.id = dh.deck_id WHERE d2.user_id = ?) as total_reviews
+			(SELECT COUNT(*) FROM deck_histories dh JOIN decks d2 ON d2.id = dh.deck_id WHERE d2.user_id = ? AND d2.deleted_at IS NULL) as total_reviews
 		FROM 
 			decks d 
 		WHERE 
-			user_id = ?
+			user_id = ? AND d.deleted_at IS NULL
 	`
 
 	err := r.db.Raw(query, userId, userId).Scan(&res).Error
@@ -113,6 +177,29 @@ func (r *DeckRepository) GetDeckStatsForUser(userId int) (*deck.GetUserStatsResu
 	return &res, nil
 }
 
+func (r *DeckRepository) CountCards(deckId int) (int, error) {
+	var countCards int
+
+	query := `
+		SELECT 
+			COUNT(*)
+		FROM 
+			deck_cards dc
+		JOIN 
+			cards c ON c.id = dc.card_id
+		WHERE 
+			dc.deck_id = ? AND c.deleted_at IS NULL
+	`
+
+	err := r.db.Raw(query, deckId).Scan(&countCards).Error
+	if err != nil {
+		return 0, err
+	}
+	return countCards, nil
+}
+
+// GetCountDeck counts the decks created since currentDate, trashed ones
+// too, so trashing and restoring a deck doesn't get around the daily limit.
 func (r *DeckRepository) GetCountDeck(userId int, currentDate string) (*int, error) {
 
 	var countDeck int
@@ -128,6 +215,15 @@ func (r *DeckRepository) GetCountDeck(userId int, currentDate string) (*int, err
 			d.user_id = ?
 	`
 
-	r.db.Raw(query, currentDate, userId).Scan(&countDeck)
+	err := r.db.Raw(query, currentDate, userId).Scan(&countDeck).Error
+	if err != nil {
+		return nil, err
+	}
 	return &countDeck, nil
 }
+
+// PurgeUserDecks hard-deletes all the user's decks, trashed ones too. Links
+// and histories go with them.
+func (r *DeckRepository) PurgeUserDecks(userId int) error {
+	return r.db.Unscoped().Where("user_id = ?", userId).Delete(&models.Deck{}).Error
+}
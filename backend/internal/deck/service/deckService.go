@@ -2,12 +2,15 @@ package service
 
 import (
 	models "dimplom_harmonic/domain"
-	"dimplom_harmonic/internal/auth"
 	"dimplom_harmonic/internal/card"
+	"dimplom_harmonic/internal/concurrency"
 	"dimplom_harmonic/internal/deck"
+	"dimplom_harmonic/internal/entitlement"
+	"dimplom_harmonic/internal/events"
+	"dimplom_harmonic/internal/realtime"
 	"dimplom_harmonic/internal/schedule"
 	wordset "dimplom_harmonic/internal/wordSet"
-	"errors"
+	"fmt"
 	"sort"
 	"time"
 
@@ -18,53 +21,33 @@ type DeckService struct {
 	deckRepo     deck.DeckRepository
 	scheduleRepo schedule.ScheduleRepository
 	cardRepo     card.CardRepository
-	userRepo     auth.UserRepository
 	wordSetRepo  wordset.WordSetRepository
+	entitlements entitlement.EntitlementService
+	outboxRepo   events.OutboxRepository
+	publisher    realtime.Publisher
 	db           *gorm.DB
 }
 
-func NewDeckService(deckRepo deck.DeckRepository, scheduleRepo schedule.ScheduleRepository, cardRepo card.CardRepository, userRepo auth.UserRepository, wordSetRepo wordset.WordSetRepository, db *gorm.DB) deck.DeckService {
+func NewDeckService(deckRepo deck.DeckRepository, scheduleRepo schedule.ScheduleRepository, cardRepo card.CardRepository, wordSetRepo wordset.WordSetRepository, entitlements entitlement.EntitlementService, outboxRepo events.OutboxRepository, publisher realtime.Publisher, db *gorm.DB) deck.DeckService {
 	return &DeckService{
 		deckRepo:     deckRepo,
 		scheduleRepo: scheduleRepo,
 		cardRepo:     cardRepo,
-		userRepo:     userRepo,
 		wordSetRepo:  wordSetRepo,
+		entitlements: entitlements,
+		outboxRepo:   outboxRepo,
+		publisher:    publisher,
 		db:           db,
 	}
 }
 
 func (s *DeckService) CreateDeck(input deck.CreateDeckRequestDTO, userId int) (*deck.CreateDeckResponseDTO, error) {
 
-	user, err := s.userRepo.GetByID(userId)
+	err := s.checkDeckLimits(userId, len(input.ExistingCardIds)+len(input.NewCards), 1)
 	if err != nil {
 		return nil, err
 	}
 
-	var isPremium bool
-	if user.PremiumExpiresAt.After(time.Now()) {
-		isPremium = true
-	} else {
-		isPremium = false
-	}
-
-	if isPremium == false {
-
-		if len(input.ExistingCardIds)+len(input.NewCards) > 7 {
-			return nil, errors.New("free_limit_words_exceeded")
-		}
-
-		currentData := time.Now().Format("2006-01-02")
-		countDecks, err := s.deckRepo.GetCountDeck(userId, currentData)
-
-		if err != nil {
-			return nil, err
-		}
-
-		if *countDecks >= 1 {
-			return nil, errors.New("free_limit_decks_exceeded")
-		}
-	}
 	newDeck := &models.Deck{
 		UserId:               userId,
 		Name:                 input.Name,
@@ -102,6 +85,10 @@ func (s *DeckService) CreateDeck(input deck.CreateDeckRequestDTO, userId int) (*
 				return err
 			}
 			newDeck.Cards = cards
+
+			if err := events.Record(s.outboxRepo.WithTx(tx), events.CardsCreated(userId, cards)...); err != nil {
+				return err
+			}
 		}
 		var cardsToLink []models.Card
 
@@ -182,12 +169,19 @@ func (s *DeckService) GetDeckByID(userId, deckId int) (*models.Deck, error) {
 	return deckG, nil
 }
 
-func (s *DeckService) Review(userId int, deckId int, results []models.CardReveiewResult) (*deck.ResponseReviewResult, error) {
+// Review records the results of a session held at reviewedAt and moves the
+// deck along its schedule. A non-zero version must match the deck's one;
+// either way the update only applies to the deck as it was read, so two
+// concurrent reviews can't both advance it.
+func (s *DeckService) Review(userId, deckId, version int, reviewedAt time.Time, results []models.CardReveiewResult) (*deck.ResponseReviewResult, error) {
 
 	dataDeck, err := s.deckRepo.GetByID(userId, deckId)
 	if err != nil {
 		return nil, err
 	}
+	if version != 0 && dataDeck.Version != version {
+		return nil, concurrency.ErrVersionConflict
+	}
 
 	changeDeck := make(map[string]any)
 
@@ -203,7 +197,7 @@ func (s *DeckService) Review(userId int, deckId int, results []models.CardReveie
 			UserId:     userId,
 			DeckId:     deckId,
 			CardId:     value.CardId,
-			ReviewDate: time.Now(),
+			ReviewDate: reviewedAt,
 			IsCorrect:  value.IsCorrect,
 		}
 		historyBatch = append(historyBatch, cardHistory)
@@ -213,7 +207,7 @@ func (s *DeckService) Review(userId int, deckId int, results []models.CardReveie
 
 	deckHistory := models.DeckHistory{
 		DeckId:     deckId,
-		ReviewDate: time.Now(),
+		ReviewDate: reviewedAt,
 		Accuracy:   int(successRate),
 	}
 
@@ -241,7 +235,7 @@ func (s *DeckService) Review(userId int, deckId int, results []models.CardReveie
 
 	if currentStep != nil {
 
-		nextReviewDate := time.Now().Add(time.Duration(currentStep.IntervalMinutes) * time.Minute)
+		nextReviewDate := reviewedAt.Add(time.Duration(currentStep.IntervalMinutes) * time.Minute)
 		newCurrentStep := currentStep.Level + 1
 
 		changeDeck["NextReviewDate"] = nextReviewDate
@@ -251,7 +245,7 @@ func (s *DeckService) Review(userId int, deckId int, results []models.CardReveie
 	} else {
 		changeDeck["IsArchived"] = true
 		responceData.Level = dataDeck.CurrentLevel
-		responceData.NextReviewDate = time.Now().AddDate(1000, 0, 0)
+		responceData.NextReviewDate = reviewedAt.AddDate(1000, 0, 0)
 	}
 
 	if successRate >= 65 {
@@ -259,34 +253,73 @@ func (s *DeckService) Review(userId int, deckId int, results []models.CardReveie
 	}
 
 	err = s.db.Transaction(func(tx *gorm.DB) error {
-		err = s.cardRepo.CreateHistory(historyBatch)
+		txCardRepo := s.cardRepo.WithTx(tx)
+		txDeckRepo := s.deckRepo.WithTx(tx)
+
+		err := txCardRepo.CreateHistory(historyBatch)
 		if err != nil {
 			return err
 		}
 
-		err = s.deckRepo.CreateHistory(&deckHistory)
+		err = txDeckRepo.CreateHistory(&deckHistory)
 		if err != nil {
 			return err
 		}
 
-		err = s.deckRepo.Update(userId, deckId, changeDeck)
+		responceData.Version, err = txDeckRepo.Update(userId, deckId, dataDeck.Version, changeDeck)
 		if err != nil {
 			return err
 		}
-		return nil
+
+		reviewed := make([]events.Event, 0, len(results)+2)
+		reviewed = append(reviewed, events.DeckReviewed{
+			UserId:         userId,
+			DeckId:         deckId,
+			Accuracy:       responceData.Accuracy,
+			Level:          responceData.Level,
+			CardsCount:     len(results),
+			CorrectCount:   countCorrect,
+			IsArchived:     changeDeck["IsArchived"] == true,
+			NextReviewDate: responceData.NextReviewDate,
+			ReviewedAt:     reviewedAt,
+		})
+		for _, value := range results {
+			reviewed = append(reviewed, events.CardReviewed{
+				UserId:     userId,
+				DeckId:     deckId,
+				CardId:     value.CardId,
+				IsCorrect:  value.IsCorrect,
+				ReviewedAt: reviewedAt,
+			})
+		}
+		if changeDeck["IsArchived"] == true {
+			reviewed = append(reviewed, events.DeckArchived{UserId: userId, DeckId: deckId, ArchivedAt: reviewedAt})
+		}
+		return events.Record(s.outboxRepo.WithTx(tx), reviewed...)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.publisher.Publish(realtime.NewEvent(realtime.EventDeckReviewed, []int{userId}, map[string]any{
+		"deckId":         deckId,
+		"accuracy":       responceData.Accuracy,
+		"level":          responceData.Level,
+		"nextReviewDate": responceData.NextReviewDate,
+		"version":        responceData.Version,
+	}))
 
 	return &responceData, nil
 }
 
-func (s *DeckService) UpdateDeck(userId int, deckId int, input deck.UpdateDeckRequestDTO) (*deck.UpdateDecResposnsekDTO, error) {
+func (s *DeckService) UpdateDeck(userId int, deckId int, version int, input deck.UpdateDeckRequestDTO) (*deck.UpdateDecResposnsekDTO, error) {
 	changeDeck := make(map[string]any, 4)
 
 	changeDeck["Name"] = input.Name
 	changeDeck["NextReviewDate"] = input.NextReviewDate
 	changeDeck["ScheduleId"] = input.ScheduleId
 
-	err := s.deckRepo.Update(userId, deckId, changeDeck)
+	newVersion, err := s.deckRepo.Update(userId, deckId, version, changeDeck)
 	if err != nil {
 		return nil, err
 	}
@@ -295,6 +328,7 @@ func (s *DeckService) UpdateDeck(userId int, deckId int, input deck.UpdateDeckRe
 		Name:           input.Name,
 		ScheduleId:     input.ScheduleId,
 		NextReviewDate: input.NextReviewDate,
+		Version:        newVersion,
 	}
 
 	return &updatedDeck, nil
@@ -322,7 +356,7 @@ func (s *DeckService) RestartProgressDeck(userId, deckId int) error {
 		changeDeck["NextReviewDate"] = time.Now()
 		changeDeck["is_archived"] = false
 
-		err = txDeckRepo.Update(userId, deckId, changeDeck)
+		_, err = txDeckRepo.Update(userId, deckId, 0, changeDeck)
 		if err != nil {
 			return err
 		}
@@ -338,3 +372,402 @@ func (s *DeckService) DeleteDeck(deckId int, userId int) error {
 	}
 	return nil
 }
+
+// checkDeckLimits checks the plan limits for an operation producing newDecks
+// decks with at most cardsCount cards each.
+func (s *DeckService) checkDeckLimits(userId int, cardsCount int, newDecks int) error {
+	err := s.entitlements.CheckCardsPerDeck(userId, cardsCount)
+	if err != nil {
+		return err
+	}
+
+	return s.entitlements.CheckDecksPerDay(userId, newDecks)
+}
+
+// DuplicateDeck clones the deck with the same cards and schedule but with
+// fresh progress: no histories are copied and the deck starts at level 0.
+func (s *DeckService) DuplicateDeck(userId, deckId int, name string) (*deck.GetAllDecksResponseDTO, error) {
+
+	source, err := s.deckRepo.GetByID(userId, deckId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkDeckLimits(userId, len(source.Cards), 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = source.Name + " (copy)"
+	}
+
+	newDeck := &models.Deck{
+		UserId:               userId,
+		Name:                 name,
+		CreatedAt:            time.Now(),
+		CurrentLevel:         0,
+		NextReviewDate:       time.Now(),
+		NextPrimaryDirection: true,
+		ScheduleId:           source.ScheduleId,
+	}
+
+	cardsToLink := cardRefs(source.Cards)
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txDeckRepo := s.deckRepo.WithTx(tx)
+
+		if err := txDeckRepo.CreateDeck(newDeck, userId); err != nil {
+			return err
+		}
+
+		if len(cardsToLink) != 0 {
+			if err := txDeckRepo.AddConection(newDeck, cardsToLink); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newDeck.Cards = cardsToLink
+	responseDeck := deck.DeckModelToListItem(newDeck)
+	return &responseDeck, nil
+}
+
+// MergeDecks moves the cards of the source deck into the target deck and
+// moves the source to the trash. Both decks must use the same schedule.
+//
+// Card histories of the source follow their cards into the target, so the
+// per-card statistics survive. The trashed source keeps no cards, so
+// restoring it can't link the same cards to two decks with one history.
+// Deck histories of the source stay with the trashed deck, since its session
+// accuracy says nothing about the merged deck.
+// The merged deck keeps the lower level and the earlier review date, so no
+// card skips a repetition.
+func (s *DeckService) MergeDecks(userId, targetDeckId, sourceDeckId int) (*deck.GetAllDecksResponseDTO, error) {
+
+	if targetDeckId == sourceDeckId {
+		return nil, deck.ErrMergeSameDeck
+	}
+
+	target, err := s.deckRepo.GetByID(userId, targetDeckId)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.deckRepo.GetByID(userId, sourceDeckId)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.ScheduleId != source.ScheduleId {
+		return nil, deck.ErrSchedulesMismatch
+	}
+
+	inTarget := make(map[int]bool, len(target.Cards))
+	for _, value := range target.Cards {
+		inTarget[value.Id] = true
+	}
+
+	var cardsToLink []models.Card
+	var sourceCardIds []int
+	for _, value := range source.Cards {
+		sourceCardIds = append(sourceCardIds, value.Id)
+		if !inTarget[value.Id] {
+			cardsToLink = append(cardsToLink, models.Card{Id: value.Id})
+		}
+	}
+
+	err = s.checkDeckLimits(userId, len(target.Cards)+len(cardsToLink), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	changeDeck := make(map[string]any)
+	if source.CurrentLevel < target.CurrentLevel {
+		changeDeck["CurrentLevel"] = source.CurrentLevel
+		target.CurrentLevel = source.CurrentLevel
+	}
+	if source.NextReviewDate.Before(target.NextReviewDate) {
+		changeDeck["NextReviewDate"] = source.NextReviewDate
+		target.NextReviewDate = source.NextReviewDate
+	}
+	if target.IsArchived && !source.IsArchived {
+		changeDeck["is_archived"] = false
+		target.IsArchived = false
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txDeckRepo := s.deckRepo.WithTx(tx)
+		txCardRepo := s.cardRepo.WithTx(tx)
+
+		if len(cardsToLink) != 0 {
+			if err := txDeckRepo.AddConection(target, cardsToLink); err != nil {
+				return err
+			}
+		}
+
+		if len(sourceCardIds) != 0 {
+			if err := txCardRepo.MoveHistories(sourceDeckId, targetDeckId, sourceCardIds); err != nil {
+				return err
+			}
+			if err := txDeckRepo.RemoveConection(source, cardRefs(source.Cards)); err != nil {
+				return err
+			}
+		}
+
+		if len(changeDeck) != 0 {
+			if _, err := txDeckRepo.Update(userId, targetDeckId, 0, changeDeck); err != nil {
+				return err
+			}
+		}
+
+		return txDeckRepo.DeleteDeck(sourceDeckId, userId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	responseDeck := deck.DeckModelToListItem(target)
+	responseDeck.CardsCount = len(target.Cards) + len(cardsToLink)
+	return &responseDeck, nil
+}
+
+// SplitDeck moves the selected cards of a deck into new decks, one per part.
+//
+// New decks continue where the original left off: they inherit its schedule,
+// level and review date, get a copy of its deck histories and take over the
+// card histories of the cards they receive.
+func (s *DeckService) SplitDeck(userId, deckId int, input deck.SplitDeckRequestDTO) ([]deck.GetAllDecksResponseDTO, error) {
+
+	if len(input.Parts) == 0 {
+		return nil, deck.ErrNoSplitParts
+	}
+
+	source, err := s.deckRepo.GetByID(userId, deckId)
+	if err != nil {
+		return nil, err
+	}
+
+	inSource := make(map[int]bool, len(source.Cards))
+	for _, value := range source.Cards {
+		inSource[value.Id] = true
+	}
+
+	used := make(map[int]bool)
+	maxPart := 0
+	for _, part := range input.Parts {
+		if len(part.CardIds) == 0 {
+			return nil, deck.ErrEmptySplitPart
+		}
+		for _, id := range part.CardIds {
+			if !inSource[id] {
+				return nil, deck.ErrCardNotInDeck
+			}
+			if used[id] {
+				return nil, deck.ErrCardSelectedTwice
+			}
+			used[id] = true
+		}
+		if len(part.CardIds) > maxPart {
+			maxPart = len(part.CardIds)
+		}
+	}
+
+	if len(used) == len(source.Cards) {
+		return nil, deck.ErrSplitLeavesNoCards
+	}
+
+	err = s.checkDeckLimits(userId, maxPart, len(input.Parts))
+	if err != nil {
+		return nil, err
+	}
+
+	var newDecks []*models.Deck
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txDeckRepo := s.deckRepo.WithTx(tx)
+		txCardRepo := s.cardRepo.WithTx(tx)
+
+		for i, part := range input.Parts {
+			name := part.Name
+			if name == "" {
+				name = fmt.Sprintf("%s (%d)", source.Name, i+1)
+			}
+
+			newDeck := &models.Deck{
+				UserId:               userId,
+				Name:                 name,
+				CreatedAt:            time.Now(),
+				CurrentLevel:         source.CurrentLevel,
+				IsArchived:           source.IsArchived,
+				NextReviewDate:       source.NextReviewDate,
+				NextPrimaryDirection: source.NextPrimaryDirection,
+				ScheduleId:           source.ScheduleId,
+			}
+
+			if err := txDeckRepo.CreateDeck(newDeck, userId); err != nil {
+				return err
+			}
+
+			var cards []models.Card
+			for _, id := range part.CardIds {
+				cards = append(cards, models.Card{Id: id})
+			}
+
+			if err := txDeckRepo.AddConection(newDeck, cards); err != nil {
+				return err
+			}
+
+			if err := txDeckRepo.RemoveConection(source, cards); err != nil {
+				return err
+			}
+
+			if err := txCardRepo.MoveHistories(deckId, newDeck.Id, part.CardIds); err != nil {
+				return err
+			}
+
+			if err := txDeckRepo.CopyHistories(deckId, newDeck.Id); err != nil {
+				return err
+			}
+
+			newDeck.Cards = cards
+			newDecks = append(newDecks, newDeck)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var responseDecks []deck.GetAllDecksResponseDTO
+	for _, value := range newDecks {
+		responseDecks = append(responseDecks, deck.DeckModelToListItem(value))
+	}
+
+	return responseDecks, nil
+}
+
+// AddCardsToDeck links existing cards of the user (from word sets or the
+// difficult-words set) to the deck. Like CreateDeck, the linked cards leave
+// the difficult-words set.
+func (s *DeckService) AddCardsToDeck(userId, deckId int, cardIds []int) (*deck.GetAllDecksResponseDTO, error) {
+
+	if len(cardIds) == 0 {
+		return nil, deck.ErrNoCards
+	}
+
+	deckA, err := s.deckRepo.GetByID(userId, deckId)
+	if err != nil {
+		return nil, err
+	}
+
+	ownedIds, err := s.cardRepo.GetOwnedCardIds(userId, cardIds)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make(map[int]bool, len(ownedIds))
+	for _, id := range ownedIds {
+		owned[id] = true
+	}
+
+	inDeck := make(map[int]bool, len(deckA.Cards))
+	for _, value := range deckA.Cards {
+		inDeck[value.Id] = true
+	}
+
+	var cardsToLink []models.Card
+	var idsToLink []int
+	for _, id := range cardIds {
+		if !owned[id] {
+			return nil, gorm.ErrRecordNotFound
+		}
+		if inDeck[id] {
+			continue
+		}
+		inDeck[id] = true
+		cardsToLink = append(cardsToLink, models.Card{Id: id})
+		idsToLink = append(idsToLink, id)
+	}
+
+	err = s.checkDeckLimits(userId, len(deckA.Cards)+len(cardsToLink), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cardsToLink) != 0 {
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			txDeckRepo := s.deckRepo.WithTx(tx)
+			txCardRepo := s.cardRepo.WithTx(tx)
+			txWordSetRepo := s.wordSetRepo.WithTx(tx)
+
+			if err := txDeckRepo.AddConection(deckA, cardsToLink); err != nil {
+				return err
+			}
+
+			defaultWordSet, err := txWordSetRepo.GetDefault(userId)
+			if err != nil {
+				return err
+			}
+
+			return txCardRepo.DeleteCardFromDefaultWordSet(defaultWordSet.Id, idsToLink)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	responseDeck := deck.DeckModelToListItem(deckA)
+	responseDeck.CardsCount = len(deckA.Cards) + len(cardsToLink)
+	return &responseDeck, nil
+}
+
+// RemoveCardsFromDeck unlinks cards from the deck. Cards left without any deck
+// or word set are removed later by the cleaner.
+func (s *DeckService) RemoveCardsFromDeck(userId, deckId int, cardIds []int) (*deck.GetAllDecksResponseDTO, error) {
+
+	if len(cardIds) == 0 {
+		return nil, deck.ErrNoCards
+	}
+
+	deckR, err := s.deckRepo.GetByID(userId, deckId)
+	if err != nil {
+		return nil, err
+	}
+
+	inDeck := make(map[int]bool, len(deckR.Cards))
+	for _, value := range deckR.Cards {
+		inDeck[value.Id] = true
+	}
+
+	var cardsToRemove []models.Card
+	for _, id := range cardIds {
+		if inDeck[id] {
+			inDeck[id] = false
+			cardsToRemove = append(cardsToRemove, models.Card{Id: id})
+		}
+	}
+
+	if len(cardsToRemove) != 0 {
+		err = s.deckRepo.RemoveConection(deckR, cardsToRemove)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	responseDeck := deck.DeckModelToListItem(deckR)
+	responseDeck.CardsCount = len(deckR.Cards) - len(cardsToRemove)
+	return &responseDeck, nil
+}
+
+func cardRefs(cards []models.Card) []models.Card {
+	var refs []models.Card
+	for _, value := range cards {
+		refs = append(refs, models.Card{Id: value.Id})
+	}
+	return refs
+}
@@ -0,0 +1,27 @@
+package entitlement
+
+// QuotaLimit describes one counted limit. Limit and Remaining are nil when the
+// plan doesn't restrict it.
+type QuotaLimit struct {
+	Limit     *int `json:"limit"`
+	Used      int  `json:"used"`
+	Remaining *int `json:"remaining"`
+}
+
+type Quota struct {
+	PlanId         string     `json:"planId"`
+	DecksToday     QuotaLimit `json:"decksToday"`
+	WordSets       QuotaLimit `json:"wordSets"`
+	CardsPerDeck   *int       `json:"cardsPerDeck"`
+	MediaStorageMb *int       `json:"mediaStorageMb"`
+}
+
+func NewQuotaLimit(limit *int, used int) QuotaLimit {
+	quotaLimit := QuotaLimit{Limit: limit, Used: used}
+
+	if limit != nil {
+		remaining := max(*limit-used, 0)
+		quotaLimit.Remaining = &remaining
+	}
+	return quotaLimit
+}
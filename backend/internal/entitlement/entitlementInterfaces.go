@@ -0,0 +1,45 @@
+package entitlement
+
+import (
+	models "dimplom_harmonic/domain"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// EntitlementService is the single place that knows what a user's plan allows.
+type EntitlementService interface {
+	IsPremium(user *models.User) bool
+	Status(user *models.User) string
+	GetPlan(user *models.User) (*models.Plan, error)
+	GetQuota(userId int) (*Quota, error)
+
+	CheckDecksPerDay(userId int, newDecks int) error
+	CheckCardsPerDeck(userId int, cardsCount int) error
+	CheckCardsAddToDeck(userId int, deckId int, addCount int) error
+	CheckWordSets(userId int, newWordSets int) error
+	RequireVerifiedEmail(userId int) error
+}
+
+type EntitlementRepository interface {
+	GetPlan(planId string) (*models.Plan, error)
+	WithTx(tx *gorm.DB) EntitlementRepository
+}
+
+const (
+	PlanFree    = "free"
+	PlanPremium = "premium"
+)
+
+const (
+	StatusFree     = "free"
+	StatusPremium  = "premium"
+	StatusLifetime = "lifetime"
+)
+
+var (
+	ErrDecksPerDayExceeded  = errors.New("free_limit_decks_exceeded")
+	ErrCardsPerDeckExceeded = errors.New("free_limit_words_exceeded")
+	ErrWordSetsExceeded     = errors.New("free_limit_word_sets_exceeded")
+	ErrEmailNotVerified     = errors.New("email_not_verified")
+)
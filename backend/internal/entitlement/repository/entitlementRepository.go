@@ -0,0 +1,32 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/entitlement"
+
+	"gorm.io/gorm"
+)
+
+type EntitlementRepository struct {
+	db *gorm.DB
+}
+
+func NewEntitlementRepository(db *gorm.DB) *EntitlementRepository {
+	return &EntitlementRepository{db: db}
+}
+
+func (r *EntitlementRepository) WithTx(tx *gorm.DB) entitlement.EntitlementRepository {
+	return &EntitlementRepository{
+		db: tx,
+	}
+}
+
+func (r *EntitlementRepository) GetPlan(planId string) (*models.Plan, error) {
+	var plan models.Plan
+
+	err := r.db.Where("id = ?", planId).First(&plan).Error
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/auth"
+	"dimplom_harmonic/internal/deck"
+	"dimplom_harmonic/internal/entitlement"
+	wordset "dimplom_harmonic/internal/wordSet"
+	"time"
+)
+
+// lifetimeThreshold marks premium that never runs out.
+const lifetimeThreshold = 100 * 365 * 24 * time.Hour
+
+type EntitlementService struct {
+	entitlementRepo entitlement.EntitlementRepository
+	userRepo        auth.UserRepository
+	deckRepo        deck.DeckRepository
+	wordSetRepo     wordset.WordSetRepository
+}
+
+func NewEntitlementService(entitlementRepo entitlement.EntitlementRepository, userRepo auth.UserRepository, deckRepo deck.DeckRepository, wordSetRepo wordset.WordSetRepository) *EntitlementService {
+	return &EntitlementService{
+		entitlementRepo: entitlementRepo,
+		userRepo:        userRepo,
+		deckRepo:        deckRepo,
+		wordSetRepo:     wordSetRepo,
+	}
+}
+
+func (s *EntitlementService) IsPremium(user *models.User) bool {
+	return user.PremiumExpiresAt.After(time.Now())
+}
+
+func (s *EntitlementService) Status(user *models.User) string {
+	if user.PremiumExpiresAt.After(time.Now().Add(lifetimeThreshold)) {
+		return entitlement.StatusLifetime
+	}
+	if s.IsPremium(user) {
+		return entitlement.StatusPremium
+	}
+	return entitlement.StatusFree
+}
+
+func (s *EntitlementService) GetPlan(user *models.User) (*models.Plan, error) {
+	if s.IsPremium(user) {
+		return s.entitlementRepo.GetPlan(entitlement.PlanPremium)
+	}
+	return s.entitlementRepo.GetPlan(entitlement.PlanFree)
+}
+
+func (s *EntitlementService) getUserPlan(userId int) (*models.Plan, error) {
+	user, err := s.userRepo.GetByID(userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPlan(user)
+}
+
+func (s *EntitlementService) GetQuota(userId int) (*entitlement.Quota, error) {
+
+	plan, err := s.getUserPlan(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	decksToday, err := s.countDecksToday(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	wordSets, err := s.wordSetRepo.CountWordSets(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := entitlement.Quota{
+		PlanId:         plan.Id,
+		DecksToday:     entitlement.NewQuotaLimit(plan.DecksPerDay, decksToday),
+		WordSets:       entitlement.NewQuotaLimit(plan.WordSets, wordSets),
+		CardsPerDeck:   plan.CardsPerDeck,
+		MediaStorageMb: plan.MediaStorageMb,
+	}
+
+	return &quota, nil
+}
+
+func (s *EntitlementService) CheckDecksPerDay(userId int, newDecks int) error {
+
+	plan, err := s.getUserPlan(userId)
+	if err != nil {
+		return err
+	}
+
+	if plan.DecksPerDay == nil || newDecks == 0 {
+		return nil
+	}
+
+	decksToday, err := s.countDecksToday(userId)
+	if err != nil {
+		return err
+	}
+
+	if decksToday+newDecks > *plan.DecksPerDay {
+		return entitlement.ErrDecksPerDayExceeded
+	}
+	return nil
+}
+
+func (s *EntitlementService) CheckCardsPerDeck(userId int, cardsCount int) error {
+
+	plan, err := s.getUserPlan(userId)
+	if err != nil {
+		return err
+	}
+
+	if plan.CardsPerDeck != nil && cardsCount > *plan.CardsPerDeck {
+		return entitlement.ErrCardsPerDeckExceeded
+	}
+	return nil
+}
+
+func (s *EntitlementService) CheckCardsAddToDeck(userId int, deckId int, addCount int) error {
+
+	plan, err := s.getUserPlan(userId)
+	if err != nil {
+		return err
+	}
+
+	if plan.CardsPerDeck == nil {
+		return nil
+	}
+
+	cardsCount, err := s.deckRepo.CountCards(deckId)
+	if err != nil {
+		return err
+	}
+
+	if cardsCount+addCount > *plan.CardsPerDeck {
+		return entitlement.ErrCardsPerDeckExceeded
+	}
+	return nil
+}
+
+func (s *EntitlementService) CheckWordSets(userId int, newWordSets int) error {
+
+	plan, err := s.getUserPlan(userId)
+	if err != nil {
+		return err
+	}
+
+	if plan.WordSets == nil {
+		return nil
+	}
+
+	wordSets, err := s.wordSetRepo.CountWordSets(userId)
+	if err != nil {
+		return err
+	}
+
+	if wordSets+newWordSets > *plan.WordSets {
+		return entitlement.ErrWordSetsExceeded
+	}
+	return nil
+}
+
+// RequireVerifiedEmail gates features that expose content to other users.
+func (s *EntitlementService) RequireVerifiedEmail(userId int) error {
+
+	user, err := s.userRepo.GetByID(userId)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		return entitlement.ErrEmailNotVerified
+	}
+	return nil
+}
+
+func (s *EntitlementService) countDecksToday(userId int) (int, error) {
+	currentData := time.Now().Format("2006-01-02")
+
+	countDecks, err := s.deckRepo.GetCountDeck(userId, currentData)
+	if err != nil {
+		return 0, err
+	}
+	return *countDecks, nil
+}
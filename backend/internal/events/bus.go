@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type handler struct {
+	name   string
+	handle func(ctx context.Context, payload []byte) error
+}
+
+// Bus keeps the subscribers of each event type.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]handler)}
+}
+
+// Subscribe registers handle for events of type E under name, which shows up
+// in dispatch errors. Handlers may see an event more than once, so they
+// must be idempotent.
+func Subscribe[E Event](bus *Bus, name string, handle func(ctx context.Context, event E) error) {
+	var zero E
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.handlers[zero.EventType()] = append(bus.handlers[zero.EventType()], handler{
+		name: name,
+		handle: func(ctx context.Context, payload []byte) error {
+			var event E
+			if err := json.Unmarshal(payload, &event); err != nil {
+				return err
+			}
+			return handle(ctx, event)
+		},
+	})
+}
+
+// Dispatch runs every subscriber of the event type. All of them run even if
+// some fail; the errors are joined.
+func (b *Bus) Dispatch(ctx context.Context, eventType string, payload []byte) error {
+	b.mu.RLock()
+	handlers := b.handlers[eventType]
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, h := range handlers {
+		if err := h.handle(ctx, payload); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
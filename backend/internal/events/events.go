@@ -0,0 +1,147 @@
+// Package events carries domain events from the core services to features
+// that react to them, like notifications, stats and webhooks. Services save
+// events to the outbox in the same transaction as the change; the dispatcher
+// then hands them to the subscribers, at least once.
+package events
+
+import (
+	"context"
+	models "dimplom_harmonic/domain"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Event interface {
+	EventType() string
+}
+
+const (
+	TypeDeckReviewed   = "deck.reviewed"
+	TypeCardReviewed   = "card.reviewed"
+	TypeDeckArchived   = "deck.archived"
+	TypeCardCreated    = "card.created"
+	TypeUserRegistered = "user.registered"
+)
+
+// DeckReviewed is raised once per review session, next to a CardReviewed for
+// each answered card.
+type DeckReviewed struct {
+	UserId         int       `json:"userId"`
+	DeckId         int       `json:"deckId"`
+	Accuracy       int       `json:"accuracy"`
+	Level          int       `json:"level"`
+	CardsCount     int       `json:"cardsCount"`
+	CorrectCount   int       `json:"correctCount"`
+	IsArchived     bool      `json:"isArchived"`
+	NextReviewDate time.Time `json:"nextReviewDate"`
+	ReviewedAt     time.Time `json:"reviewedAt"`
+}
+
+func (DeckReviewed) EventType() string { return TypeDeckReviewed }
+
+type CardReviewed struct {
+	UserId     int       `json:"userId"`
+	DeckId     int       `json:"deckId"`
+	CardId     int       `json:"cardId"`
+	IsCorrect  bool      `json:"isCorrect"`
+	ReviewedAt time.Time `json:"reviewedAt"`
+}
+
+func (CardReviewed) EventType() string { return TypeCardReviewed }
+
+// DeckArchived is raised when a review moves a deck past the last step of
+// its schedule.
+type DeckArchived struct {
+	UserId     int       `json:"userId"`
+	DeckId     int       `json:"deckId"`
+	ArchivedAt time.Time `json:"archivedAt"`
+}
+
+func (DeckArchived) EventType() string { return TypeDeckArchived }
+
+type CardCreated struct {
+	UserId     int   `json:"userId"`
+	CardId     int   `json:"cardId"`
+	DeckIds    []int `json:"deckIds"`
+	WordSetIds []int `json:"wordSetIds"`
+}
+
+func (CardCreated) EventType() string { return TypeCardCreated }
+
+// CardsCreated builds a CardCreated for each card, with the decks and word
+// sets it was created in.
+func CardsCreated(userId int, cards []models.Card) []Event {
+	created := make([]Event, 0, len(cards))
+	for _, c := range cards {
+		event := CardCreated{
+			UserId:     userId,
+			CardId:     c.Id,
+			DeckIds:    make([]int, 0, len(c.Decks)),
+			WordSetIds: make([]int, 0, len(c.WordSets)),
+		}
+		for _, d := range c.Decks {
+			event.DeckIds = append(event.DeckIds, d.Id)
+		}
+		for _, ws := range c.WordSets {
+			event.WordSetIds = append(event.WordSetIds, ws.Id)
+		}
+		created = append(created, event)
+	}
+	return created
+}
+
+type UserRegistered struct {
+	UserId       int       `json:"userId"`
+	Login        string    `json:"login"`
+	Email        string    `json:"email"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+func (UserRegistered) EventType() string { return TypeUserRegistered }
+
+type eventIdKey struct{}
+
+// WithEventId stores the outbox id of the event being dispatched. Subscribers
+// can use it to drop events they have already handled.
+func WithEventId(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, eventIdKey{}, id)
+}
+
+// EventId returns the outbox id set by WithEventId, or 0.
+func EventId(ctx context.Context) int64 {
+	id, _ := ctx.Value(eventIdKey{}).(int64)
+	return id
+}
+
+type OutboxRepository interface {
+	Add(events []models.OutboxEvent) error
+	// ClaimPending locks up to limit events that are due, skipping ones
+	// another dispatcher holds. Call it inside a transaction.
+	ClaimPending(limit int) ([]models.OutboxEvent, error)
+	MarkDispatched(id int64) error
+	MarkFailed(id int64, attempts int, lastError string, retryAt time.Time) error
+	WithTx(tx *gorm.DB) OutboxRepository
+}
+
+// Record saves the events to the outbox. Pass the repository of the
+// transaction making the change, so the events exist only if it commits.
+func Record(repo OutboxRepository, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	rows := make([]models.OutboxEvent, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, models.OutboxEvent{
+			EventType: event.EventType(),
+			Payload:   string(payload),
+		})
+	}
+	return repo.Add(rows)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/events"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+func (r *OutboxRepository) WithTx(tx *gorm.DB) events.OutboxRepository {
+	return &OutboxRepository{
+		db: tx,
+	}
+}
+
+func (r *OutboxRepository) Add(rows []models.OutboxEvent) error {
+	return r.db.Omit("LastError", "AvailableAt", "DispatchedAt", "CreatedAt").Create(&rows).Error
+}
+
+func (r *OutboxRepository) ClaimPending(limit int) ([]models.OutboxEvent, error) {
+	var rows []models.OutboxEvent
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("dispatched_at IS NULL AND available_at <= NOW()").
+		Order("id").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *OutboxRepository) MarkDispatched(id int64) error {
+	return r.db.Model(&models.OutboxEvent{}).
+		Where("id = ?", id).
+		Update("dispatched_at", time.Now()).Error
+}
+
+func (r *OutboxRepository) MarkFailed(id int64, attempts int, lastError string, retryAt time.Time) error {
+	return r.db.Model(&models.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"attempts":     attempts,
+			"last_error":   lastError,
+			"available_at": retryAt,
+		}).Error
+}
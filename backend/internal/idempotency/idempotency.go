@@ -0,0 +1,54 @@
+// Package idempotency remembers the responses of unsafe requests sent with an
+// Idempotency-Key header, so a retried request gets the first response back
+// instead of being applied twice.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const Header = "Idempotency-Key"
+
+// MaxKeyLength matches the key column.
+const MaxKeyLength = 255
+
+// InProgressLease is how long a claimed key without a response blocks
+// retries. After that the request is taken for dead (a crash or a restart)
+// and a retry may claim the key again.
+const InProgressLease = time.Minute
+
+type Store interface {
+	// Begin claims key for the user. When the key is already taken and not
+	// expired, it returns the existing record and false instead. A key still
+	// in progress after InProgressLease can be claimed again.
+	Begin(userId int, key, fingerprint string, ttl time.Duration) (*Record, bool, error)
+	// Complete stores the response of a claimed key.
+	Complete(userId int, key string, response Response) error
+	// Release frees a claimed key, so the request can be retried.
+	Release(userId int, key string) error
+}
+
+// Record is a claimed key. Response stays nil while the first request is
+// still running.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+}
+
+type Response struct {
+	StatusCode  int
+	ContentType string
+	ETag        string
+	Body        []byte
+}
+
+// Fingerprint identifies the request a key was used with, so the same key
+// can't be replayed for a different request.
+func Fingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
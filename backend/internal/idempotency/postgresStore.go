@@ -0,0 +1,86 @@
+package idempotency
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Begin takes the key over when the stored one has expired, so the cleaner
+// running late doesn't block a new request, or when its request has been in
+// progress longer than InProgressLease.
+func (s *PostgresStore) Begin(userId int, key, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	now := time.Now()
+
+	var claimed []int64
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status_code = NULL,
+			content_type = '',
+			etag = '',
+			response_body = NULL,
+			created_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= ?
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at <= ?)
+		RETURNING id
+	`
+	err := s.db.Raw(query, userId, key, fingerprint, now.Add(ttl), now, now.Add(-InProgressLease)).Scan(&claimed).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(claimed) != 0 {
+		return nil, true, nil
+	}
+
+	var row struct {
+		Fingerprint  string
+		StatusCode   *int
+		ContentType  string
+		Etag         string
+		ResponseBody []byte
+	}
+	err = s.db.Raw(`
+		SELECT fingerprint, status_code, content_type, etag, response_body
+		FROM idempotency_keys
+		WHERE user_id = ? AND key = ?
+	`, userId, key).Scan(&row).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	record := Record{Fingerprint: row.Fingerprint}
+	if row.StatusCode != nil {
+		record.Response = &Response{
+			StatusCode:  *row.StatusCode,
+			ContentType: row.ContentType,
+			ETag:        row.Etag,
+			Body:        row.ResponseBody,
+		}
+	}
+	return &record, false, nil
+}
+
+func (s *PostgresStore) Complete(userId int, key string, response Response) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = ?, content_type = ?, etag = ?, response_body = ?
+		WHERE user_id = ? AND key = ?
+	`
+	return s.db.Exec(query, response.StatusCode, response.ContentType, response.ETag, response.Body, userId, key).Error
+}
+
+func (s *PostgresStore) Release(userId int, key string) error {
+	return s.db.Exec(`DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?`, userId, key).Error
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	models "dimplom_harmonic/domain"
+	"time"
+)
+
+// JobDTO shows the job's metrics on the instance that answers; runs on
+// other instances are in the history.
+type JobDTO struct {
+	Name            string     `json:"name"`
+	IntervalSeconds int        `json:"intervalSeconds"`
+	Running         bool       `json:"running"`
+	Runs            int64      `json:"runs"`
+	Failures        int64      `json:"failures"`
+	Skipped         int64      `json:"skipped"`
+	LastStartedAt   *time.Time `json:"lastStartedAt"`
+	LastDurationMs  int64      `json:"lastDurationMs"`
+	LastAffected    int64      `json:"lastAffected"`
+	LastError       string     `json:"lastError,omitempty"`
+}
+
+type RunDTO struct {
+	Id         int64      `json:"id"`
+	Instance   string     `json:"instance"`
+	Status     string     `json:"status"`
+	Affected   int64      `json:"affected"`
+	Error      *string    `json:"error"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt"`
+	DurationMs *int       `json:"durationMs"`
+}
+
+func RunModelTo(m *models.JobRun) RunDTO {
+	return RunDTO{
+		Id:         m.Id,
+		Instance:   m.Instance,
+		Status:     m.Status,
+		Affected:   m.Affected,
+		Error:      m.Error,
+		StartedAt:  m.StartedAt,
+		FinishedAt: m.FinishedAt,
+		DurationMs: m.DurationMs,
+	}
+}
+
+type RunPageDTO struct {
+	Runs  []RunDTO `json:"runs"`
+	Total int64    `json:"total"`
+	Page  int      `json:"page"`
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"dimplom_harmonic/internal/jobs"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+type JobsHandler struct {
+	service jobs.JobService
+}
+
+func NewJobsHandler(service jobs.JobService) *JobsHandler {
+	return &JobsHandler{service: service}
+}
+
+func pageParams(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	return page, min(pageSize, maxPageSize)
+}
+
+func (h *JobsHandler) HDGetJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.service.GetJobs())
+}
+
+func (h *JobsHandler) HDGetRuns(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := pageParams(r)
+
+	runs, err := h.service.GetRuns(chi.URLParam(r, "jobName"), page, pageSize)
+	if errors.Is(err, jobs.ErrUnknownJob) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(runs)
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+	models "dimplom_harmonic/domain"
+	"errors"
+	"time"
+)
+
+// Job is a periodic task. Across all instances it runs at most once per
+// Interval, and never twice at the same time.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Run does one pass and returns how much it did, like the number of
+	// deleted rows. It should stop early once ctx is cancelled.
+	Run func(ctx context.Context) (int64, error)
+}
+
+type JobService interface {
+	GetJobs() []JobDTO
+	GetRuns(name string, page, pageSize int) (*RunPageDTO, error)
+}
+
+type JobRepository interface {
+	// TryLock takes the Postgres advisory lock for key on a connection of
+	// its own, since the lock belongs to the session. release unlocks it.
+	TryLock(ctx context.Context, key int64) (release func(), locked bool, err error)
+	GetLastStart(name string) (*time.Time, error)
+	// FailAbandoned closes runs left "running" by an instance that stopped
+	// mid-run. Call it while holding the job's lock.
+	FailAbandoned(name string) error
+	StartRun(run *models.JobRun) error
+	FinishRun(run *models.JobRun) error
+	GetRuns(name string, limit, offset int) ([]models.JobRun, int64, error)
+}
+
+var ErrUnknownJob = errors.New("unknown_job")
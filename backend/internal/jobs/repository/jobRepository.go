@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	models "dimplom_harmonic/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+func (r *JobRepository) TryLock(ctx context.Context, key int64) (func(), bool, error) {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&locked)
+	if err != nil || !locked {
+		conn.Close()
+		return nil, false, err
+	}
+
+	release := func() {
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, key)
+		if err != nil {
+			// The lock must not go back to the pool with the connection;
+			// dropping the connection ends the session and frees it.
+			log.Printf("Advisory unlock error: %v", err)
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return release, true, nil
+}
+
+func (r *JobRepository) GetLastStart(name string) (*time.Time, error) {
+	var startedAt []time.Time
+	err := r.db.Model(&models.JobRun{}).
+		Where("job_name = ?", name).
+		Order("started_at DESC").
+		Limit(1).
+		Pluck("started_at", &startedAt).Error
+	if err != nil || len(startedAt) == 0 {
+		return nil, err
+	}
+	return &startedAt[0], nil
+}
+
+func (r *JobRepository) FailAbandoned(name string) error {
+	query := `
+		UPDATE job_runs
+		SET status = 'failed', error = 'instance stopped during the run', finished_at = NOW()
+		WHERE job_name = ? AND status = 'running'
+	`
+	return r.db.Exec(query, name).Error
+}
+
+func (r *JobRepository) StartRun(run *models.JobRun) error {
+	return r.db.Omit("Error", "FinishedAt", "DurationMs").Create(run).Error
+}
+
+func (r *JobRepository) FinishRun(run *models.JobRun) error {
+	return r.db.Model(run).Select("Status", "Affected", "Error", "FinishedAt", "DurationMs").Updates(run).Error
+}
+
+func (r *JobRepository) GetRuns(name string, limit, offset int) ([]models.JobRun, int64, error) {
+	var runs []models.JobRun
+	var total int64
+
+	err := r.db.Model(&models.JobRun{}).Where("job_name = ?", name).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = r.db.
+		Where("job_name = ?", name).
+		Order("started_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&runs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
@@ -0,0 +1,223 @@
+// Package jobs runs periodic background jobs. Every instance runs the same
+// jobs; a Postgres advisory lock per job elects the one that does each run,
+// and the job_runs history keeps runs Interval apart across instances.
+package jobs
+
+import (
+	"context"
+	models "dimplom_harmonic/domain"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkInterval is how often an instance checks whether a job is due.
+const checkInterval = time.Minute
+
+type metrics struct {
+	running       bool
+	runs          int64
+	failures      int64
+	skipped       int64
+	lastStartedAt *time.Time
+	lastDuration  time.Duration
+	lastAffected  int64
+	lastError     string
+}
+
+type Runner struct {
+	repo     JobRepository
+	instance string
+	jobs     []Job
+
+	mu      sync.Mutex
+	metrics map[string]*metrics
+	wg      sync.WaitGroup
+}
+
+func NewRunner(repo JobRepository) *Runner {
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+	return &Runner{repo: repo, instance: instance, metrics: make(map[string]*metrics)}
+}
+
+// Register adds a job. Call it before Start.
+func (r *Runner) Register(jobs ...Job) {
+	for _, job := range jobs {
+		r.jobs = append(r.jobs, job)
+		r.metrics[job.Name] = &metrics{}
+	}
+}
+
+// Start checks every job right away and then every checkInterval, until ctx
+// is cancelled. A running job gets the cancelled ctx too.
+func (r *Runner) Start(ctx context.Context) {
+	for _, job := range r.jobs {
+		r.wg.Go(func() {
+			tiker := time.NewTicker(min(job.Interval, checkInterval))
+			defer tiker.Stop()
+
+			for {
+				r.runIfDue(ctx, job)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-tiker.C:
+				}
+			}
+		})
+	}
+}
+
+// Wait returns once every job has stopped after the Start ctx is cancelled.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("jobs:" + name))
+	return int64(h.Sum64())
+}
+
+func (r *Runner) runIfDue(ctx context.Context, job Job) {
+	release, locked, err := r.repo.TryLock(ctx, lockKey(job.Name))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Job %s lock error: %v", job.Name, err)
+		}
+		return
+	}
+	if !locked {
+		r.update(job.Name, func(m *metrics) { m.skipped++ })
+		return
+	}
+	defer release()
+
+	lastStart, err := r.repo.GetLastStart(job.Name)
+	if err != nil {
+		log.Printf("Job %s history error: %v", job.Name, err)
+		return
+	}
+	if lastStart != nil && time.Since(*lastStart) < job.Interval {
+		return
+	}
+
+	if err := r.repo.FailAbandoned(job.Name); err != nil {
+		log.Printf("Job %s history error: %v", job.Name, err)
+		return
+	}
+
+	run := models.JobRun{
+		JobName:   job.Name,
+		Instance:  r.instance,
+		Status:    models.JobRunning,
+		StartedAt: time.Now(),
+	}
+	if err := r.repo.StartRun(&run); err != nil {
+		log.Printf("Job %s history error: %v", job.Name, err)
+		return
+	}
+	r.update(job.Name, func(m *metrics) {
+		m.running = true
+		m.lastStartedAt = &run.StartedAt
+	})
+
+	affected, runErr := r.run(ctx, job)
+
+	finishedAt := time.Now()
+	duration := finishedAt.Sub(run.StartedAt)
+	durationMs := int(duration.Milliseconds())
+	run.FinishedAt = &finishedAt
+	run.DurationMs = &durationMs
+	run.Affected = affected
+	run.Status = models.JobSucceeded
+	if runErr != nil {
+		message := runErr.Error()
+		run.Error = &message
+		run.Status = models.JobFailed
+		if ctx.Err() != nil && errors.Is(runErr, context.Canceled) {
+			run.Status = models.JobCancelled
+		}
+		log.Printf("Job %s %s: %v", job.Name, run.Status, runErr)
+	}
+
+	if err := r.repo.FinishRun(&run); err != nil {
+		log.Printf("Job %s history error: %v", job.Name, err)
+	}
+	r.update(job.Name, func(m *metrics) {
+		m.running = false
+		m.runs++
+		m.lastDuration = duration
+		m.lastAffected = affected
+		m.lastError = ""
+		if runErr != nil {
+			m.failures++
+			m.lastError = runErr.Error()
+		}
+	})
+}
+
+// run calls the job, turning a panic into an error so one broken job
+// doesn't take the server down.
+func (r *Runner) run(ctx context.Context, job Job) (affected int64, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func (r *Runner) update(name string, change func(m *metrics)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change(r.metrics[name])
+}
+
+func (r *Runner) GetJobs() []JobDTO {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]JobDTO, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		m := r.metrics[job.Name]
+		result = append(result, JobDTO{
+			Name:            job.Name,
+			IntervalSeconds: int(job.Interval.Seconds()),
+			Running:         m.running,
+			Runs:            m.runs,
+			Failures:        m.failures,
+			Skipped:         m.skipped,
+			LastStartedAt:   m.lastStartedAt,
+			LastDurationMs:  m.lastDuration.Milliseconds(),
+			LastAffected:    m.lastAffected,
+			LastError:       m.lastError,
+		})
+	}
+	return result
+}
+
+func (r *Runner) GetRuns(name string, page, pageSize int) (*RunPageDTO, error) {
+	if _, ok := r.metrics[name]; !ok {
+		return nil, ErrUnknownJob
+	}
+
+	runs, total, err := r.repo.GetRuns(name, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]RunDTO, 0, len(runs))
+	for i := range runs {
+		result = append(result, RunModelTo(&runs[i]))
+	}
+	return &RunPageDTO{Runs: result, Total: total, Page: page}, nil
+}
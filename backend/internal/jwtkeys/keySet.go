@@ -0,0 +1,265 @@
+// Package jwtkeys signs and verifies access tokens. Tokens are signed with
+// one RSA (RS256) or Ed25519 (EdDSA) key and carry its id in the "kid"
+// header; any number of public keys are accepted for verification, which is
+// what makes key rotation possible. The public keys are published as a JWKS.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type verificationKey struct {
+	kid    string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+type KeySet struct {
+	signingKid    string
+	signingMethod jwt.SigningMethod
+	signingKey    crypto.PrivateKey
+
+	keys map[string]verificationKey
+
+	// legacySecret keeps HS256 tokens valid while moving off the shared
+	// secret. When there is no signing key it is also used to sign.
+	legacySecret []byte
+}
+
+// NewHMACKeySet signs and verifies with a shared HS256 secret, as before key
+// files were supported. Nothing is published in the JWKS.
+func NewHMACKeySet(secret []byte) *KeySet {
+	return &KeySet{keys: map[string]verificationKey{}, legacySecret: secret}
+}
+
+// LoadKeySet reads the PEM private key to sign with and the PEM public (or
+// private) keys to verify with. The signing key is always accepted for
+// verification. A non-empty legacySecret still accepts HS256 tokens.
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string, legacySecret []byte) (*KeySet, error) {
+	private, err := readPrivateKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	signing, err := newVerificationKey(publicOf(private))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", signingKeyFile, err)
+	}
+
+	k := &KeySet{
+		signingKid:    signing.kid,
+		signingMethod: signing.method,
+		signingKey:    private,
+		keys:          map[string]verificationKey{signing.kid: signing},
+		legacySecret:  legacySecret,
+	}
+
+	for _, file := range verificationKeyFiles {
+		public, err := readPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := newVerificationKey(public)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		k.keys[key.kid] = key
+	}
+
+	return k, nil
+}
+
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if k.signingKey == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.legacySecret)
+	}
+
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	token.Header["kid"] = k.signingKid
+	return token.SignedString(k.signingKey)
+}
+
+// Parse verifies the token's signature and expiry and fills claims.
+func (k *KeySet) Parse(tokenString string, claims jwt.Claims) error {
+	methods := []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+	if len(k.legacySecret) != 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, k.keyfunc, jwt.WithValidMethods(methods))
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+func (k *KeySet) keyfunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return k.legacySecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if key.method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("key %q doesn't sign with %s", kid, token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		jwk := publicJWK(key.public)
+		jwk.Kid = key.kid
+		jwk.Use = "sig"
+		jwk.Alg = key.method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func (k *KeySet) HDJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(k.JWKS())
+}
+
+func newVerificationKey(public crypto.PublicKey) (verificationKey, error) {
+	var method jwt.SigningMethod
+	switch public.(type) {
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", public)
+	}
+
+	return verificationKey{kid: thumbprint(public), method: method, public: public}, nil
+}
+
+func publicJWK(public crypto.PublicKey) JWK {
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: b64(key)}
+	}
+	return JWK{}
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint, used as the key id so it never
+// has to be configured and can't collide between different keys.
+func thumbprint(public crypto.PublicKey) string {
+	jwk := publicJWK(public)
+
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, jwk.X)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func publicOf(private crypto.PrivateKey) crypto.PublicKey {
+	if signer, ok := private.(crypto.Signer); ok {
+		return signer.Public()
+	}
+	return nil
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", file)
+	}
+	return block, nil
+}
+
+func readPrivateKey(file string) (crypto.PrivateKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return key, nil
+}
+
+// readPublicKey accepts a public key, or a private key whose public half is
+// used.
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "PUBLIC KEY" {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		return key, nil
+	}
+
+	private, err := readPrivateKey(file)
+	if err != nil {
+		return nil, err
+	}
+	return publicOf(private), nil
+}
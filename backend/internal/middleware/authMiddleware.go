@@ -3,19 +3,21 @@ package middleware
 import (
 	"context"
 	models "dimplom_harmonic/domain"
-	"fmt"
+	"dimplom_harmonic/internal/jwtkeys"
 	"log"
 	"net/http"
 	"strings"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type contextKey string
 
 const UserIDKey contextKey = "userID"
 
-func NewAuthMiddleware(secretKey []byte) func(http.Handler) http.Handler {
+// TokenExpiresAtKey holds the access token's expiry, for long-lived responses
+// that have to end when the token does.
+const TokenExpiresAtKey contextKey = "tokenExpiresAt"
+
+func NewAuthMiddleware(keys *jwtkeys.KeySet) func(http.Handler) http.Handler {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -35,15 +37,9 @@ func NewAuthMiddleware(secretKey []byte) func(http.Handler) http.Handler {
 			tokenString := headerParts[1]
 			claims := &models.AppClaims{}
 
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return secretKey, nil
-			})
-			log.Println("Token parsing error:", err)
-
-			if err != nil || !token.Valid {
+			err := keys.Parse(tokenString, claims)
+			if err != nil {
+				log.Println("Token parsing error:", err)
 				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 				return
 			}
@@ -51,7 +47,29 @@ func NewAuthMiddleware(secretKey []byte) func(http.Handler) http.Handler {
 			userID := claims.UserID
 
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			if claims.ExpiresAt != nil {
+				ctx = context.WithValue(ctx, TokenExpiresAtKey, claims.ExpiresAt.Time)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// NewStreamAuthMiddleware checks the same token as NewAuthMiddleware, but also
+// takes it from the access_token query parameter: EventSource can't send
+// headers.
+func NewStreamAuthMiddleware(keys *jwtkeys.KeySet) func(http.Handler) http.Handler {
+	auth := NewAuthMiddleware(keys)
+
+	return func(next http.Handler) http.Handler {
+		authed := auth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.URL.Query().Get("access_token")
+			if token != "" && r.Header.Get("Authorization") == "" {
+				r = r.Clone(r.Context())
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+			authed.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"dimplom_harmonic/internal/idempotency"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// maxIdempotentBody is the largest body a request with an Idempotency-Key
+// may have; it is read whole to fingerprint it.
+const maxIdempotentBody = 1 << 20
+
+// NewIdempotencyMiddleware replays the stored response when a request comes
+// again with the same Idempotency-Key. Keys are per user, so it goes after
+// the auth middleware. Requests without the header pass through, and so do
+// requests the store fails on.
+func NewIdempotencyMiddleware(store idempotency.Store, ttl time.Duration) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotency.Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idempotency.MaxKeyLength {
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+
+			userId := r.Context().Value(UserIDKey).(int)
+
+			// A body over the limit is refused before the key is claimed,
+			// so a retry with a smaller body isn't answered from the store.
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+			r.Body.Close()
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Can't read body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := idempotency.Fingerprint(r.Method, r.URL.Path, body)
+
+			record, claimed, err := store.Begin(userId, key, fingerprint, ttl)
+			if err != nil {
+				log.Printf("Idempotency key error: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !claimed {
+				switch {
+				case record.Fingerprint != fingerprint:
+					http.Error(w, "Idempotency-Key was used with another request", http.StatusUnprocessableEntity)
+				case record.Response == nil:
+					http.Error(w, "A request with this Idempotency-Key is in progress", http.StatusConflict)
+				default:
+					if record.Response.ContentType != "" {
+						w.Header().Set("Content-Type", record.Response.ContentType)
+					}
+					if record.Response.ETag != "" {
+						w.Header().Set("ETag", record.Response.ETag)
+					}
+					w.Header().Set("Idempotent-Replayed", "true")
+					w.WriteHeader(record.Response.StatusCode)
+					w.Write(record.Response.Body)
+				}
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			// Server errors aren't remembered: the request may well succeed
+			// when retried.
+			if recorder.statusCode >= http.StatusInternalServerError {
+				if err := store.Release(userId, key); err != nil {
+					log.Printf("Release idempotency key error: %v", err)
+				}
+				return
+			}
+
+			err = store.Complete(userId, key, idempotency.Response{
+				StatusCode:  recorder.statusCode,
+				ContentType: w.Header().Get("Content-Type"),
+				ETag:        w.Header().Get("ETag"),
+				Body:        recorder.body.Bytes(),
+			})
+			if err != nil {
+				log.Printf("Store idempotent response error: %v", err)
+			}
+		})
+	}
+}
+
+// responseRecorder passes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
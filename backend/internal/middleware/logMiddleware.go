@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// NewRedactQueryMiddleware hides the values of the given query parameters in
+// r.RequestURI, which is what the request logger prints. Routing and handlers
+// read r.URL and still see them. Use it before the logger.
+func NewRedactQueryMiddleware(params ...string) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+
+			redacted := false
+			for _, param := range params {
+				if query.Has(param) {
+					query.Set(param, "REDACTED")
+					redacted = true
+				}
+			}
+
+			if redacted {
+				uri := url.URL{Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: query.Encode()}
+				r = r.WithContext(r.Context())
+				r.RequestURI = uri.RequestURI()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
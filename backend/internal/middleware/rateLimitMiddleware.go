@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"dimplom_harmonic/internal/ratelimit"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// NewRateLimitMiddleware rejects requests over any of the limits with 429 and
+// Retry-After. If the store fails, requests are let through.
+func NewRateLimitMiddleware(store ratelimit.Store, limits ...ratelimit.Limit) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			for _, limit := range limits {
+				key := limit.KeyFunc(r)
+				if key == "" {
+					continue
+				}
+
+				result, err := store.Hit(limit.Name+":"+key, limit.Requests, limit.Window)
+				if err != nil {
+					log.Printf("Rate limit %s error: %v", limit.Name, err)
+					continue
+				}
+
+				if !result.Allowed {
+					retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+					w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+					http.Error(w, "Too many requests", http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
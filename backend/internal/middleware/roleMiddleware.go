@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	models "dimplom_harmonic/domain"
+	"net/http"
+	"slices"
+)
+
+type UserGetter interface {
+	GetByID(id int) (*models.User, error)
+}
+
+// NewRoleMiddleware lets through users with one of the roles. It goes after
+// the auth middleware and reads the role from the database, so a revoked
+// role stops working before the access token expires.
+func NewRoleMiddleware(users UserGetter, roles ...string) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			userId := r.Context().Value(UserIDKey).(int)
+
+			user, err := users.GetByID(userId)
+			if err != nil || !slices.Contains(roles, user.Role) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
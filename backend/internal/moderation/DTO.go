@@ -0,0 +1,63 @@
+package moderation
+
+import "time"
+
+type ReportRequestDTO struct {
+	Reason  string `json:"reason"`
+	Comment string `json:"comment"`
+}
+
+type ActionRequestDTO struct {
+	Reason string `json:"reason"`
+}
+
+type ReportDTO struct {
+	Id            int        `json:"id"`
+	TargetType    string     `json:"targetType"`
+	TargetId      int        `json:"targetId"`
+	WordSetId     int        `json:"wordSetId"`
+	WordSetName   string     `json:"wordSetName"`
+	WordSetHidden bool       `json:"wordSetHidden"`
+	CardWord      string     `json:"cardWord,omitempty"`
+	ReporterId    *int       `json:"reporterId"`
+	ReporterLogin string     `json:"reporterLogin"`
+	Reason        string     `json:"reason"`
+	Comment       string     `json:"comment"`
+	Status        string     `json:"status"`
+	OpenReports   int        `json:"openReports"`
+	ResolvedBy    *int       `json:"resolvedBy"`
+	ResolvedAt    *time.Time `json:"resolvedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+type ReportsPageDTO struct {
+	Reports []ReportDTO `json:"reports"`
+	Total   int64       `json:"total"`
+	Page    int         `json:"page"`
+}
+
+func ReportResultTo(r []ReportResult) []ReportDTO {
+	reports := make([]ReportDTO, 0, len(r))
+
+	for _, value := range r {
+		reports = append(reports, ReportDTO{
+			Id:            value.Id,
+			TargetType:    value.TargetType,
+			TargetId:      value.TargetId,
+			WordSetId:     value.WordSetId,
+			WordSetName:   value.WordSetName,
+			WordSetHidden: value.WordSetHidden,
+			CardWord:      value.CardWord,
+			ReporterId:    value.ReporterId,
+			ReporterLogin: value.ReporterLogin,
+			Reason:        value.Reason,
+			Comment:       value.Comment,
+			Status:        value.Status,
+			OpenReports:   value.OpenReports,
+			ResolvedBy:    value.ResolvedBy,
+			ResolvedAt:    value.ResolvedAt,
+			CreatedAt:     value.CreatedAt,
+		})
+	}
+	return reports
+}
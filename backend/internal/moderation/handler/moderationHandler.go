@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"dimplom_harmonic/internal/middleware"
+	"dimplom_harmonic/internal/moderation"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+type ModerationHandler struct {
+	service moderation.ModerationService
+}
+
+func NewModerationHandler(service moderation.ModerationService) *ModerationHandler {
+	return &ModerationHandler{service: service}
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+func pageParams(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	return page, min(pageSize, maxPageSize)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, moderation.ErrAlreadyReported), errors.Is(err, moderation.ErrReportNotOpen):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, moderation.ErrOwnContent):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func (h *ModerationHandler) HDReportWordSet(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	wordSetId, err := strconv.Atoi(chi.URLParam(r, "wordSetID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var input moderation.ReportRequestDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.ReportWordSet(userId, wordSetId, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *ModerationHandler) HDReportCard(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	wordSetId, err := strconv.Atoi(chi.URLParam(r, "wordSetID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cardId, err := strconv.Atoi(chi.URLParam(r, "cardID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var input moderation.ReportRequestDTO
+	err = json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.ReportCard(userId, wordSetId, cardId, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *ModerationHandler) HDGetReports(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := pageParams(r)
+
+	reports, err := h.service.GetReports(r.URL.Query().Get("status"), page, pageSize)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reports)
+}
+
+func (h *ModerationHandler) HDDismissReport(w http.ResponseWriter, r *http.Request) {
+	moderatorId := r.Context().Value(middleware.UserIDKey).(int)
+	reportId, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.DismissReport(moderatorId, reportId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *ModerationHandler) HDActionReport(w http.ResponseWriter, r *http.Request) {
+	moderatorId := r.Context().Value(middleware.UserIDKey).(int)
+	reportId, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var input moderation.ActionRequestDTO
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&input)
+		if err != nil {
+			http.Error(w, "Wrong format json", http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = h.service.ActionReport(moderatorId, reportId, input.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,56 @@
+package moderation
+
+import (
+	models "dimplom_harmonic/domain"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type ModerationService interface {
+	ReportWordSet(userId, wordSetId int, input ReportRequestDTO) error
+	ReportCard(userId, wordSetId, cardId int, input ReportRequestDTO) error
+	GetReports(status string, page, pageSize int) (*ReportsPageDTO, error)
+	DismissReport(moderatorId, reportId int) error
+	ActionReport(moderatorId, reportId int, reason string) error
+}
+
+type ModerationRepository interface {
+	CreateReport(report *models.ContentReport) (bool, error)
+	CountReporters(wordSetId int) (int, error)
+	HideWordSet(wordSetId int) error
+	UnhideWordSet(wordSetId int) error
+	GetReport(reportId int) (*models.ContentReport, error)
+	GetReports(status string, limit, offset int) ([]ReportResult, int64, error)
+	ResolveReports(wordSetId, moderatorId int, status string) (int64, error)
+	WithTx(tx *gorm.DB) ModerationRepository
+}
+
+type ReportResult struct {
+	models.ContentReport
+	ReporterLogin string
+	WordSetName   string
+	WordSetHidden bool
+	CardWord      string
+	OpenReports   int
+}
+
+const (
+	TargetWordSet = "word_set"
+	TargetCard    = "card"
+)
+
+const (
+	ActionDismissReports = "dismiss_reports"
+)
+
+var Reasons = []string{"spam", "offensive", "copyright", "wrong_content", "other"}
+
+var (
+	ErrInvalidReason    = errors.New("invalid_reason")
+	ErrInvalidStatus    = errors.New("invalid_status")
+	ErrOwnContent       = errors.New("cant_report_own_content")
+	ErrAlreadyReported  = errors.New("already_reported")
+	ErrReportNotOpen    = errors.New("report_not_open")
+	ErrCardNotInWordSet = errors.New("card_not_in_word_set")
+)
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/moderation"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ModerationRepository struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) *ModerationRepository {
+	return &ModerationRepository{db: db}
+}
+
+// CreateReport returns false when the reporter already has an open report
+// on the same target.
+func (r *ModerationRepository) CreateReport(report *models.ContentReport) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(report)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *ModerationRepository) CountReporters(wordSetId int) (int, error) {
+	var count int
+
+	query := `
+		SELECT 
+			COUNT(DISTINCT reporter_id)
+		FROM 
+			content_reports
+		WHERE 
+			word_set_id = ? AND status = ?
+	`
+	err := r.db.Raw(query, wordSetId, models.ReportOpen).Scan(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *ModerationRepository) HideWordSet(wordSetId int) error {
+	return r.db.Model(&models.WordSet{}).
+		Where("id = ? AND hidden_at IS NULL", wordSetId).
+		Update("hidden_at", time.Now()).Error
+}
+
+func (r *ModerationRepository) UnhideWordSet(wordSetId int) error {
+	return r.db.Model(&models.WordSet{}).
+		Where("id = ?", wordSetId).
+		Update("hidden_at", nil).Error
+}
+
+func (r *ModerationRepository) GetReport(reportId int) (*models.ContentReport, error) {
+	var report models.ContentReport
+	err := r.db.First(&report, reportId).Error
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *ModerationRepository) GetReports(status string, limit, offset int) ([]moderation.ReportResult, int64, error) {
+	var reports []moderation.ReportResult
+	var total int64
+
+	err := r.db.Model(&models.ContentReport{}).Where("status = ?", status).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT 
+			cr.*,
+			COALESCE(u.login, '') as reporter_login,
+			ws.name as word_set_name,
+			ws.hidden_at IS NOT NULL as word_set_hidden,
+			COALESCE(c.original_word, '') as card_word,
+			(SELECT COUNT(*) FROM content_reports o WHERE o.word_set_id = cr.word_set_id AND o.status = 'open') as open_reports
+		FROM 
+			content_reports cr
+			JOIN word_sets ws ON ws.id = cr.word_set_id
+			LEFT JOIN users u ON u.id = cr.reporter_id
+			LEFT JOIN cards c ON cr.target_type = 'card' AND c.id = cr.target_id
+		WHERE 
+			cr.status = ?
+		ORDER BY 
+			cr.created_at DESC, cr.id DESC
+		LIMIT ? OFFSET ?
+	`
+	err = r.db.Raw(query, status, limit, offset).Scan(&reports).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return reports, total, nil
+}
+
+// ResolveReports closes every open report of the word set, the ones about
+// its cards included.
+func (r *ModerationRepository) ResolveReports(wordSetId, moderatorId int, status string) (int64, error) {
+	result := r.db.Model(&models.ContentReport{}).
+		Where("word_set_id = ? AND status = ?", wordSetId, models.ReportOpen).
+		Updates(map[string]any{
+			"status":      status,
+			"resolved_by": moderatorId,
+			"resolved_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *ModerationRepository) WithTx(tx *gorm.DB) moderation.ModerationRepository {
+	return &ModerationRepository{
+		db: tx,
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/admin"
+	"dimplom_harmonic/internal/moderation"
+	wordset "dimplom_harmonic/internal/wordSet"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const maxCommentLength = 1000
+
+type ModerationService struct {
+	moderationRepo moderation.ModerationRepository
+	wordSetRepo    wordset.WordSetRepository
+	adminRepo      admin.AdminRepository
+	adminService   admin.AdminService
+	hideThreshold  int
+	db             *gorm.DB
+}
+
+// NewModerationService hides a public set once hideThreshold different users
+// have open reports on it; 0 turns auto-hiding off.
+func NewModerationService(moderationRepo moderation.ModerationRepository, wordSetRepo wordset.WordSetRepository, adminRepo admin.AdminRepository, adminService admin.AdminService, hideThreshold int, db *gorm.DB) *ModerationService {
+	return &ModerationService{
+		moderationRepo: moderationRepo,
+		wordSetRepo:    wordSetRepo,
+		adminRepo:      adminRepo,
+		adminService:   adminService,
+		hideThreshold:  hideThreshold,
+		db:             db,
+	}
+}
+
+// getPublicWordSet returns the set only if the user may report it. Private
+// sets look missing, so reports can't be used to probe for them.
+func (s *ModerationService) getPublicWordSet(userId, wordSetId int) (*wordset.WordSetGetResult, error) {
+	wordSet, err := s.wordSetRepo.GetWordSetByID(userId, wordSetId)
+	if err != nil {
+		return nil, err
+	}
+	if wordSet.Id == 0 || !wordSet.IsPublic {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if wordSet.UserId == userId {
+		return nil, moderation.ErrOwnContent
+	}
+	return wordSet, nil
+}
+
+func (s *ModerationService) ReportWordSet(userId, wordSetId int, input moderation.ReportRequestDTO) error {
+	if _, err := s.getPublicWordSet(userId, wordSetId); err != nil {
+		return err
+	}
+
+	return s.createReport(userId, moderation.TargetWordSet, wordSetId, wordSetId, input)
+}
+
+func (s *ModerationService) ReportCard(userId, wordSetId, cardId int, input moderation.ReportRequestDTO) error {
+	wordSet, err := s.getPublicWordSet(userId, wordSetId)
+	if err != nil {
+		return err
+	}
+
+	inWordSet := slices.ContainsFunc(wordSet.Cards, func(c models.Card) bool {
+		return c.Id == cardId
+	})
+	if !inWordSet {
+		return moderation.ErrCardNotInWordSet
+	}
+
+	return s.createReport(userId, moderation.TargetCard, cardId, wordSetId, input)
+}
+
+func (s *ModerationService) createReport(userId int, targetType string, targetId, wordSetId int, input moderation.ReportRequestDTO) error {
+	if !slices.Contains(moderation.Reasons, input.Reason) {
+		return moderation.ErrInvalidReason
+	}
+
+	comment := strings.TrimSpace(input.Comment)
+	if runes := []rune(comment); len(runes) > maxCommentLength {
+		comment = string(runes[:maxCommentLength])
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txModerationRepo := s.moderationRepo.WithTx(tx)
+
+		created, err := txModerationRepo.CreateReport(&models.ContentReport{
+			ReporterId: &userId,
+			TargetType: targetType,
+			TargetId:   targetId,
+			WordSetId:  wordSetId,
+			Reason:     input.Reason,
+			Comment:    comment,
+			Status:     models.ReportOpen,
+			CreatedAt:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if !created {
+			return moderation.ErrAlreadyReported
+		}
+
+		if s.hideThreshold == 0 {
+			return nil
+		}
+
+		reporters, err := txModerationRepo.CountReporters(wordSetId)
+		if err != nil {
+			return err
+		}
+		if reporters >= s.hideThreshold {
+			return txModerationRepo.HideWordSet(wordSetId)
+		}
+		return nil
+	})
+}
+
+func (s *ModerationService) GetReports(status string, page, pageSize int) (*moderation.ReportsPageDTO, error) {
+	if status == "" {
+		status = models.ReportOpen
+	}
+	if status != models.ReportOpen && status != models.ReportDismissed && status != models.ReportActioned {
+		return nil, moderation.ErrInvalidStatus
+	}
+
+	reports, total, err := s.moderationRepo.GetReports(status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &moderation.ReportsPageDTO{
+		Reports: moderation.ReportResultTo(reports),
+		Total:   total,
+		Page:    page,
+	}, nil
+}
+
+func (s *ModerationService) getOpenReport(reportId int) (*models.ContentReport, error) {
+	report, err := s.moderationRepo.GetReport(reportId)
+	if err != nil {
+		return nil, err
+	}
+	if report.Status != models.ReportOpen {
+		return nil, moderation.ErrReportNotOpen
+	}
+	return report, nil
+}
+
+// DismissReport closes all open reports of the set and brings it back to the
+// public list if it was auto-hidden.
+func (s *ModerationService) DismissReport(moderatorId, reportId int) error {
+	report, err := s.getOpenReport(reportId)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txModerationRepo := s.moderationRepo.WithTx(tx)
+
+		dismissed, err := txModerationRepo.ResolveReports(report.WordSetId, moderatorId, models.ReportDismissed)
+		if err != nil {
+			return err
+		}
+		if err := txModerationRepo.UnhideWordSet(report.WordSetId); err != nil {
+			return err
+		}
+
+		entry, err := admin.NewAuditLog(moderatorId, moderation.ActionDismissReports, admin.TargetWordSet, report.WordSetId, map[string]any{
+			"reportId":  reportId,
+			"dismissed": dismissed,
+		})
+		if err != nil {
+			return err
+		}
+		return s.adminRepo.WithTx(tx).CreateAuditLog(entry)
+	})
+}
+
+// ActionReport unpublishes the set, which also notifies the owner, and closes
+// its open reports. A set the owner already unpublished only gets its
+// reports closed.
+func (s *ModerationService) ActionReport(moderatorId, reportId int, reason string) error {
+	report, err := s.getOpenReport(reportId)
+	if err != nil {
+		return err
+	}
+
+	if reason == "" {
+		reason = report.Reason
+	}
+
+	err = s.adminService.UnpublishWordSet(moderatorId, report.WordSetId, reason)
+	if err != nil && !errors.Is(err, admin.ErrWordSetNotPublic) {
+		return err
+	}
+
+	_, err = s.moderationRepo.ResolveReports(report.WordSetId, moderatorId, models.ReportActioned)
+	return err
+}
@@ -0,0 +1,69 @@
+package notification
+
+import (
+	models "dimplom_harmonic/domain"
+	"fmt"
+	"time"
+)
+
+// PreferencesDTO gives times as "HH:MM" in Timezone.
+type PreferencesDTO struct {
+	EmailEnabled    bool    `json:"emailEnabled"`
+	PushEnabled     bool    `json:"pushEnabled"`
+	QuietHoursStart *string `json:"quietHoursStart"`
+	QuietHoursEnd   *string `json:"quietHoursEnd"`
+	DigestEnabled   bool    `json:"digestEnabled"`
+	DigestTime      string  `json:"digestTime"`
+	Timezone        string  `json:"timezone"`
+}
+
+func FormatMinute(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+func PreferencesModelTo(m *models.NotificationPreference) PreferencesDTO {
+	prefs := PreferencesDTO{
+		EmailEnabled:  m.EmailEnabled,
+		PushEnabled:   m.PushEnabled,
+		DigestEnabled: m.DigestEnabled,
+		DigestTime:    FormatMinute(m.DigestMinute),
+		Timezone:      m.Timezone,
+	}
+	if m.QuietStartMinute != nil && m.QuietEndMinute != nil {
+		start, end := FormatMinute(*m.QuietStartMinute), FormatMinute(*m.QuietEndMinute)
+		prefs.QuietHoursStart, prefs.QuietHoursEnd = &start, &end
+	}
+	return prefs
+}
+
+type NotificationDTO struct {
+	Id        int64     `json:"id"`
+	Channel   string    `json:"channel"`
+	Kind      string    `json:"kind"`
+	DeckId    *int      `json:"deckId"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	Error     *string   `json:"error"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NotificationModelTo(m *models.SentNotification) NotificationDTO {
+	return NotificationDTO{
+		Id:        m.Id,
+		Channel:   m.Channel,
+		Kind:      m.Kind,
+		DeckId:    m.DeckId,
+		Title:     m.Title,
+		Body:      m.Body,
+		Status:    m.Status,
+		Error:     m.Error,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+type NotificationPageDTO struct {
+	Notifications []NotificationDTO `json:"notifications"`
+	Total         int64             `json:"total"`
+	Page          int               `json:"page"`
+}
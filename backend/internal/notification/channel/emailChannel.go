@@ -0,0 +1,34 @@
+package channel
+
+import (
+	"context"
+	"dimplom_harmonic/internal/mailer"
+	"dimplom_harmonic/internal/notification"
+)
+
+type EmailChannel struct {
+	mailer mailer.Mailer
+	appURL string
+}
+
+func NewEmailChannel(mailer mailer.Mailer, appURL string) *EmailChannel {
+	return &EmailChannel{mailer: mailer, appURL: appURL}
+}
+
+func (c *EmailChannel) Name() string {
+	return notification.ChannelEmail
+}
+
+// Enabled leaves out unconfirmed addresses, so reminders don't go to a
+// mistyped email.
+func (c *EmailChannel) Enabled(recipient notification.Recipient) bool {
+	return recipient.Preferences.EmailEnabled && recipient.EmailVerified
+}
+
+func (c *EmailChannel) Send(_ context.Context, recipient notification.Recipient, message notification.Message) error {
+	return c.mailer.Send(mailer.Message{
+		To:      recipient.Email,
+		Subject: message.Title,
+		Body:    message.Body + "\n\n" + message.URL + "\n\nReminder settings: " + c.appURL + "/profile",
+	})
+}
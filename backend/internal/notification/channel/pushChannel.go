@@ -0,0 +1,76 @@
+package channel
+
+import (
+	"context"
+	"dimplom_harmonic/internal/notification"
+	"dimplom_harmonic/internal/webpush"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+type PushChannel struct {
+	pushRepo webpush.PushRepository
+	sender   *webpush.Sender
+}
+
+func NewPushChannel(pushRepo webpush.PushRepository, sender *webpush.Sender) *PushChannel {
+	return &PushChannel{pushRepo: pushRepo, sender: sender}
+}
+
+func (c *PushChannel) Name() string {
+	return notification.ChannelPush
+}
+
+func (c *PushChannel) Enabled(recipient notification.Recipient) bool {
+	return recipient.Preferences.PushEnabled
+}
+
+// Send pushes the message to every device of the user. Subscriptions the
+// push service has forgotten are deleted; the send fails only if no device
+// got the message.
+func (c *PushChannel) Send(ctx context.Context, recipient notification.Recipient, message notification.Message) error {
+	subscriptions, err := c.pushRepo.GetSubscriptions(recipient.UserId)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return errors.New("no push subscriptions")
+	}
+
+	payload, err := json.Marshal(webpush.Payload{
+		Title: message.Title,
+		Body:  message.Body,
+		URL:   message.URL,
+		Tag:   message.Kind,
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	delivered := 0
+	for _, subscription := range subscriptions {
+		err := c.sender.Send(ctx, subscription, payload, message.Kind)
+		switch {
+		case err == nil:
+			delivered++
+			if err := c.pushRepo.MarkUsed(subscription.Id); err != nil {
+				log.Printf("Mark push subscription %d used error: %v", subscription.Id, err)
+			}
+		case errors.Is(err, webpush.ErrSubscriptionGone):
+			if err := c.pushRepo.DeleteByEndpoint(subscription.Endpoint); err != nil {
+				log.Printf("Delete push subscription %d error: %v", subscription.Id, err)
+			}
+			errs = append(errs, fmt.Errorf("subscription %d: %w", subscription.Id, err))
+		default:
+			errs = append(errs, fmt.Errorf("subscription %d: %w", subscription.Id, err))
+		}
+	}
+
+	if delivered == 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"dimplom_harmonic/internal/middleware"
+	"dimplom_harmonic/internal/notification"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+type NotificationHandler struct {
+	service notification.NotificationService
+}
+
+func NewNotificationHandler(service notification.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+func pageParams(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	return page, min(pageSize, maxPageSize)
+}
+
+func (h *NotificationHandler) HDGetPreferences(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	prefs, err := h.service.GetPreferences(userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(prefs)
+}
+
+func (h *NotificationHandler) HDUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input notification.PreferencesDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	prefs, err := h.service.UpdatePreferences(userId, input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(prefs)
+}
+
+func (h *NotificationHandler) HDGetNotifications(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	page, pageSize := pageParams(r)
+
+	notifications, err := h.service.GetNotifications(userId, page, pageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notifications)
+}
@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ReminderMessage tells about decks that just became due.
+func ReminderMessage(appURL string, recipient Recipient, decks []DueDeckResult) Message {
+	if len(decks) == 1 {
+		return Message{
+			Kind:  KindReminder,
+			Title: "\"" + decks[0].DeckName + "\" is ready for review",
+			Body:  "Hi " + recipient.Login + ",\n\nYour deck \"" + decks[0].DeckName + "\" is due for review.",
+			URL:   appURL + "/decks/" + strconv.Itoa(decks[0].DeckId) + "/review",
+		}
+	}
+
+	return Message{
+		Kind:  KindReminder,
+		Title: strconv.Itoa(len(decks)) + " decks are ready for review",
+		Body:  "Hi " + recipient.Login + ",\n\nThese decks are due for review:\n" + deckList(decks),
+		URL:   appURL + "/decks",
+	}
+}
+
+// DigestMessage lists every due deck of the user.
+func DigestMessage(appURL string, recipient Recipient, decks []DueDeckResult) Message {
+	title := strconv.Itoa(len(decks)) + " decks to review today"
+	if len(decks) == 1 {
+		title = "1 deck to review today"
+	}
+
+	return Message{
+		Kind:  KindDigest,
+		Title: title,
+		Body:  "Hi " + recipient.Login + ",\n\nDue for review:\n" + deckList(decks),
+		URL:   appURL + "/decks",
+	}
+}
+
+func deckList(decks []DueDeckResult) string {
+	var b strings.Builder
+	for _, d := range decks {
+		b.WriteString("- " + d.DeckName + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
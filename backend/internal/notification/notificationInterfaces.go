@@ -0,0 +1,90 @@
+package notification
+
+import (
+	"context"
+	models "dimplom_harmonic/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationService interface {
+	GetPreferences(userId int) (*PreferencesDTO, error)
+	UpdatePreferences(userId int, input PreferencesDTO) (*PreferencesDTO, error)
+	GetNotifications(userId, page, pageSize int) (*NotificationPageDTO, error)
+}
+
+type NotificationRepository interface {
+	GetPreferences(userId int) (*models.NotificationPreference, error)
+	SavePreferences(prefs *models.NotificationPreference) error
+	GetRecipients(userIds []int) ([]Recipient, error)
+
+	// GetNewlyDue returns decks of users without a digest that became due in
+	// (from, to] and haven't been reminded about yet.
+	GetNewlyDue(from, to time.Time) ([]DueDeckResult, error)
+	// GetDigestUsers returns users whose digest time has passed today in
+	// their timezone, who have due decks and haven't got today's digest.
+	GetDigestUsers() ([]int, error)
+	GetDueDecks(userIds []int) ([]DueDeckResult, error)
+
+	// Claim records the notifications and returns the ones that weren't
+	// recorded before; only those should be sent.
+	Claim(notifications []models.SentNotification) ([]models.SentNotification, error)
+	MarkFailed(ids []int64, reason string) error
+	GetSentNotifications(userId, limit, offset int) ([]models.SentNotification, int64, error)
+
+	WithTx(tx *gorm.DB) NotificationRepository
+}
+
+type DueDeckResult struct {
+	UserId         int
+	DeckId         int
+	DeckName       string
+	NextReviewDate time.Time
+}
+
+// Recipient is a user with their preferences, defaults filled in.
+type Recipient struct {
+	UserId        int
+	Login         string
+	Email         string
+	EmailVerified bool
+	Preferences   models.NotificationPreference
+}
+
+type Message struct {
+	Kind  string
+	Title string
+	Body  string
+	URL   string
+}
+
+// Channel delivers messages one way, like email or web push. New channels
+// only need to be passed to the reminder worker.
+type Channel interface {
+	Name() string
+	// Enabled reports whether the recipient wants and can get messages
+	// through the channel.
+	Enabled(recipient Recipient) bool
+	Send(ctx context.Context, recipient Recipient, message Message) error
+}
+
+const (
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+
+	KindReminder = "reminder"
+	KindDigest   = "digest"
+
+	// ReminderLookback is how far back the reminder worker looks for decks
+	// that became due. Reminders held back by quiet hours or downtime go out
+	// as long as the deck became due within it.
+	ReminderLookback = 24 * time.Hour
+)
+
+var (
+	ErrInvalidTime     = errors.New("invalid_time")
+	ErrInvalidTimezone = errors.New("invalid_timezone")
+	ErrQuietHours      = errors.New("quiet_hours_need_start_and_end")
+)
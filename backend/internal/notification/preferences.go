@@ -0,0 +1,40 @@
+package notification
+
+import (
+	models "dimplom_harmonic/domain"
+	"time"
+	// Embedded so timezones work in images without tzdata.
+	_ "time/tzdata"
+)
+
+// Location returns the user's timezone, falling back to UTC.
+func Location(prefs models.NotificationPreference) *time.Location {
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// InQuietHours reports whether t falls into the user's quiet hours. The
+// start is inclusive and the end exclusive; "22:00"-"08:00" wraps midnight.
+func InQuietHours(prefs models.NotificationPreference, t time.Time) bool {
+	if prefs.QuietStartMinute == nil || prefs.QuietEndMinute == nil {
+		return false
+	}
+	start, end := *prefs.QuietStartMinute, *prefs.QuietEndMinute
+
+	local := t.In(Location(prefs))
+	minute := local.Hour()*60 + local.Minute()
+
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// StartOfDay returns local midnight of t's day in the user's timezone.
+func StartOfDay(prefs models.NotificationPreference, t time.Time) time.Time {
+	local := t.In(Location(prefs))
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/notification"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func (r *NotificationRepository) WithTx(tx *gorm.DB) notification.NotificationRepository {
+	return &NotificationRepository{db: tx}
+}
+
+func (r *NotificationRepository) GetPreferences(userId int) (*models.NotificationPreference, error) {
+	var prefs models.NotificationPreference
+	err := r.db.Where("user_id = ?", userId).First(&prefs).Error
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+func (r *NotificationRepository) SavePreferences(prefs *models.NotificationPreference) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(prefs).Error
+}
+
+func (r *NotificationRepository) GetRecipients(userIds []int) ([]notification.Recipient, error) {
+	if len(userIds) == 0 {
+		return nil, nil
+	}
+
+	var users []struct {
+		Id              int
+		Login           string
+		Email           string
+		EmailVerifiedAt *time.Time
+	}
+	err := r.db.Table("users").Select("id, login, email, email_verified_at").Where("id IN ?", userIds).Scan(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var prefs []models.NotificationPreference
+	err = r.db.Where("user_id IN ?", userIds).Find(&prefs).Error
+	if err != nil {
+		return nil, err
+	}
+	prefsByUser := make(map[int]models.NotificationPreference, len(prefs))
+	for _, p := range prefs {
+		prefsByUser[p.UserId] = p
+	}
+
+	recipients := make([]notification.Recipient, 0, len(users))
+	for _, u := range users {
+		p, ok := prefsByUser[u.Id]
+		if !ok {
+			p = models.DefaultNotificationPreference(u.Id)
+		}
+		recipients = append(recipients, notification.Recipient{
+			UserId:        u.Id,
+			Login:         u.Login,
+			Email:         u.Email,
+			EmailVerified: u.EmailVerifiedAt != nil,
+			Preferences:   p,
+		})
+	}
+	return recipients, nil
+}
+
+func (r *NotificationRepository) GetNewlyDue(from, to time.Time) ([]notification.DueDeckResult, error) {
+	var decks []notification.DueDeckResult
+
+	query := `
+		SELECT 
+			d.user_id,
+			d.id as deck_id,
+			d.name as deck_name,
+			d.next_review_date
+		FROM 
+			decks d
+			LEFT JOIN notification_preferences p ON p.user_id = d.user_id
+		WHERE 
+			d.deleted_at IS NULL 
+			AND NOT d.is_archived
+			AND d.next_review_date > ? AND d.next_review_date <= ?
+			AND (p.user_id IS NULL OR ((p.email_enabled OR p.push_enabled) AND NOT p.digest_enabled))
+			AND NOT EXISTS (
+				SELECT 1 FROM sent_notifications n
+				WHERE n.user_id = d.user_id AND n.kind = 'reminder' AND n.deck_id = d.id AND n.due_at = d.next_review_date
+			)
+		ORDER BY 
+			d.user_id, d.next_review_date, d.id
+	`
+	err := r.db.Raw(query, from, to).Scan(&decks).Error
+	if err != nil {
+		return nil, err
+	}
+	return decks, nil
+}
+
+func (r *NotificationRepository) GetDigestUsers() ([]int, error) {
+	var userIds []int
+
+	query := `
+		SELECT p.user_id
+		FROM notification_preferences p
+		WHERE 
+			p.digest_enabled
+			AND (p.email_enabled OR p.push_enabled)
+			AND (NOW() AT TIME ZONE p.timezone)::time >= make_time(p.digest_minute / 60, p.digest_minute % 60, 0)
+			AND EXISTS (
+				SELECT 1 FROM decks d
+				WHERE d.user_id = p.user_id AND d.deleted_at IS NULL AND NOT d.is_archived AND d.next_review_date <= NOW()
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM sent_notifications n
+				WHERE n.user_id = p.user_id AND n.kind = 'digest' AND n.due_at = date_trunc('day', NOW(), p.timezone)
+			)
+	`
+	err := r.db.Raw(query).Scan(&userIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return userIds, nil
+}
+
+func (r *NotificationRepository) GetDueDecks(userIds []int) ([]notification.DueDeckResult, error) {
+	var decks []notification.DueDeckResult
+
+	query := `
+		SELECT 
+			d.user_id,
+			d.id as deck_id,
+			d.name as deck_name,
+			d.next_review_date
+		FROM decks d
+		WHERE 
+			d.user_id IN ?
+			AND d.deleted_at IS NULL 
+			AND NOT d.is_archived
+			AND d.next_review_date <= NOW()
+		ORDER BY 
+			d.user_id, d.next_review_date, d.id
+	`
+	err := r.db.Raw(query, userIds).Scan(&decks).Error
+	if err != nil {
+		return nil, err
+	}
+	return decks, nil
+}
+
+// Claim inserts row by row: with ON CONFLICT DO NOTHING a batch insert
+// returns ids only for new rows, and they can't be matched to the input.
+func (r *NotificationRepository) Claim(notifications []models.SentNotification) ([]models.SentNotification, error) {
+	claimed := make([]models.SentNotification, 0, len(notifications))
+
+	for _, n := range notifications {
+		result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Omit("Error").Create(&n)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, n)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *NotificationRepository) MarkFailed(ids []int64, reason string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.SentNotification{}).Where("id IN ?", ids).Updates(map[string]any{
+		"Status": models.NotificationFailed,
+		"Error":  reason,
+	}).Error
+}
+
+func (r *NotificationRepository) GetSentNotifications(userId, limit, offset int) ([]models.SentNotification, int64, error) {
+	var notifications []models.SentNotification
+	var total int64
+
+	err := r.db.Model(&models.SentNotification{}).Where("user_id = ?", userId).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = r.db.
+		Where("user_id = ?", userId).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
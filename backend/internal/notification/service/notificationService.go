@@ -0,0 +1,114 @@
+package service
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/notification"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationService struct {
+	notificationRepo notification.NotificationRepository
+}
+
+func NewNotificationService(notificationRepo notification.NotificationRepository) *NotificationService {
+	return &NotificationService{notificationRepo: notificationRepo}
+}
+
+// parseMinute turns "HH:MM" into minutes since midnight.
+func parseMinute(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, notification.ErrInvalidTime
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (s *NotificationService) preferences(userId int) (*models.NotificationPreference, error) {
+	prefs, err := s.notificationRepo.GetPreferences(userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		defaults := models.DefaultNotificationPreference(userId)
+		return &defaults, nil
+	}
+	return prefs, err
+}
+
+func (s *NotificationService) GetPreferences(userId int) (*notification.PreferencesDTO, error) {
+	prefs, err := s.preferences(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := notification.PreferencesModelTo(prefs)
+	return &result, nil
+}
+
+// UpdatePreferences replaces all preferences. An empty digest time or
+// timezone keeps the current one.
+func (s *NotificationService) UpdatePreferences(userId int, input notification.PreferencesDTO) (*notification.PreferencesDTO, error) {
+	prefs, err := s.preferences(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs.EmailEnabled = input.EmailEnabled
+	prefs.PushEnabled = input.PushEnabled
+	prefs.DigestEnabled = input.DigestEnabled
+
+	if (input.QuietHoursStart == nil) != (input.QuietHoursEnd == nil) {
+		return nil, notification.ErrQuietHours
+	}
+	prefs.QuietStartMinute, prefs.QuietEndMinute = nil, nil
+	if input.QuietHoursStart != nil {
+		start, err := parseMinute(*input.QuietHoursStart)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseMinute(*input.QuietHoursEnd)
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, notification.ErrQuietHours
+		}
+		prefs.QuietStartMinute, prefs.QuietEndMinute = &start, &end
+	}
+
+	if input.DigestTime != "" {
+		prefs.DigestMinute, err = parseMinute(input.DigestTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if input.Timezone != "" {
+		if _, err := time.LoadLocation(input.Timezone); err != nil || input.Timezone == "Local" {
+			return nil, notification.ErrInvalidTimezone
+		}
+		prefs.Timezone = input.Timezone
+	}
+
+	prefs.UpdatedAt = time.Now()
+	if err := s.notificationRepo.SavePreferences(prefs); err != nil {
+		return nil, err
+	}
+
+	result := notification.PreferencesModelTo(prefs)
+	return &result, nil
+}
+
+func (s *NotificationService) GetNotifications(userId, page, pageSize int) (*notification.NotificationPageDTO, error) {
+	notifications, total, err := s.notificationRepo.GetSentNotifications(userId, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]notification.NotificationDTO, 0, len(notifications))
+	for i := range notifications {
+		result = append(result, notification.NotificationModelTo(&notifications[i]))
+	}
+	return &notification.NotificationPageDTO{Notifications: result, Total: total, Page: page}, nil
+}
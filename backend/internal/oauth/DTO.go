@@ -0,0 +1,9 @@
+package oauth
+
+type AuthURLResponseDTO struct {
+	URL string `json:"url"`
+}
+
+type ProvidersResponseDTO struct {
+	Providers []string `json:"providers"`
+}
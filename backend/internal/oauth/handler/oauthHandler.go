@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"dimplom_harmonic/internal/auth"
+	userHandler "dimplom_harmonic/internal/auth/handler"
+	"dimplom_harmonic/internal/middleware"
+	"dimplom_harmonic/internal/oauth"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// stateCookie binds the OAuth state to the browser that started the flow, so a
+// callback URL crafted from someone else's flow is rejected. It holds a hash of
+// the state and lives as long as the state itself.
+const (
+	stateCookie    = "oauth_state"
+	stateCookieTTL = 10 * time.Minute
+)
+
+type OAuthHandler struct {
+	service oauth.OAuthService
+	users   *userHandler.UserHandler
+	appURL  string
+}
+
+func NewOAuthHandler(service oauth.OAuthService, users *userHandler.UserHandler, appURL string) *OAuthHandler {
+	return &OAuthHandler{service: service, users: users, appURL: appURL}
+}
+
+func (h *OAuthHandler) HDGetProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(oauth.ProvidersResponseDTO{Providers: h.service.Providers()})
+}
+
+// HDLogin sends the browser to the provider's consent page.
+func (h *OAuthHandler) HDLogin(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+
+	authURL, state, err := h.service.AuthURL(provider, 0)
+	if errors.Is(err, oauth.ErrUnknownProvider) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	setStateCookie(w, state)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// HDLink returns the consent page URL instead of redirecting, because the
+// access token comes in a header the browser won't send on navigation. The
+// app must call it with credentials so the state cookie is stored.
+func (h *OAuthHandler) HDLink(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	provider := chi.URLParam(r, "provider")
+
+	authURL, state, err := h.service.AuthURL(provider, userId)
+	if errors.Is(err, oauth.ErrUnknownProvider) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	setStateCookie(w, state)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(oauth.AuthURLResponseDTO{URL: authURL})
+}
+
+// HDCallback finishes the flow and redirects back to the app. After a login
+// only the refresh cookie is set; the app gets its access token through
+// /auth/refresh as after a page reload. With 2FA on, the app gets the
+// challenge token to finish the login at /login/2fa.
+func (h *OAuthHandler) HDCallback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	query := r.URL.Query()
+
+	stateMatches := checkStateCookie(r, query.Get("state"))
+	clearStateCookie(w)
+
+	if providerErr := query.Get("error"); providerErr != "" {
+		h.redirectError(w, r, providerErr)
+		return
+	}
+
+	if !stateMatches {
+		log.Printf("OAuth callback %s: state cookie missing or mismatched", provider)
+		h.redirectError(w, r, oauth.ErrInvalidState.Error())
+		return
+	}
+
+	result, err := h.service.Callback(provider, query.Get("code"), query.Get("state"), userHandler.SessionMeta(r))
+	if err != nil {
+		log.Printf("OAuth callback %s: %v", provider, err)
+
+		switch {
+		case errors.Is(err, oauth.ErrUnknownProvider), errors.Is(err, oauth.ErrInvalidState),
+			errors.Is(err, auth.ErrProviderEmailNotVerified), errors.Is(err, auth.ErrIdentityLinked):
+			h.redirectError(w, r, err.Error())
+		default:
+			h.redirectError(w, r, "oauth_failed")
+		}
+		return
+	}
+
+	if result.Linked {
+		http.Redirect(w, r, h.appURL+"/profile?linked="+url.QueryEscape(provider), http.StatusFound)
+		return
+	}
+
+	if result.Login.TwoFactorToken != "" {
+		http.Redirect(w, r, h.appURL+"/login?two_factor_token="+url.QueryEscape(result.Login.TwoFactorToken), http.StatusFound)
+		return
+	}
+
+	h.users.SetRefreshCookie(w, result.Login.RefreshToken)
+	http.Redirect(w, r, h.appURL+"/decks", http.StatusFound)
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+func setStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    hashState(state),
+		HttpOnly: true,
+		Path:     "/api/auth/oauth",
+		MaxAge:   int(stateCookieTTL.Seconds()),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    "",
+		Path:     "/api/auth/oauth",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+func checkStateCookie(r *http.Request, state string) bool {
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil || state == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(hashState(state))) == 1
+}
+
+func (h *OAuthHandler) redirectError(w http.ResponseWriter, r *http.Request, code string) {
+	http.Redirect(w, r, h.appURL+"/login?oauth_error="+url.QueryEscape(code), http.StatusFound)
+}
@@ -0,0 +1,40 @@
+package oauth
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/auth"
+	"errors"
+)
+
+type OAuthService interface {
+	Providers() []string
+	// AuthURL returns the consent page URL and the state it carries, which
+	// the caller binds to the browser starting the flow.
+	AuthURL(provider string, linkUserId int) (authURL string, state string, err error)
+	Callback(provider, code, state string, meta auth.SessionMeta) (*CallbackResult, error)
+}
+
+type OAuthRepository interface {
+	SaveState(state *models.OAuthState) error
+	TakeState(state string) (*models.OAuthState, error)
+}
+
+// Provider is an external login provider using the authorization code flow
+// with PKCE. Exchange returns the user the code was issued for.
+type Provider interface {
+	Name() string
+	AuthCodeURL(state, nonce, codeChallenge string) (string, error)
+	Exchange(code, codeVerifier, nonce string) (*auth.ExternalIdentity, error)
+}
+
+// CallbackResult holds the login result, or Linked when the flow was started
+// from the profile to link a provider.
+type CallbackResult struct {
+	Login  *auth.LoginResult
+	Linked bool
+}
+
+var (
+	ErrUnknownProvider = errors.New("unknown_provider")
+	ErrInvalidState    = errors.New("invalid_oauth_state")
+)
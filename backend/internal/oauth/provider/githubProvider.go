@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"dimplom_harmonic/internal/auth"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// GitHubProvider logs in with GitHub. GitHub is plain OAuth2 without ID
+// tokens, so the user and their verified emails are read from the API.
+type GitHubProvider struct {
+	clientId     string
+	clientSecret string
+	redirectURL  string
+	client       *http.Client
+}
+
+const (
+	githubAuthorizeURL = "https://github.com/login/oauth/authorize"
+	githubTokenURL     = "https://github.com/login/oauth/access_token"
+	githubAPIURL       = "https://api.github.com"
+)
+
+func NewGitHubProvider(clientId, clientSecret, redirectURL string) *GitHubProvider {
+	return &GitHubProvider{
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		client:       &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *GitHubProvider) Name() string {
+	return "github"
+}
+
+func (p *GitHubProvider) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	query := url.Values{}
+	query.Set("client_id", p.clientId)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", "read:user user:email")
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	return githubAuthorizeURL + "?" + query.Encode(), nil
+}
+
+func (p *GitHubProvider) Exchange(code, codeVerifier, nonce string) (*auth.ExternalIdentity, error) {
+	form := url.Values{}
+	form.Set("client_id", p.clientId)
+	form.Set("client_secret", p.clientSecret)
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	var tokens struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	err := postForm(p.client, githubTokenURL, form, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("github: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return nil, fmt.Errorf("github: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+
+	var user struct {
+		Id    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	err = getJSON(p.client, githubAPIURL+"/user", tokens.AccessToken, &user)
+	if err != nil {
+		return nil, fmt.Errorf("github: %w", err)
+	}
+	if user.Id == 0 {
+		return nil, errors.New("github: empty user")
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	err = getJSON(p.client, githubAPIURL+"/user/emails", tokens.AccessToken, &emails)
+	if err != nil {
+		return nil, fmt.Errorf("github: %w", err)
+	}
+
+	identity := &auth.ExternalIdentity{
+		Provider: p.Name(),
+		Subject:  strconv.FormatInt(user.Id, 10),
+		Name:     user.Name,
+	}
+	for _, value := range emails {
+		if value.Primary {
+			identity.Email = value.Email
+			identity.EmailVerified = value.Verified
+		}
+	}
+
+	return identity, nil
+}
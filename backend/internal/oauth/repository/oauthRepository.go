@@ -0,0 +1,41 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+
+	"gorm.io/gorm"
+)
+
+type OAuthRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthRepository(db *gorm.DB) *OAuthRepository {
+	return &OAuthRepository{db: db}
+}
+
+func (r *OAuthRepository) SaveState(state *models.OAuthState) error {
+	return r.db.Create(state).Error
+}
+
+// TakeState deletes the state and returns it, so a callback can't be
+// replayed. Expired states are treated as missing.
+func (r *OAuthRepository) TakeState(state string) (*models.OAuthState, error) {
+	var states []models.OAuthState
+
+	query := `
+		DELETE FROM 
+			oauth_states
+		WHERE 
+			state = ? AND expires_at > NOW()
+		RETURNING *
+	`
+	err := r.db.Raw(query, state).Scan(&states).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &states[0], nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/auth"
+	"dimplom_harmonic/internal/oauth"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OAuthService struct {
+	oauthRepo   oauth.OAuthRepository
+	userService auth.UserService
+	providers   map[string]oauth.Provider
+}
+
+func NewOAuthService(oauthRepo oauth.OAuthRepository, userService auth.UserService, providers []oauth.Provider) *OAuthService {
+	byName := make(map[string]oauth.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &OAuthService{oauthRepo: oauthRepo, userService: userService, providers: byName}
+}
+
+const oauthStateTTL = 10 * time.Minute
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *OAuthService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// AuthURL starts the flow and returns where to send the browser, along with
+// the state the callback must come back with. With
+// linkUserId set the callback links the provider to that user instead of
+// logging in.
+func (s *OAuthService) AuthURL(providerName string, linkUserId int) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", oauth.ErrUnknownProvider
+	}
+
+	state, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	oauthState := models.OAuthState{
+		State:        state,
+		Provider:     providerName,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}
+	if linkUserId != 0 {
+		oauthState.UserId = &linkUserId
+	}
+
+	if err := s.oauthRepo.SaveState(&oauthState); err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authURL, err := provider.AuthCodeURL(state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+func (s *OAuthService) Callback(providerName, code, state string, meta auth.SessionMeta) (*oauth.CallbackResult, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, oauth.ErrUnknownProvider
+	}
+
+	oauthState, err := s.oauthRepo.TakeState(state)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, oauth.ErrInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+	if oauthState.Provider != providerName {
+		return nil, oauth.ErrInvalidState
+	}
+
+	identity, err := provider.Exchange(code, oauthState.CodeVerifier, oauthState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if oauthState.UserId != nil {
+		err = s.userService.LinkIdentity(*oauthState.UserId, *identity)
+		if err != nil {
+			return nil, err
+		}
+		return &oauth.CallbackResult{Linked: true}, nil
+	}
+
+	login, err := s.userService.OAuthLogin(*identity, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth.CallbackResult{Login: login}, nil
+}
@@ -0,0 +1,167 @@
+package offlinesync
+
+import (
+	models "dimplom_harmonic/domain"
+	"time"
+)
+
+type SyncRequestDTO struct {
+	Cursor  string             `json:"cursor"`
+	Reviews []OfflineReviewDTO `json:"reviews"`
+}
+
+type OfflineReviewDTO struct {
+	ClientId   string                 `json:"clientId"`
+	DeckId     int                    `json:"deckId"`
+	ReviewedAt time.Time              `json:"reviewedAt"`
+	Results    []OfflineCardResultDTO `json:"results"`
+}
+
+type OfflineCardResultDTO struct {
+	CardId    int  `json:"cardId"`
+	IsCorrect bool `json:"isCorrect"`
+}
+
+type ReviewResultDTO struct {
+	ClientId       string     `json:"clientId"`
+	DeckId         int        `json:"deckId"`
+	Status         string     `json:"status"`
+	Error          string     `json:"error,omitempty"`
+	Accuracy       int        `json:"accuracy,omitempty"`
+	Level          int        `json:"level,omitempty"`
+	NextReviewDate *time.Time `json:"nextReviewDate,omitempty"`
+	Version        int        `json:"version,omitempty"`
+}
+
+// SyncResponseDTO holds everything changed since the cursor. With Reset the
+// client should drop its copy and keep only what is sent.
+type SyncResponseDTO struct {
+	Cursor    string            `json:"cursor"`
+	Reset     bool              `json:"reset"`
+	Reviews   []ReviewResultDTO `json:"reviews"`
+	Decks     []DeckDTO         `json:"decks"`
+	WordSets  []WordSetDTO      `json:"wordSets"`
+	Cards     []CardDTO         `json:"cards"`
+	Schedules []ScheduleDTO     `json:"schedules"`
+	Deleted   DeletedDTO        `json:"deleted"`
+}
+
+type DeckDTO struct {
+	Id                   int       `json:"id"`
+	Name                 string    `json:"name"`
+	ScheduleId           int       `json:"scheduleId"`
+	CurrentLevel         int       `json:"currentLevel"`
+	IsArchived           bool      `json:"isArchived"`
+	NextReviewDate       time.Time `json:"nextReviewDate"`
+	NextPrimaryDirection bool      `json:"nextPrimaryDirection"`
+	Version              int       `json:"version"`
+	CardIds              []int     `json:"cardIds"`
+	Deleted              bool      `json:"deleted"`
+}
+
+type WordSetDTO struct {
+	Id        int    `json:"id"`
+	UserId    int    `json:"userId"`
+	Name      string `json:"name"`
+	IsPublic  bool   `json:"isPublic"`
+	IsDefault bool   `json:"isDefault"`
+	Role      string `json:"role"`
+	Version   int    `json:"version"`
+	CardIds   []int  `json:"cardIds"`
+	Deleted   bool   `json:"deleted"`
+}
+
+type CardDTO struct {
+	Id                 int    `json:"id"`
+	OriginalWord       string `json:"originalWord"`
+	Translation        string `json:"translation"`
+	OriginalContext    string `json:"originalContext"`
+	TranslationContext string `json:"translationContext"`
+	Version            int    `json:"version"`
+	Deleted            bool   `json:"deleted"`
+}
+
+type ScheduleDTO struct {
+	Id        int                `json:"id"`
+	Name      string             `json:"name"`
+	IsDefault bool               `json:"isDefault"`
+	Version   int                `json:"version"`
+	Levels    []ScheduleLevelDTO `json:"levels"`
+}
+
+type ScheduleLevelDTO struct {
+	Level           int `json:"level"`
+	IntervalMinutes int `json:"intervalMinutes"`
+}
+
+type DeletedDTO struct {
+	Decks     []int `json:"decks"`
+	WordSets  []int `json:"wordSets"`
+	Schedules []int `json:"schedules"`
+}
+
+func DeckModelTo(m *models.Deck, cardIds []int) DeckDTO {
+	return DeckDTO{
+		Id:                   m.Id,
+		Name:                 m.Name,
+		ScheduleId:           m.ScheduleId,
+		CurrentLevel:         m.CurrentLevel,
+		IsArchived:           m.IsArchived,
+		NextReviewDate:       m.NextReviewDate,
+		NextPrimaryDirection: m.NextPrimaryDirection,
+		Version:              m.Version,
+		CardIds:              nonNilInts(cardIds),
+		Deleted:              m.DeletedAt.Valid,
+	}
+}
+
+func WordSetResultTo(m *WordSetResult, cardIds []int) WordSetDTO {
+	return WordSetDTO{
+		Id:        m.Id,
+		UserId:    m.UserId,
+		Name:      m.Name,
+		IsPublic:  m.IsPublic,
+		IsDefault: m.IsDefault,
+		Role:      m.Role,
+		Version:   m.Version,
+		CardIds:   nonNilInts(cardIds),
+		Deleted:   m.DeletedAt.Valid,
+	}
+}
+
+func CardModelTo(m *models.Card) CardDTO {
+	return CardDTO{
+		Id:                 m.Id,
+		OriginalWord:       m.OriginalWord,
+		Translation:        m.Translation,
+		OriginalContext:    m.OriginalContext,
+		TranslationContext: m.TranslationContext,
+		Version:            m.Version,
+		Deleted:            m.DeletedAt.Valid,
+	}
+}
+
+func ScheduleModelTo(m *models.DeckSchedule) ScheduleDTO {
+	levels := make([]ScheduleLevelDTO, 0, len(m.ScheduleSteps))
+	for _, step := range m.ScheduleSteps {
+		levels = append(levels, ScheduleLevelDTO{
+			Level:           step.Level,
+			IntervalMinutes: step.IntervalMinutes,
+		})
+	}
+
+	return ScheduleDTO{
+		Id:        m.Id,
+		Name:      m.Name,
+		IsDefault: m.IsDefault,
+		Version:   m.Version,
+		Levels:    levels,
+	}
+}
+
+func nonNilInts(values []int) []int {
+	if values == nil {
+		return []int{}
+	}
+	return values
+}
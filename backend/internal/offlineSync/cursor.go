@@ -0,0 +1,42 @@
+package offlinesync
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor is the oldest transaction that was still running when the client
+// last synced, and when that was.
+type Cursor struct {
+	Xmin     int64
+	IssuedAt time.Time
+}
+
+func EncodeCursor(c Cursor) string {
+	raw := strconv.FormatInt(c.Xmin, 10) + "." + strconv.FormatInt(c.IssuedAt.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(value string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	xminStr, issuedStr, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	xmin, err := strconv.ParseInt(xminStr, 10, 64)
+	if err != nil || xmin < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	issued, err := strconv.ParseInt(issuedStr, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{Xmin: xmin, IssuedAt: time.Unix(issued, 0)}, nil
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"dimplom_harmonic/internal/middleware"
+	offlinesync "dimplom_harmonic/internal/offlineSync"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type SyncHandler struct {
+	service offlinesync.SyncService
+}
+
+func NewSyncHandler(service offlinesync.SyncService) *SyncHandler {
+	return &SyncHandler{service: service}
+}
+
+func (h *SyncHandler) HDSync(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input offlinesync.SyncRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format JSON", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.service.Sync(userId, input)
+	if errors.Is(err, offlinesync.ErrInvalidCursor) || errors.Is(err, offlinesync.ErrTooManyReviews) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
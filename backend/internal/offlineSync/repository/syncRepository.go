@@ -0,0 +1,180 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	offlinesync "dimplom_harmonic/internal/offlineSync"
+
+	"gorm.io/gorm"
+)
+
+type SyncRepository struct {
+	db *gorm.DB
+}
+
+func NewSyncRepository(db *gorm.DB) *SyncRepository {
+	return &SyncRepository{db: db}
+}
+
+func (r *SyncRepository) WithTx(tx *gorm.DB) offlinesync.SyncRepository {
+	return &SyncRepository{
+		db: tx,
+	}
+}
+
+// CurrentXmin returns the oldest transaction still running for the current
+// snapshot. Changes made by older transactions are all visible.
+func (r *SyncRepository) CurrentXmin() (int64, error) {
+	var xmin int64
+	err := r.db.Raw(`SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint`).Scan(&xmin).Error
+	if err != nil {
+		return 0, err
+	}
+	return xmin, nil
+}
+
+func (r *SyncRepository) GetDecks(userId int, since int64) ([]models.Deck, error) {
+	var decks []models.Deck
+	err := r.db.Unscoped().
+		Where("user_id = ? AND sync_xid >= ?", userId, since).
+		Order("id").
+		Find(&decks).Error
+	if err != nil {
+		return nil, err
+	}
+	return decks, nil
+}
+
+// GetWordSets returns the user's own sets and the shared ones they accepted.
+// A set counts as changed for a member when their membership changed too.
+func (r *SyncRepository) GetWordSets(userId int, since int64) ([]offlinesync.WordSetResult, error) {
+	var wordSets []offlinesync.WordSetResult
+
+	query := `
+		SELECT
+			ws.id,
+			COALESCE(ws.user_id, 0) AS user_id,
+			ws.name,
+			ws.is_public,
+			ws.is_default,
+			ws.deleted_at,
+			ws.version,
+			COALESCE(m.role, 'owner') AS role
+		FROM word_sets ws
+		LEFT JOIN word_set_members m ON m.word_set_id = ws.id AND m.user_id = ? AND m.accepted_at IS NOT NULL
+		WHERE
+			(ws.user_id = ? OR m.id IS NOT NULL)
+			AND (ws.sync_xid >= ? OR m.sync_xid >= ?)
+		ORDER BY ws.id
+	`
+	err := r.db.Raw(query, userId, userId, since, since).Scan(&wordSets).Error
+	if err != nil {
+		return nil, err
+	}
+	return wordSets, nil
+}
+
+// GetCards returns cards of the user's decks and visible sets that changed
+// themselves or were just linked to one of them.
+func (r *SyncRepository) GetCards(userId int, since int64) ([]models.Card, error) {
+	var cards []models.Card
+
+	query := `
+		SELECT
+			c.id,
+			c.original_word,
+			c.translation,
+			c.original_context,
+			c.translation_context,
+			c.version,
+			c.deleted_at
+		FROM cards c
+		WHERE
+			EXISTS (
+				SELECT 1
+				FROM deck_cards dc
+				JOIN decks d ON d.id = dc.deck_id
+				WHERE
+					dc.card_id = c.id
+					AND d.user_id = ?
+					AND (c.sync_xid >= ? OR dc.sync_xid >= ?)
+			)
+			OR EXISTS (
+				SELECT 1
+				FROM set_to_card_link l
+				JOIN word_sets ws ON ws.id = l.word_set_id
+				LEFT JOIN word_set_members m ON m.word_set_id = ws.id AND m.user_id = ? AND m.accepted_at IS NOT NULL
+				WHERE
+					l.card_id = c.id
+					AND (ws.user_id = ? OR m.id IS NOT NULL)
+					AND (c.sync_xid >= ? OR l.sync_xid >= ? OR m.sync_xid >= ?)
+			)
+		ORDER BY c.id
+	`
+	err := r.db.Raw(query, userId, since, since, userId, userId, since, since, since).Scan(&cards).Error
+	if err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+func (r *SyncRepository) GetSchedules(userId int, since int64) ([]models.DeckSchedule, error) {
+	var schedules []models.DeckSchedule
+	err := r.db.Preload("ScheduleSteps").
+		Where("user_id = ? AND sync_xid >= ?", userId, since).
+		Order("id").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *SyncRepository) GetDeckCardIds(deckIds []int) ([]offlinesync.CardLink, error) {
+	var links []offlinesync.CardLink
+	if len(deckIds) == 0 {
+		return links, nil
+	}
+
+	err := r.db.Raw(`
+		SELECT deck_id AS parent_id, card_id
+		FROM deck_cards
+		WHERE deck_id IN ?
+		ORDER BY deck_id, card_id
+	`, deckIds).Scan(&links).Error
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *SyncRepository) GetWordSetCardIds(wordSetIds []int) ([]offlinesync.CardLink, error) {
+	var links []offlinesync.CardLink
+	if len(wordSetIds) == 0 {
+		return links, nil
+	}
+
+	err := r.db.Raw(`
+		SELECT word_set_id AS parent_id, card_id
+		FROM set_to_card_link
+		WHERE word_set_id IN ?
+		ORDER BY word_set_id, card_id
+	`, wordSetIds).Scan(&links).Error
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *SyncRepository) GetDeletions(userId int, since int64) ([]offlinesync.Deletion, error) {
+	var deletions []offlinesync.Deletion
+	err := r.db.Raw(`
+		SELECT entity, entity_id
+		FROM sync_deletions
+		WHERE user_id = ? AND sync_xid >= ?
+		ORDER BY id
+	`, userId, since).Scan(&deletions).Error
+	if err != nil {
+		return nil, err
+	}
+	return deletions, nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"database/sql"
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/concurrency"
+	"dimplom_harmonic/internal/deck"
+	offlinesync "dimplom_harmonic/internal/offlineSync"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SyncService struct {
+	repo        offlinesync.SyncRepository
+	deckService deck.DeckService
+	db          *gorm.DB
+}
+
+func NewSyncService(repo offlinesync.SyncRepository, deckService deck.DeckService, db *gorm.DB) offlinesync.SyncService {
+	return &SyncService{repo: repo, deckService: deckService, db: db}
+}
+
+// Sync applies the client's offline reviews first and then sends back
+// everything changed since its cursor, including the reviews' own effects.
+// Without a cursor, or with one older than the kept deletions, the client
+// gets a full snapshot.
+func (s *SyncService) Sync(userId int, input offlinesync.SyncRequestDTO) (*offlinesync.SyncResponseDTO, error) {
+	if len(input.Reviews) > offlinesync.MaxReviews {
+		return nil, offlinesync.ErrTooManyReviews
+	}
+
+	var since int64
+	reset := true
+	if input.Cursor != "" {
+		cursor, err := offlinesync.DecodeCursor(input.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(cursor.IssuedAt) < offlinesync.DeletionsRetention {
+			since = cursor.Xmin
+			reset = false
+		}
+	}
+
+	response := offlinesync.SyncResponseDTO{
+		Reset:   reset,
+		Reviews: s.applyReviews(userId, input.Reviews),
+	}
+
+	// One snapshot for every read, so the cursor matches what was sent.
+	txOptions := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := s.repo.WithTx(tx)
+
+		xmin, err := txRepo.CurrentXmin()
+		if err != nil {
+			return err
+		}
+		response.Cursor = offlinesync.EncodeCursor(offlinesync.Cursor{Xmin: xmin, IssuedAt: time.Now()})
+
+		return s.collectChanges(txRepo, userId, since, reset, &response)
+	}, txOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (s *SyncService) collectChanges(repo offlinesync.SyncRepository, userId int, since int64, reset bool, response *offlinesync.SyncResponseDTO) error {
+	decks, err := repo.GetDecks(userId, since)
+	if err != nil {
+		return err
+	}
+	wordSets, err := repo.GetWordSets(userId, since)
+	if err != nil {
+		return err
+	}
+	cards, err := repo.GetCards(userId, since)
+	if err != nil {
+		return err
+	}
+	schedules, err := repo.GetSchedules(userId, since)
+	if err != nil {
+		return err
+	}
+
+	deckIds := make([]int, 0, len(decks))
+	for _, d := range decks {
+		deckIds = append(deckIds, d.Id)
+	}
+	deckLinks, err := repo.GetDeckCardIds(deckIds)
+	if err != nil {
+		return err
+	}
+	deckCards := groupLinks(deckLinks)
+
+	wordSetIds := make([]int, 0, len(wordSets))
+	for _, ws := range wordSets {
+		wordSetIds = append(wordSetIds, ws.Id)
+	}
+	wordSetLinks, err := repo.GetWordSetCardIds(wordSetIds)
+	if err != nil {
+		return err
+	}
+	wordSetCards := groupLinks(wordSetLinks)
+
+	response.Decks = make([]offlinesync.DeckDTO, 0, len(decks))
+	for _, d := range decks {
+		if reset && d.DeletedAt.Valid {
+			continue
+		}
+		response.Decks = append(response.Decks, offlinesync.DeckModelTo(&d, deckCards[d.Id]))
+	}
+
+	response.WordSets = make([]offlinesync.WordSetDTO, 0, len(wordSets))
+	sentWordSets := make(map[int]bool, len(wordSets))
+	for _, ws := range wordSets {
+		sentWordSets[ws.Id] = true
+		if reset && ws.DeletedAt.Valid {
+			continue
+		}
+		response.WordSets = append(response.WordSets, offlinesync.WordSetResultTo(&ws, wordSetCards[ws.Id]))
+	}
+
+	response.Cards = make([]offlinesync.CardDTO, 0, len(cards))
+	for _, c := range cards {
+		if reset && c.DeletedAt.Valid {
+			continue
+		}
+		response.Cards = append(response.Cards, offlinesync.CardModelTo(&c))
+	}
+
+	response.Schedules = make([]offlinesync.ScheduleDTO, 0, len(schedules))
+	for _, sch := range schedules {
+		response.Schedules = append(response.Schedules, offlinesync.ScheduleModelTo(&sch))
+	}
+
+	response.Deleted = offlinesync.DeletedDTO{Decks: []int{}, WordSets: []int{}, Schedules: []int{}}
+	if reset {
+		return nil
+	}
+
+	deletions, err := repo.GetDeletions(userId, since)
+	if err != nil {
+		return err
+	}
+	for _, deletion := range deletions {
+		switch deletion.Entity {
+		case offlinesync.EntityDeck:
+			response.Deleted.Decks = append(response.Deleted.Decks, deletion.EntityId)
+		case offlinesync.EntityWordSet:
+			// A member who left and came back still sees the set.
+			if !sentWordSets[deletion.EntityId] {
+				response.Deleted.WordSets = append(response.Deleted.WordSets, deletion.EntityId)
+			}
+		case offlinesync.EntitySchedule:
+			response.Deleted.Schedules = append(response.Deleted.Schedules, deletion.EntityId)
+		}
+	}
+	return nil
+}
+
+// applyReviews runs the reviews through DeckService.Review oldest first, so
+// the outcome doesn't depend on the order the client queued them in. Each
+// review applies to the deck as it is by then; a review that can't be
+// applied is rejected without stopping the rest.
+func (s *SyncService) applyReviews(userId int, reviews []offlinesync.OfflineReviewDTO) []offlinesync.ReviewResultDTO {
+	results := make([]offlinesync.ReviewResultDTO, len(reviews))
+
+	order := make([]int, len(reviews))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return reviews[order[i]].ReviewedAt.Before(reviews[order[j]].ReviewedAt)
+	})
+
+	now := time.Now()
+	for _, i := range order {
+		review := reviews[i]
+		results[i] = offlinesync.ReviewResultDTO{
+			ClientId: review.ClientId,
+			DeckId:   review.DeckId,
+			Status:   offlinesync.ReviewRejected,
+		}
+
+		if review.ReviewedAt.IsZero() {
+			results[i].Error = "reviewedAt is required"
+			continue
+		}
+		if len(review.Results) == 0 {
+			results[i].Error = "results are empty"
+			continue
+		}
+
+		reviewedAt := review.ReviewedAt
+		if reviewedAt.After(now) {
+			reviewedAt = now
+		}
+
+		cardResults := make([]models.CardReveiewResult, len(review.Results))
+		for j, value := range review.Results {
+			cardResults[j] = models.CardReveiewResult{
+				CardId:    value.CardId,
+				IsCorrect: value.IsCorrect,
+			}
+		}
+
+		applied, err := s.deckService.Review(userId, review.DeckId, 0, reviewedAt, cardResults)
+		if errors.Is(err, concurrency.ErrVersionConflict) {
+			// The deck changed while this review was applied; it is read
+			// again on the retry.
+			applied, err = s.deckService.Review(userId, review.DeckId, 0, reviewedAt, cardResults)
+		}
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				results[i].Error = "deck not found"
+			} else {
+				results[i].Error = err.Error()
+			}
+			continue
+		}
+
+		nextReviewDate := applied.NextReviewDate
+		results[i].Status = offlinesync.ReviewApplied
+		results[i].Accuracy = applied.Accuracy
+		results[i].Level = applied.Level
+		results[i].NextReviewDate = &nextReviewDate
+		results[i].Version = applied.Version
+	}
+
+	return results
+}
+
+func groupLinks(links []offlinesync.CardLink) map[int][]int {
+	grouped := make(map[int][]int)
+	for _, link := range links {
+		grouped[link.ParentId] = append(grouped[link.ParentId], link.CardId)
+	}
+	return grouped
+}
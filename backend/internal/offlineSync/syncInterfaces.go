@@ -0,0 +1,64 @@
+package offlinesync
+
+import (
+	models "dimplom_harmonic/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SyncService interface {
+	Sync(userId int, input SyncRequestDTO) (*SyncResponseDTO, error)
+}
+
+// SyncRepository reads rows changed by transactions not older than the
+// cursor. Soft-deleted rows are included, so clients learn about deletions.
+type SyncRepository interface {
+	CurrentXmin() (int64, error)
+	GetDecks(userId int, since int64) ([]models.Deck, error)
+	GetWordSets(userId int, since int64) ([]WordSetResult, error)
+	GetCards(userId int, since int64) ([]models.Card, error)
+	GetSchedules(userId int, since int64) ([]models.DeckSchedule, error)
+	GetDeckCardIds(deckIds []int) ([]CardLink, error)
+	GetWordSetCardIds(wordSetIds []int) ([]CardLink, error)
+	GetDeletions(userId int, since int64) ([]Deletion, error)
+	WithTx(tx *gorm.DB) SyncRepository
+}
+
+type WordSetResult struct {
+	models.WordSet
+	Role string
+}
+
+type CardLink struct {
+	ParentId int
+	CardId   int
+}
+
+type Deletion struct {
+	Entity   string
+	EntityId int
+}
+
+const (
+	EntityDeck     = "deck"
+	EntityWordSet  = "word_set"
+	EntitySchedule = "schedule"
+)
+
+const (
+	ReviewApplied  = "applied"
+	ReviewRejected = "rejected"
+)
+
+// DeletionsRetention is how long deletions are kept. A client with an older
+// cursor gets a full snapshot instead.
+const DeletionsRetention = 90 * 24 * time.Hour
+
+const MaxReviews = 500
+
+var (
+	ErrInvalidCursor  = errors.New("invalid_cursor")
+	ErrTooManyReviews = errors.New("too_many_reviews")
+)
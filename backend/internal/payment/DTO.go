@@ -0,0 +1,44 @@
+package payment
+
+import (
+	models "dimplom_harmonic/domain"
+	"time"
+)
+
+type CheckoutRequestDTO struct {
+	PlanId string `json:"planId"`
+}
+
+type CheckoutResponseDTO struct {
+	SessionId string `json:"sessionId"`
+	URL       string `json:"url"`
+}
+
+type PaymentDTO struct {
+	Id        int        `json:"id"`
+	Kind      string     `json:"kind"`
+	PlanId    string     `json:"planId"`
+	Amount    int64      `json:"amount"`
+	Currency  string     `json:"currency"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	PaidAt    *time.Time `json:"paidAt"`
+}
+
+func PaymentsModelTo(m []models.Payment) []PaymentDTO {
+	payments := make([]PaymentDTO, 0, len(m))
+
+	for _, value := range m {
+		payments = append(payments, PaymentDTO{
+			Id:        value.Id,
+			Kind:      value.Kind,
+			PlanId:    value.PlanId,
+			Amount:    value.Amount,
+			Currency:  value.Currency,
+			Status:    value.Status,
+			CreatedAt: value.CreatedAt,
+			PaidAt:    value.PaidAt,
+		})
+	}
+	return payments
+}
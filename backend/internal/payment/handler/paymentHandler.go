@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"dimplom_harmonic/internal/middleware"
+	"dimplom_harmonic/internal/payment"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+)
+
+const maxWebhookSize = 64 << 10
+
+type PaymentHandler struct {
+	service payment.PaymentService
+}
+
+func NewPaymentHandler(service payment.PaymentService) *PaymentHandler {
+	return &PaymentHandler{service: service}
+}
+
+func (h *PaymentHandler) HDCreateCheckout(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	var input payment.CheckoutRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		http.Error(w, "Wrong format json", http.StatusBadRequest)
+		return
+	}
+
+	checkout, err := h.service.CreateCheckout(userId, input.PlanId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(checkout)
+}
+
+func (h *PaymentHandler) HDWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		http.Error(w, "Can't read body", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.HandleWebhook(payload, r.Header)
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		http.Error(w, "Invalid signature", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Payment webhook error: %v", err)
+		http.Error(w, "Webhook processing failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *PaymentHandler) HDCancelSubscription(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	err := h.service.CancelSubscription(userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *PaymentHandler) HDGetPayments(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	payments, err := h.service.GetPayments(userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(payments)
+}
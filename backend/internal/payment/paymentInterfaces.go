@@ -0,0 +1,97 @@
+package payment
+
+import (
+	models "dimplom_harmonic/domain"
+	"errors"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentService interface {
+	CreateCheckout(userId int, planId string) (*CheckoutResponseDTO, error)
+	HandleWebhook(payload []byte, header http.Header) error
+	CancelSubscription(userId int) error
+	GetPayments(userId int) ([]PaymentDTO, error)
+}
+
+type PaymentRepository interface {
+	CreatePayment(payment *models.Payment) error
+	GetPaymentByExternalId(externalId string) (*models.Payment, error)
+	UpdatePayment(paymentId int, changePayment map[string]any) error
+	GetPayments(userId int) ([]models.Payment, error)
+
+	SaveSubscription(subscription *models.Subscription) error
+	GetSubscriptionByExternalId(externalId string) (*models.Subscription, error)
+	GetActiveSubscription(userId int) (*models.Subscription, error)
+	UpdateSubscription(subscriptionId int, changeSubscription map[string]any) error
+
+	SaveEvent(event *models.PaymentEvent) (bool, error)
+	WithTx(tx *gorm.DB) PaymentRepository
+}
+
+// Provider is a payment gateway. Implementations translate their own API and
+// webhook payloads into the types below.
+type Provider interface {
+	Name() string
+	CreateCheckoutSession(params CheckoutParams) (*CheckoutSession, error)
+	CancelSubscription(subscriptionId string) error
+	ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error)
+}
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+const (
+	EventCheckoutCompleted     = "checkout_completed"
+	EventInvoicePaid           = "invoice_paid"
+	EventInvoiceFailed         = "invoice_failed"
+	EventSubscriptionCancelled = "subscription_cancelled"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusPaid     = "paid"
+	StatusFailed   = "failed"
+	StatusActive   = "active"
+	StatusCanceled = "canceled"
+)
+
+const (
+	KindCheckout = "checkout"
+	KindInvoice  = "invoice"
+)
+
+type Plan struct {
+	Id        string
+	PriceId   string
+	Months    int
+	Recurring bool
+}
+
+type CheckoutParams struct {
+	UserId     int
+	Email      string
+	Plan       Plan
+	SuccessURL string
+	CancelURL  string
+}
+
+type CheckoutSession struct {
+	Id  string
+	URL string
+}
+
+type WebhookEvent struct {
+	Id   string
+	Type string
+
+	ObjectId       string
+	SubscriptionId string
+	UserId         int
+	PlanId         string
+	Amount         int64
+	Currency       string
+	BillingReason  string
+	PeriodEnd      time.Time
+}
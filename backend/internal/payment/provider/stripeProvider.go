@@ -0,0 +1,251 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"dimplom_harmonic/internal/payment"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signatureTolerance is how old a signed webhook may be before it's rejected
+// as a possible replay.
+const signatureTolerance = 5 * time.Minute
+
+// StripeProvider talks to the Stripe API, or to anything speaking the same
+// protocol at apiURL (a local fake server in development).
+type StripeProvider struct {
+	apiURL        string
+	secretKey     string
+	webhookSecret string
+	client        *http.Client
+}
+
+func NewStripeProvider(apiURL, secretKey, webhookSecret string) *StripeProvider {
+	return &StripeProvider{
+		apiURL:        strings.TrimRight(apiURL, "/"),
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *StripeProvider) Name() string {
+	return "stripe"
+}
+
+func (p *StripeProvider) CreateCheckoutSession(params payment.CheckoutParams) (*payment.CheckoutSession, error) {
+	userId := strconv.Itoa(params.UserId)
+
+	form := url.Values{}
+	form.Set("line_items[0][price]", params.Plan.PriceId)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("success_url", params.SuccessURL)
+	form.Set("cancel_url", params.CancelURL)
+	form.Set("client_reference_id", userId)
+	form.Set("customer_email", params.Email)
+	form.Set("metadata[user_id]", userId)
+	form.Set("metadata[plan_id]", params.Plan.Id)
+
+	if params.Plan.Recurring {
+		form.Set("mode", "subscription")
+		form.Set("subscription_data[metadata][user_id]", userId)
+		form.Set("subscription_data[metadata][plan_id]", params.Plan.Id)
+	} else {
+		form.Set("mode", "payment")
+	}
+
+	var session struct {
+		Id  string `json:"id"`
+		URL string `json:"url"`
+	}
+
+	err := p.post("/v1/checkout/sessions", form, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment.CheckoutSession{Id: session.Id, URL: session.URL}, nil
+}
+
+func (p *StripeProvider) CancelSubscription(subscriptionId string) error {
+	form := url.Values{}
+	form.Set("cancel_at_period_end", "true")
+
+	return p.post("/v1/subscriptions/"+url.PathEscape(subscriptionId), form, nil)
+}
+
+func (p *StripeProvider) post(path string, form url.Values, out any) error {
+	req, err := http.NewRequest(http.MethodPost, p.apiURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.secretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("stripe: %s: %s", resp.Status, apiErr.Error.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type stripeEvent struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object stripeObject `json:"object"`
+	} `json:"data"`
+}
+
+type stripeObject struct {
+	Id                string            `json:"id"`
+	ClientReferenceId string            `json:"client_reference_id"`
+	Subscription      string            `json:"subscription"`
+	AmountTotal       int64             `json:"amount_total"`
+	AmountPaid        int64             `json:"amount_paid"`
+	AmountDue         int64             `json:"amount_due"`
+	Currency          string            `json:"currency"`
+	BillingReason     string            `json:"billing_reason"`
+	CurrentPeriodEnd  int64             `json:"current_period_end"`
+	Metadata          map[string]string `json:"metadata"`
+
+	SubscriptionDetails struct {
+		Metadata map[string]string `json:"metadata"`
+	} `json:"subscription_details"`
+
+	Lines struct {
+		Data []struct {
+			Period struct {
+				End int64 `json:"end"`
+			} `json:"period"`
+		} `json:"data"`
+	} `json:"lines"`
+}
+
+// ParseWebhook verifies the Stripe-Signature header and maps the event onto a
+// payment.WebhookEvent. Unknown event types are returned with an empty Type.
+func (p *StripeProvider) ParseWebhook(payload []byte, header http.Header) (*payment.WebhookEvent, error) {
+	if err := p.verifySignature(payload, header.Get("Stripe-Signature")); err != nil {
+		return nil, err
+	}
+
+	var raw stripeEvent
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, err
+	}
+
+	object := raw.Data.Object
+	event := &payment.WebhookEvent{
+		Id:       raw.Id,
+		ObjectId: object.Id,
+		Currency: object.Currency,
+	}
+
+	metadata := object.Metadata
+
+	switch raw.Type {
+	case "checkout.session.completed":
+		event.Type = payment.EventCheckoutCompleted
+		event.SubscriptionId = object.Subscription
+		event.Amount = object.AmountTotal
+		if object.ClientReferenceId != "" {
+			event.UserId, _ = strconv.Atoi(object.ClientReferenceId)
+		}
+	case "invoice.paid", "invoice.payment_failed":
+		event.Type = payment.EventInvoicePaid
+		event.Amount = object.AmountPaid
+		if raw.Type == "invoice.payment_failed" {
+			event.Type = payment.EventInvoiceFailed
+			event.Amount = object.AmountDue
+		}
+		event.SubscriptionId = object.Subscription
+		event.BillingReason = object.BillingReason
+		if len(object.Lines.Data) != 0 {
+			event.PeriodEnd = time.Unix(object.Lines.Data[0].Period.End, 0)
+		}
+		if len(object.SubscriptionDetails.Metadata) != 0 {
+			metadata = object.SubscriptionDetails.Metadata
+		}
+	case "customer.subscription.deleted":
+		event.Type = payment.EventSubscriptionCancelled
+		event.SubscriptionId = object.Id
+		if object.CurrentPeriodEnd != 0 {
+			event.PeriodEnd = time.Unix(object.CurrentPeriodEnd, 0)
+		}
+	}
+
+	if event.UserId == 0 && metadata["user_id"] != "" {
+		event.UserId, _ = strconv.Atoi(metadata["user_id"])
+	}
+	event.PlanId = metadata["plan_id"]
+
+	return event, nil
+}
+
+func (p *StripeProvider) verifySignature(payload []byte, signatureHeader string) error {
+	if signatureHeader == "" || p.webhookSecret == "" {
+		return payment.ErrInvalidSignature
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unixTime, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return payment.ErrInvalidSignature
+	}
+
+	age := time.Since(time.Unix(unixTime, 0))
+	if age > signatureTolerance || age < -signatureTolerance {
+		return errors.Join(payment.ErrInvalidSignature, errors.New("timestamp outside tolerance"))
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.webhookSecret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return payment.ErrInvalidSignature
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/payment"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+func (r *PaymentRepository) WithTx(tx *gorm.DB) payment.PaymentRepository {
+	return &PaymentRepository{
+		db: tx,
+	}
+}
+
+func (r *PaymentRepository) CreatePayment(payment *models.Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *PaymentRepository) GetPaymentByExternalId(externalId string) (*models.Payment, error) {
+	var payment models.Payment
+
+	err := r.db.Where("external_id = ?", externalId).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepository) UpdatePayment(paymentId int, changePayment map[string]any) error {
+	return r.db.Model(&models.Payment{}).Where("id = ?", paymentId).Updates(changePayment).Error
+}
+
+func (r *PaymentRepository) GetPayments(userId int) ([]models.Payment, error) {
+	var payments []models.Payment
+
+	err := r.db.Where("user_id = ?", userId).Order("created_at DESC").Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (r *PaymentRepository) SaveSubscription(subscription *models.Subscription) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "external_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "current_period_end"}),
+	}).Create(subscription).Error
+}
+
+func (r *PaymentRepository) GetSubscriptionByExternalId(externalId string) (*models.Subscription, error) {
+	var subscription models.Subscription
+
+	err := r.db.Where("external_id = ?", externalId).First(&subscription).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *PaymentRepository) GetActiveSubscription(userId int) (*models.Subscription, error) {
+	var subscription models.Subscription
+
+	err := r.db.Where("user_id = ? AND status = ?", userId, payment.StatusActive).
+		Order("created_at DESC").
+		First(&subscription).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *PaymentRepository) UpdateSubscription(subscriptionId int, changeSubscription map[string]any) error {
+	return r.db.Model(&models.Subscription{}).Where("id = ?", subscriptionId).Updates(changeSubscription).Error
+}
+
+// SaveEvent records a processed webhook event. It returns false when the
+// event was already recorded, so the caller can skip a redelivery.
+func (r *PaymentRepository) SaveEvent(event *models.PaymentEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}},
+		DoNothing: true,
+	}).Create(event)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected != 0, nil
+}
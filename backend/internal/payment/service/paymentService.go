@@ -0,0 +1,341 @@
+package service
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/auth"
+	"dimplom_harmonic/internal/entitlement"
+	"dimplom_harmonic/internal/payment"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentService struct {
+	paymentRepo  payment.PaymentRepository
+	userRepo     auth.UserRepository
+	provider     payment.Provider
+	entitlements entitlement.EntitlementService
+	plans        map[string]payment.Plan
+	successURL   string
+	cancelURL    string
+	db           *gorm.DB
+}
+
+func NewPaymentService(paymentRepo payment.PaymentRepository, userRepo auth.UserRepository, provider payment.Provider, entitlements entitlement.EntitlementService, plans []payment.Plan, successURL, cancelURL string, db *gorm.DB) *PaymentService {
+	plansById := make(map[string]payment.Plan, len(plans))
+	for _, plan := range plans {
+		plansById[plan.Id] = plan
+	}
+
+	return &PaymentService{
+		paymentRepo:  paymentRepo,
+		userRepo:     userRepo,
+		provider:     provider,
+		entitlements: entitlements,
+		plans:        plansById,
+		successURL:   successURL,
+		cancelURL:    cancelURL,
+		db:           db,
+	}
+}
+
+func (s *PaymentService) CreateCheckout(userId int, planId string) (*payment.CheckoutResponseDTO, error) {
+
+	plan, ok := s.plans[planId]
+	if !ok {
+		return nil, errors.New("unknown plan")
+	}
+
+	user, err := s.userRepo.GetByID(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.entitlements.Status(user) == entitlement.StatusLifetime {
+		return nil, errors.New("lifetime premium is already active")
+	}
+
+	if plan.Recurring {
+		_, err := s.paymentRepo.GetActiveSubscription(userId)
+		if err == nil {
+			return nil, errors.New("subscription is already active")
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	session, err := s.provider.CreateCheckoutSession(payment.CheckoutParams{
+		UserId:     userId,
+		Email:      user.Email,
+		Plan:       plan,
+		SuccessURL: s.successURL,
+		CancelURL:  s.cancelURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newPayment := models.Payment{
+		UserId:     userId,
+		Provider:   s.provider.Name(),
+		Kind:       payment.KindCheckout,
+		ExternalId: session.Id,
+		PlanId:     plan.Id,
+		Status:     payment.StatusPending,
+		CreatedAt:  time.Now(),
+	}
+
+	err = s.paymentRepo.CreatePayment(&newPayment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment.CheckoutResponseDTO{SessionId: session.Id, URL: session.URL}, nil
+}
+
+// HandleWebhook applies a provider event. Every event is recorded in the same
+// transaction as its effects, so redeliveries of a processed event are no-ops.
+func (s *PaymentService) HandleWebhook(payload []byte, header http.Header) error {
+
+	event, err := s.provider.ParseWebhook(payload, header)
+	if err != nil {
+		return err
+	}
+
+	if event.Type == "" {
+		return nil
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txPaymentRepo := s.paymentRepo.WithTx(tx)
+		txUserRepo := s.userRepo.WithTx(tx)
+
+		isNew, err := txPaymentRepo.SaveEvent(&models.PaymentEvent{
+			Provider:    s.provider.Name(),
+			EventId:     event.Id,
+			Type:        event.Type,
+			ProcessedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if !isNew {
+			log.Println("Payment event", event.Id, "was already processed")
+			return nil
+		}
+
+		switch event.Type {
+		case payment.EventCheckoutCompleted:
+			return s.checkoutCompleted(txPaymentRepo, txUserRepo, event)
+		case payment.EventInvoicePaid:
+			return s.invoicePaid(txPaymentRepo, txUserRepo, event)
+		case payment.EventInvoiceFailed:
+			return s.invoiceFailed(txPaymentRepo, event)
+		case payment.EventSubscriptionCancelled:
+			return s.subscriptionCancelled(txPaymentRepo, txUserRepo, event)
+		}
+		return nil
+	})
+}
+
+func (s *PaymentService) checkoutCompleted(paymentRepo payment.PaymentRepository, userRepo auth.UserRepository, event *payment.WebhookEvent) error {
+
+	checkout, err := paymentRepo.GetPaymentByExternalId(event.ObjectId)
+	if err != nil {
+		return err
+	}
+
+	if checkout.Status == payment.StatusPaid {
+		return nil
+	}
+
+	plan, ok := s.plans[checkout.PlanId]
+	if !ok {
+		return errors.New("unknown plan")
+	}
+
+	now := time.Now()
+	err = paymentRepo.UpdatePayment(checkout.Id, map[string]any{
+		"Status":         payment.StatusPaid,
+		"PaidAt":         now,
+		"Amount":         event.Amount,
+		"Currency":       event.Currency,
+		"SubscriptionId": event.SubscriptionId,
+	})
+	if err != nil {
+		return err
+	}
+
+	user, err := userRepo.GetByID(checkout.UserId)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := extendPremium(user.PremiumExpiresAt, plan)
+
+	if plan.Recurring && event.SubscriptionId != "" {
+		err = paymentRepo.SaveSubscription(&models.Subscription{
+			UserId:           checkout.UserId,
+			Provider:         s.provider.Name(),
+			ExternalId:       event.SubscriptionId,
+			PlanId:           plan.Id,
+			Status:           payment.StatusActive,
+			CurrentPeriodEnd: expiresAt,
+			CreatedAt:        now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return userRepo.UpdatePremiumExpiresAt(checkout.UserId, expiresAt)
+}
+
+// invoicePaid extends premium on subscription renewals. The first invoice of a
+// subscription is only recorded: its period is granted by checkoutCompleted.
+func (s *PaymentService) invoicePaid(paymentRepo payment.PaymentRepository, userRepo auth.UserRepository, event *payment.WebhookEvent) error {
+
+	subscription, err := paymentRepo.GetSubscriptionByExternalId(event.SubscriptionId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = paymentRepo.CreatePayment(&models.Payment{
+		UserId:         subscription.UserId,
+		Provider:       s.provider.Name(),
+		Kind:           payment.KindInvoice,
+		ExternalId:     event.ObjectId,
+		SubscriptionId: event.SubscriptionId,
+		PlanId:         subscription.PlanId,
+		Amount:         event.Amount,
+		Currency:       event.Currency,
+		Status:         payment.StatusPaid,
+		CreatedAt:      now,
+		PaidAt:         &now,
+	})
+	if err != nil {
+		return err
+	}
+
+	if event.BillingReason == "subscription_create" {
+		return nil
+	}
+
+	user, err := userRepo.GetByID(subscription.UserId)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := event.PeriodEnd
+	if expiresAt.IsZero() {
+		expiresAt = extendPremium(user.PremiumExpiresAt, s.plans[subscription.PlanId])
+	}
+	if user.PremiumExpiresAt.After(expiresAt) {
+		expiresAt = user.PremiumExpiresAt
+	}
+
+	err = paymentRepo.UpdateSubscription(subscription.Id, map[string]any{
+		"Status":           payment.StatusActive,
+		"CurrentPeriodEnd": expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return userRepo.UpdatePremiumExpiresAt(subscription.UserId, expiresAt)
+}
+
+func (s *PaymentService) invoiceFailed(paymentRepo payment.PaymentRepository, event *payment.WebhookEvent) error {
+
+	subscription, err := paymentRepo.GetSubscriptionByExternalId(event.SubscriptionId)
+	if err != nil {
+		return err
+	}
+
+	return paymentRepo.CreatePayment(&models.Payment{
+		UserId:         subscription.UserId,
+		Provider:       s.provider.Name(),
+		Kind:           payment.KindInvoice,
+		ExternalId:     event.ObjectId,
+		SubscriptionId: event.SubscriptionId,
+		PlanId:         subscription.PlanId,
+		Amount:         event.Amount,
+		Currency:       event.Currency,
+		Status:         payment.StatusFailed,
+		CreatedAt:      time.Now(),
+	})
+}
+
+// subscriptionCancelled ends premium at the end of the paid period. Lifetime
+// premium bought separately is left untouched.
+func (s *PaymentService) subscriptionCancelled(paymentRepo payment.PaymentRepository, userRepo auth.UserRepository, event *payment.WebhookEvent) error {
+
+	subscription, err := paymentRepo.GetSubscriptionByExternalId(event.SubscriptionId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	periodEnd := event.PeriodEnd
+	if periodEnd.IsZero() || periodEnd.After(subscription.CurrentPeriodEnd) {
+		periodEnd = subscription.CurrentPeriodEnd
+	}
+
+	err = paymentRepo.UpdateSubscription(subscription.Id, map[string]any{
+		"Status":     payment.StatusCanceled,
+		"CanceledAt": now,
+	})
+	if err != nil {
+		return err
+	}
+
+	user, err := userRepo.GetByID(subscription.UserId)
+	if err != nil {
+		return err
+	}
+
+	if s.entitlements.Status(user) == entitlement.StatusLifetime || !user.PremiumExpiresAt.After(periodEnd) {
+		return nil
+	}
+
+	return userRepo.UpdatePremiumExpiresAt(subscription.UserId, periodEnd)
+}
+
+// CancelSubscription asks the provider to stop renewing. Premium stays active
+// until the provider reports the subscription as deleted at period end.
+func (s *PaymentService) CancelSubscription(userId int) error {
+
+	subscription, err := s.paymentRepo.GetActiveSubscription(userId)
+	if err != nil {
+		return err
+	}
+
+	return s.provider.CancelSubscription(subscription.ExternalId)
+}
+
+func (s *PaymentService) GetPayments(userId int) ([]payment.PaymentDTO, error) {
+
+	payments, err := s.paymentRepo.GetPayments(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment.PaymentsModelTo(payments), nil
+}
+
+func extendPremium(current time.Time, plan payment.Plan) time.Time {
+	now := time.Now()
+	if current.After(now) {
+		now = current
+	}
+
+	if plan.Months == 0 {
+		return now.AddDate(1000, 0, 0)
+	}
+	return now.AddDate(0, plan.Months, 0)
+}
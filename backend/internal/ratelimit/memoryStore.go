@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type counter struct {
+	count     int
+	expiresAt time.Time
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]*counter), lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Hit(key string, limit int, window time.Duration) (Result, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, c := range s.counters {
+			if !c.expiresAt.After(now) {
+				delete(s.counters, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	c, ok := s.counters[key]
+	if !ok || !c.expiresAt.After(now) {
+		c = &counter{expiresAt: now.Add(window)}
+		s.counters[key] = c
+	}
+	c.count++
+
+	return newResult(c.count, limit, c.expiresAt.Sub(now)), nil
+}
+
+func newResult(count, limit int, resetIn time.Duration) Result {
+	if count > limit {
+		return Result{Allowed: false, RetryAfter: resetIn}
+	}
+	return Result{Allowed: true, Remaining: limit - count}
+}
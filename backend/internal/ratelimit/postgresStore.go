@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Hit increments the counter in one statement, starting a new window when the
+// old one has expired, so concurrent instances can't lose updates.
+func (s *PostgresStore) Hit(key string, limit int, window time.Duration) (Result, error) {
+	now := time.Now()
+
+	var row struct {
+		Count     int
+		ExpiresAt time.Time
+	}
+
+	query := `
+		INSERT INTO rate_limits (key, count, expires_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limits.expires_at <= ? THEN 1 ELSE rate_limits.count + 1 END,
+			expires_at = CASE WHEN rate_limits.expires_at <= ? THEN EXCLUDED.expires_at ELSE rate_limits.expires_at END
+		RETURNING count, expires_at
+	`
+	err := s.db.Raw(query, key, now.Add(window), now, now).Scan(&row).Error
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(row.Count, limit, row.ExpiresAt.Sub(now)), nil
+}
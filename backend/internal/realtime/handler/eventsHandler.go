@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"dimplom_harmonic/internal/middleware"
+	"dimplom_harmonic/internal/realtime"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// heartbeatInterval keeps proxies from closing an idle stream.
+const heartbeatInterval = 25 * time.Second
+
+type EventsHandler struct {
+	bus realtime.Bus
+
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+func NewEventsHandler(bus realtime.Bus) *EventsHandler {
+	return &EventsHandler{bus: bus, shutdown: make(chan struct{})}
+}
+
+// Shutdown ends every open stream, so a graceful server shutdown doesn't
+// wait for them. Clients reconnect after the retry delay.
+func (h *EventsHandler) Shutdown() {
+	h.shutdownOnce.Do(func() { close(h.shutdown) })
+}
+
+// HDStream sends the user's events as server-sent events until the client
+// disconnects or the access token expires. Ending the stream with the token
+// makes revoked sessions and deleted accounts stop receiving events.
+func (h *EventsHandler) HDStream(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	subscription := h.bus.Subscribe(userId)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	var tokenExpired <-chan time.Time
+	if expiresAt, ok := r.Context().Value(middleware.TokenExpiresAtKey).(time.Time); ok {
+		expiry := time.NewTimer(time.Until(expiresAt))
+		defer expiry.Stop()
+		tokenExpired = expiry.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.shutdown:
+			return
+		case <-tokenExpired:
+			fmt.Fprintf(w, "event: %s\ndata: {}\n\n", realtime.EventSessionExpired)
+			flusher.Flush()
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event := <-subscription.Events:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+			flusher.Flush()
+		}
+	}
+}
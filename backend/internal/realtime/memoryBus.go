@@ -0,0 +1,67 @@
+package realtime
+
+import (
+	"log"
+	"sync"
+)
+
+// subscriberBuffer is how many events a slow session may lag behind before
+// new ones are dropped for it.
+const subscriberBuffer = 32
+
+// MemoryBus delivers events to sessions connected to this instance.
+type MemoryBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]map[chan Event]struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subscribers: make(map[int]map[chan Event]struct{})}
+}
+
+func (b *MemoryBus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	sent := make(map[int]bool, len(event.UserIds))
+	for _, userId := range event.UserIds {
+		if sent[userId] {
+			continue
+		}
+		sent[userId] = true
+
+		for ch := range b.subscribers[userId] {
+			select {
+			case ch <- event:
+			default:
+				log.Printf("Dropped %s event for user %d: session is too slow", event.Type, userId)
+			}
+		}
+	}
+}
+
+func (b *MemoryBus) Subscribe(userId int) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[userId] == nil {
+		b.subscribers[userId] = make(map[chan Event]struct{})
+	}
+	b.subscribers[userId][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return &Subscription{
+		Events: ch,
+		close: func() {
+			once.Do(func() {
+				b.mu.Lock()
+				delete(b.subscribers[userId], ch)
+				if len(b.subscribers[userId]) == 0 {
+					delete(b.subscribers, userId)
+				}
+				b.mu.Unlock()
+			})
+		},
+	}
+}
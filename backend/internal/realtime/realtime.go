@@ -0,0 +1,57 @@
+// Package realtime pushes events about a user's data to their open sessions,
+// so other tabs and devices see changes without reloading. Events are plain
+// JSON, so the in-process bus can later be swapped for one backed by
+// Postgres LISTEN/NOTIFY without touching the services.
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+)
+
+const (
+	EventDeckReviewed   = "deck.reviewed"
+	EventCardCreated    = "card.created"
+	EventCardUpdated    = "card.updated"
+	EventCardDeleted    = "card.deleted"
+	EventWordSetChanged = "word_set.changed"
+
+	// EventSessionExpired is the last event of a stream, sent when the access
+	// token it was opened with expires. The client reconnects with a fresh one.
+	EventSessionExpired = "session.expired"
+)
+
+// Event is delivered to every open session of the users in UserIds.
+type Event struct {
+	Type    string          `json:"type"`
+	UserIds []int           `json:"userIds"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type Publisher interface {
+	Publish(event Event)
+}
+
+type Bus interface {
+	Publisher
+	// Subscribe returns the user's events until the subscription is closed.
+	Subscribe(userId int) *Subscription
+}
+
+type Subscription struct {
+	Events <-chan Event
+	close  func()
+}
+
+func (s *Subscription) Close() {
+	s.close()
+}
+
+func NewEvent(eventType string, userIds []int, data any) Event {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Marshal %s event error: %v", eventType, err)
+		raw = json.RawMessage("{}")
+	}
+	return Event{Type: eventType, UserIds: userIds, Data: raw}
+}
@@ -6,6 +6,7 @@ type ScheduleDTO struct {
 	Id              int                  `json:"id"`
 	Name            string               `json:"name"`
 	SchedueleLevels []SchedueleLevelsDTO `json:"levels"`
+	Version         int                  `json:"version"`
 }
 
 type SchedueleLevelsDTO struct {
@@ -38,6 +39,7 @@ func ScheduleModelTo(m *models.DeckSchedule) ScheduleDTO {
 
 	schedleReturn.Id = m.Id
 	schedleReturn.Name = m.Name
+	schedleReturn.Version = m.Version
 	scheduleSteps := []SchedueleLevelsDTO{}
 
 	for _, value := range m.ScheduleSteps {
@@ -63,6 +65,7 @@ func ScheduleListModelTo(m []models.DeckSchedule) []ScheduleDTO {
 }
 
 type UpdateScheduleDTO struct {
-	Name   string                `json:"name"`
-	Levels []models.ScheduleStep `json:"levels"`
+	Name    string                `json:"name"`
+	Levels  []models.ScheduleStep `json:"levels"`
+	Version int                   `json:"version"`
 }
@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"dimplom_harmonic/internal/concurrency"
 	"dimplom_harmonic/internal/middleware"
 	"dimplom_harmonic/internal/schedule"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -93,13 +95,23 @@ func (h *ScheduleHandler) HDUpdateSchedule(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Wrong Json format", http.StatusBadRequest)
 		return
 	}
-	responseData, err := h.service.UpdateSchedule(userId, scheduleId, input.Name, input.Levels)
+	version, fromHeader, err := concurrency.ExpectedVersion(r, input.Version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	responseData, err := h.service.UpdateSchedule(userId, scheduleId, version, input.Name, input.Levels)
+	if errors.Is(err, concurrency.ErrVersionConflict) {
+		concurrency.WriteConflict(w, fromHeader)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	concurrency.SetETag(w, responseData.Version)
 	w.Header().Set("Content-Type", "Application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(responseData)
@@ -2,9 +2,11 @@ package repository
 
 import (
 	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/concurrency"
 	"dimplom_harmonic/internal/schedule"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ScheduleRepository struct {
@@ -58,12 +60,31 @@ func (r *ScheduleRepository) CreateScheduleInterval(scheduleSteps []models.Sched
 	return nil
 }
 
-func (r *ScheduleRepository) UpdateScheduleName(scheduleId int, schedule map[string]any) error {
-	result := r.db.Model(models.DeckSchedule{}).Where("id = ?", scheduleId).Updates(schedule)
+// UpdateScheduleName bumps the schedule's version and returns the new one. A
+// non-zero version makes the update conditional on the schedule still
+// having it.
+func (r *ScheduleRepository) UpdateScheduleName(scheduleId, version int, schedule map[string]any) (int, error) {
+	var updated models.DeckSchedule
+
+	query := r.db.Model(&updated).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "version"}}}).
+		Where("id = ?", scheduleId)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	schedule["version"] = gorm.Expr("version + 1")
+	result := query.Updates(schedule)
 	if result.Error != nil {
-		return result.Error
+		return 0, result.Error
 	}
-	return nil
+	if result.RowsAffected == 0 {
+		if version != 0 {
+			return 0, concurrency.ErrVersionConflict
+		}
+		return 0, gorm.ErrRecordNotFound
+	}
+	return updated.Version, nil
 }
 
 func (r *ScheduleRepository) DeleteScheduleInterval(scheduleId int) error {
@@ -88,6 +109,17 @@ func (r *ScheduleRepository) DeleteSchedule(schedule *models.DeckSchedule, sched
 	return nil
 }
 
+func (r *ScheduleRepository) GetDefaultSchedule(userId int) (*models.DeckSchedule, error) {
+	var schedule models.DeckSchedule
+	err := r.db.Where("user_id = ? AND is_default", userId).First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// UpdateSchedule moves decks and group assignments from one schedule to
+// another.
 func (r *ScheduleRepository) UpdateSchedule(scheduleId, newScheduleId int) error {
 
 	query := `
@@ -101,5 +133,5 @@ func (r *ScheduleRepository) UpdateSchedule(scheduleId, newScheduleId int) error
 		return err
 	}
 
-	return nil
+	return r.db.Exec(`UPDATE group_assignments SET schedule_id = ? WHERE schedule_id = ?`, newScheduleId, scheduleId).Error
 }
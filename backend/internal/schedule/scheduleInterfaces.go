@@ -10,20 +10,21 @@ type ScheduleService interface {
 	CreateSchedule(scheduleCreate models.DeckSchedule, levels []models.ScheduleStep) (*models.DeckSchedule, error)
 	GetAllSchedules(userId int) ([]models.DeckSchedule, error)
 	DeleteSchedule(scheduleId, newscheduleId int) error
-	UpdateSchedule(userId, scheduleId int, name string, levels []models.ScheduleStep) (*models.DeckSchedule, error)
+	UpdateSchedule(userId, scheduleId, version int, name string, levels []models.ScheduleStep) (*models.DeckSchedule, error)
 }
 
 type ScheduleRepository interface {
 	CreateSchedule(schedule *models.DeckSchedule) error
 	GetAllSchedules(userId int) ([]models.DeckSchedule, error)
 	DeleteSchedule(schedule *models.DeckSchedule, scheduleId int) error
-	UpdateScheduleName(scheduleId int, scheduleName map[string]any) error
+	UpdateScheduleName(scheduleId, version int, scheduleName map[string]any) (int, error)
 
 	CreateScheduleInterval(scheduleSteps []models.ScheduleStep) error
 	GetInterval(scheduleId int, level int) (*int, error)
 	DeleteScheduleInterval(scheduleId int) error
 
 	GetSchedule(scheduleId int) (*models.DeckSchedule, error)
+	GetDefaultSchedule(userId int) (*models.DeckSchedule, error)
 	UpdateSchedule(scheduleId, newScheduleId int) error
 
 	WithTx(tx *gorm.DB) ScheduleRepository
@@ -93,7 +93,7 @@ func (s *ScheduleService) DeleteSchedule(scheduleId int, newscheduleId int) erro
 
 }
 
-func (s *ScheduleService) UpdateSchedule(userId, scheduleId int, name string, levels []models.ScheduleStep) (*models.DeckSchedule, error) {
+func (s *ScheduleService) UpdateSchedule(userId, scheduleId, version int, name string, levels []models.ScheduleStep) (*models.DeckSchedule, error) {
 	var scheduleUpdate *models.DeckSchedule
 	var scheduleSteps []models.ScheduleStep
 
@@ -107,9 +107,12 @@ func (s *ScheduleService) UpdateSchedule(userId, scheduleId int, name string, le
 		scheduleSteps = append(scheduleSteps, level)
 	}
 
+	var newVersion int
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		txWordSetRepo := s.repo.WithTx(tx)
-		if err := txWordSetRepo.UpdateScheduleName(scheduleId, scheduleUpdateName); err != nil {
+		var err error
+		newVersion, err = txWordSetRepo.UpdateScheduleName(scheduleId, version, scheduleUpdateName)
+		if err != nil {
 			return err
 		}
 
@@ -132,6 +135,7 @@ func (s *ScheduleService) UpdateSchedule(userId, scheduleId int, name string, le
 		Name:          name,
 		ScheduleSteps: scheduleSteps,
 		UserId:        userId,
+		Version:       newVersion,
 	}
 
 	return scheduleUpdate, nil
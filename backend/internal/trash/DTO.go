@@ -0,0 +1,70 @@
+package trash
+
+import (
+	models "dimplom_harmonic/domain"
+	"time"
+)
+
+type TrashDTO struct {
+	Decks    []TrashItemDTO `json:"decks"`
+	WordSets []TrashItemDTO `json:"wordSets"`
+	Cards    []TrashCardDTO `json:"cards"`
+}
+
+type TrashItemDTO struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"`
+}
+
+type TrashCardDTO struct {
+	Id           int       `json:"id"`
+	OriginalWord string    `json:"originalWord"`
+	Translation  string    `json:"translation"`
+	DeletedAt    time.Time `json:"deletedAt"`
+	PurgeAt      time.Time `json:"purgeAt"`
+}
+
+func DecksModelToTrash(m []models.Deck, retention time.Duration) []TrashItemDTO {
+	items := make([]TrashItemDTO, 0, len(m))
+
+	for _, value := range m {
+		items = append(items, TrashItemDTO{
+			Id:        value.Id,
+			Name:      value.Name,
+			DeletedAt: value.DeletedAt.Time,
+			PurgeAt:   value.DeletedAt.Time.Add(retention),
+		})
+	}
+	return items
+}
+
+func WordSetsModelToTrash(m []models.WordSet, retention time.Duration) []TrashItemDTO {
+	items := make([]TrashItemDTO, 0, len(m))
+
+	for _, value := range m {
+		items = append(items, TrashItemDTO{
+			Id:        value.Id,
+			Name:      value.Name,
+			DeletedAt: value.DeletedAt.Time,
+			PurgeAt:   value.DeletedAt.Time.Add(retention),
+		})
+	}
+	return items
+}
+
+func CardsModelToTrash(m []models.Card, retention time.Duration) []TrashCardDTO {
+	items := make([]TrashCardDTO, 0, len(m))
+
+	for _, value := range m {
+		items = append(items, TrashCardDTO{
+			Id:           value.Id,
+			OriginalWord: value.OriginalWord,
+			Translation:  value.Translation,
+			DeletedAt:    value.DeletedAt.Time,
+			PurgeAt:      value.DeletedAt.Time.Add(retention),
+		})
+	}
+	return items
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"dimplom_harmonic/internal/middleware"
+	"dimplom_harmonic/internal/trash"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TrashHandler struct {
+	service trash.TrashService
+}
+
+func NewTrashHandler(service trash.TrashService) *TrashHandler {
+	return &TrashHandler{service: service}
+}
+
+func (h *TrashHandler) HDGetTrash(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+
+	trashDTO, err := h.service.GetTrash(userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(trashDTO)
+}
+
+func (h *TrashHandler) HDRestoreDeck(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	deckId, err := strconv.Atoi(chi.URLParam(r, "deckID"))
+	if err != nil {
+		http.Error(w, "Invalid deck_id", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.RestoreDeck(userId, deckId)
+	if err != nil {
+		http.Error(w, "This deck not found in trash", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TrashHandler) HDRestoreWordSet(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	wordSetId, err := strconv.Atoi(chi.URLParam(r, "wordSetID"))
+	if err != nil {
+		http.Error(w, "Invalid word_set_id", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.RestoreWordSet(userId, wordSetId)
+	if err != nil {
+		http.Error(w, "This word set not found in trash", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TrashHandler) HDRestoreCard(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
+	cardId, err := strconv.Atoi(chi.URLParam(r, "cardID"))
+	if err != nil {
+		http.Error(w, "Invalid card_id", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.RestoreCard(userId, cardId)
+	if err != nil {
+		http.Error(w, "This card not found in trash", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"dimplom_harmonic/internal/card"
+	"dimplom_harmonic/internal/deck"
+	"dimplom_harmonic/internal/trash"
+	wordset "dimplom_harmonic/internal/wordSet"
+	"time"
+)
+
+type TrashService struct {
+	deckRepo    deck.DeckRepository
+	wordSetRepo wordset.WordSetRepository
+	cardRepo    card.CardRepository
+	retention   time.Duration
+}
+
+func NewTrashService(deckRepo deck.DeckRepository, wordSetRepo wordset.WordSetRepository, cardRepo card.CardRepository, retention time.Duration) *TrashService {
+	return &TrashService{
+		deckRepo:    deckRepo,
+		wordSetRepo: wordSetRepo,
+		cardRepo:    cardRepo,
+		retention:   retention,
+	}
+}
+
+func (s *TrashService) GetTrash(userId int) (*trash.TrashDTO, error) {
+
+	decks, err := s.deckRepo.GetDeleted(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	wordSets, err := s.wordSetRepo.GetDeleted(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	cards, err := s.cardRepo.GetDeleted(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	trashDTO := trash.TrashDTO{
+		Decks:    trash.DecksModelToTrash(decks, s.retention),
+		WordSets: trash.WordSetsModelToTrash(wordSets, s.retention),
+		Cards:    trash.CardsModelToTrash(cards, s.retention),
+	}
+
+	return &trashDTO, nil
+}
+
+func (s *TrashService) RestoreDeck(userId, deckId int) error {
+	return s.deckRepo.Restore(userId, deckId)
+}
+
+func (s *TrashService) RestoreWordSet(userId, wordSetId int) error {
+	return s.wordSetRepo.Restore(userId, wordSetId)
+}
+
+func (s *TrashService) RestoreCard(userId, cardId int) error {
+	return s.cardRepo.Restore(userId, cardId)
+}
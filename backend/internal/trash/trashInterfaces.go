@@ -0,0 +1,8 @@
+package trash
+
+type TrashService interface {
+	GetTrash(userId int) (*TrashDTO, error)
+	RestoreDeck(userId, deckId int) error
+	RestoreWordSet(userId, wordSetId int) error
+	RestoreCard(userId, cardId int) error
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	models "dimplom_harmonic/domain"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+type CreateWebhookDTO struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	Secret     string   `json:"secret"`
+}
+
+// UpdateWebhookDTO changes only the fields that are set. Turning IsActive on
+// again clears the failure counter.
+type UpdateWebhookDTO struct {
+	URL        *string  `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	Secret     *string  `json:"secret"`
+	IsActive   *bool    `json:"isActive"`
+}
+
+// WebhookDTO carries the secret only in the answer that set it.
+type WebhookDTO struct {
+	Id           int        `json:"id"`
+	URL          string     `json:"url"`
+	EventTypes   []string   `json:"eventTypes"`
+	Secret       string     `json:"secret,omitempty"`
+	IsActive     bool       `json:"isActive"`
+	FailureCount int        `json:"failureCount"`
+	DisabledAt   *time.Time `json:"disabledAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+func WebhookModelTo(m *models.Webhook) WebhookDTO {
+	return WebhookDTO{
+		Id:           m.Id,
+		URL:          m.URL,
+		EventTypes:   strings.Split(m.EventTypes, ","),
+		IsActive:     m.IsActive,
+		FailureCount: m.FailureCount,
+		DisabledAt:   m.DisabledAt,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+	}
+}
+
+type DeliveryDTO struct {
+	Id             int64           `json:"id"`
+	EventId        int64           `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"responseStatus"`
+	ResponseBody   *string         `json:"responseBody"`
+	LastError      *string         `json:"lastError"`
+	DurationMs     *int            `json:"durationMs"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+func DeliveryModelTo(m *models.WebhookDelivery) DeliveryDTO {
+	delivery := DeliveryDTO{
+		Id:             m.Id,
+		EventId:        m.EventId,
+		EventType:      m.EventType,
+		Payload:        json.RawMessage(m.Payload),
+		Status:         m.Status,
+		Attempts:       m.Attempts,
+		ResponseStatus: m.ResponseStatus,
+		ResponseBody:   m.ResponseBody,
+		LastError:      m.LastError,
+		DurationMs:     m.DurationMs,
+		DeliveredAt:    m.DeliveredAt,
+		CreatedAt:      m.CreatedAt,
+	}
+	if m.Status == models.DeliveryPending {
+		delivery.NextAttemptAt = &m.NextAttemptAt
+	}
+	return delivery
+}
+
+type DeliveryPageDTO struct {
+	Deliveries []DeliveryDTO `json:"deliveries"`
+	Total      int64         `json:"total"`
+	Page       int           `json:"page"`
+}
+
+// Payload is the body posted to the webhook. Id is the same for every retry
+// of an event, so receivers can drop repeats.
+type Payload struct {
+	Id        int64     `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
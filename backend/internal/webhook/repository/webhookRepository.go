@@ -0,0 +1,169 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/webhook"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) WithTx(tx *gorm.DB) webhook.WebhookRepository {
+	return &WebhookRepository{db: tx}
+}
+
+func (r *WebhookRepository) CreateWebhook(webhook *models.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+func (r *WebhookRepository) GetWebhooks(userId int) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.Where("user_id = ?", userId).Order("id").Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) GetWebhook(userId, webhookId int) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.Where("id = ? AND user_id = ?", webhookId, userId).First(&webhook).Error
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) CountWebhooks(userId int) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Webhook{}).Where("user_id = ?", userId).Count(&count).Error
+	return count, err
+}
+
+func (r *WebhookRepository) UpdateWebhook(webhookId int, changes map[string]any) error {
+	return r.db.Model(&models.Webhook{}).Where("id = ?", webhookId).Updates(changes).Error
+}
+
+func (r *WebhookRepository) DeleteWebhook(webhookId int) error {
+	return r.db.Delete(&models.Webhook{}, webhookId).Error
+}
+
+func (r *WebhookRepository) GetSubscribed(userId int, eventType string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+
+	query := `
+		SELECT * FROM webhooks
+		WHERE user_id = ? AND is_active AND ? = ANY(string_to_array(event_types, ','))
+	`
+	err := r.db.Raw(query, userId, eventType).Scan(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) AddDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Select("WebhookId", "EventId", "EventType", "Payload").
+		Create(&deliveries).Error
+}
+
+func (r *WebhookRepository) GetDeliveries(webhookId, limit, offset int) ([]models.WebhookDelivery, int64, error) {
+	var deliveries []models.WebhookDelivery
+	var total int64
+
+	err := r.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookId).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = r.db.
+		Where("webhook_id = ?", webhookId).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+func (r *WebhookRepository) ClaimDue(limit int, lease time.Duration) ([]webhook.DeliveryTarget, error) {
+	var targets []webhook.DeliveryTarget
+
+	query := `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.is_active
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT ?
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = ?
+		FROM due, webhooks w
+		WHERE d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.*, w.url, w.secret
+	`
+	err := r.db.Raw(query, limit, time.Now().Add(lease)).Scan(&targets).Error
+	if err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r *WebhookRepository) SaveAttempt(delivery *models.WebhookDelivery) error {
+	return r.db.Model(delivery).Select(
+		"Status", "Attempts", "ResponseStatus", "ResponseBody", "LastError",
+		"DurationMs", "NextAttemptAt", "DeliveredAt",
+	).Updates(delivery).Error
+}
+
+func (r *WebhookRepository) ResetFailures(webhookId int) error {
+	return r.db.Exec(`UPDATE webhooks SET failure_count = 0 WHERE id = ? AND failure_count <> 0`, webhookId).Error
+}
+
+func (r *WebhookRepository) RecordFailure(webhookId, disableAfter int) (bool, error) {
+	var disabled []bool
+
+	query := `
+		UPDATE webhooks
+		SET 
+			failure_count = failure_count + 1,
+			is_active = failure_count + 1 < ?,
+			disabled_at = CASE WHEN failure_count + 1 >= ? THEN NOW() ELSE disabled_at END,
+			updated_at = NOW()
+		WHERE id = ? AND is_active
+		RETURNING NOT is_active
+	`
+	err := r.db.Raw(query, disableAfter, disableAfter, webhookId).Scan(&disabled).Error
+	if err != nil {
+		return false, err
+	}
+	return len(disabled) == 1 && disabled[0], nil
+}
+
+func (r *WebhookRepository) FailPending(webhookId int, reason string) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'failed', last_error = ?
+		WHERE webhook_id = ? AND status = 'pending'
+	`
+	return r.db.Exec(query, reason, webhookId).Error
+}
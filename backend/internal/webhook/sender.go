@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"dimplom_harmonic/internal/safehttp"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sendTimeout     = 10 * time.Second
+	maxResponseBody = 1024
+)
+
+type HTTPSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender posts deliveries over HTTP. Unless allowPrivate is set, it
+// refuses to connect to private addresses. Redirects are not followed: a 3xx
+// answer is a failed attempt, and the user should set the final URL.
+func NewHTTPSender(allowPrivate bool) *HTTPSender {
+	return &HTTPSender{client: safehttp.NewClient(sendTimeout, allowPrivate)}
+}
+
+func (s *HTTPSender) Send(ctx context.Context, target DeliveryTarget) Attempt {
+	body := []byte(target.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return Attempt{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Memofold-Webhooks/1.0")
+	req.Header.Set(EventHeader, target.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(target.Id, 10))
+	req.Header.Set(SignatureHeader, Sign(target.Secret, time.Now(), body))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Attempt{Duration: time.Since(start), Err: err}
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	attempt := Attempt{
+		StatusCode: resp.StatusCode,
+		Body:       responseText(respBody),
+		Duration:   time.Since(start),
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Err = fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// responseText makes the receiver's body safe to store in a TEXT column: the
+// cut at maxResponseBody may split a rune, and Postgres rejects NUL bytes.
+func responseText(body []byte) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(string(body), ""), "\x00", "")
+}
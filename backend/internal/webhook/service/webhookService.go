@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/events"
+	"dimplom_harmonic/internal/webhook"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+type WebhookService struct {
+	webhookRepo webhook.WebhookRepository
+}
+
+func NewWebhookService(webhookRepo webhook.WebhookRepository) *WebhookService {
+	return &WebhookService{webhookRepo: webhookRepo}
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func validateURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if len(rawURL) > webhook.MaxURLLength {
+		return "", webhook.ErrInvalidURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" || parsed.User != nil {
+		return "", webhook.ErrInvalidURL
+	}
+	return parsed.String(), nil
+}
+
+// joinEventTypes checks and dedupes the event types, keeping the order of
+// webhook.EventTypes.
+func joinEventTypes(eventTypes []string) (string, error) {
+	if len(eventTypes) == 0 {
+		return "", webhook.ErrInvalidEventType
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(webhook.EventTypes, eventType) {
+			return "", webhook.ErrInvalidEventType
+		}
+	}
+
+	var joined []string
+	for _, eventType := range webhook.EventTypes {
+		if slices.Contains(eventTypes, eventType) {
+			joined = append(joined, eventType)
+		}
+	}
+	return strings.Join(joined, ","), nil
+}
+
+func (s *WebhookService) CreateWebhook(userId int, input webhook.CreateWebhookDTO) (*webhook.WebhookDTO, error) {
+	webhookURL, err := validateURL(input.URL)
+	if err != nil {
+		return nil, err
+	}
+	eventTypes, err := joinEventTypes(input.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		secret, err = generateSecret()
+		if err != nil {
+			return nil, err
+		}
+	} else if len(secret) < webhook.MinSecretLength || len(secret) > webhook.MaxSecretLength {
+		return nil, webhook.ErrInvalidSecret
+	}
+
+	count, err := s.webhookRepo.CountWebhooks(userId)
+	if err != nil {
+		return nil, err
+	}
+	if count >= webhook.MaxWebhooks {
+		return nil, webhook.ErrTooManyWebhooks
+	}
+
+	now := time.Now()
+	newWebhook := models.Webhook{
+		UserId:     userId,
+		URL:        webhookURL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		IsActive:   true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.webhookRepo.CreateWebhook(&newWebhook); err != nil {
+		return nil, err
+	}
+
+	result := webhook.WebhookModelTo(&newWebhook)
+	result.Secret = secret
+	return &result, nil
+}
+
+func (s *WebhookService) GetWebhooks(userId int) ([]webhook.WebhookDTO, error) {
+	webhooks, err := s.webhookRepo.GetWebhooks(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]webhook.WebhookDTO, 0, len(webhooks))
+	for i := range webhooks {
+		result = append(result, webhook.WebhookModelTo(&webhooks[i]))
+	}
+	return result, nil
+}
+
+func (s *WebhookService) UpdateWebhook(userId, webhookId int, input webhook.UpdateWebhookDTO) (*webhook.WebhookDTO, error) {
+	current, err := s.webhookRepo.GetWebhook(userId, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]any{"UpdatedAt": time.Now()}
+
+	if input.URL != nil {
+		webhookURL, err := validateURL(*input.URL)
+		if err != nil {
+			return nil, err
+		}
+		changes["URL"] = webhookURL
+	}
+	if input.EventTypes != nil {
+		eventTypes, err := joinEventTypes(input.EventTypes)
+		if err != nil {
+			return nil, err
+		}
+		changes["EventTypes"] = eventTypes
+	}
+	if input.Secret != nil {
+		if len(*input.Secret) < webhook.MinSecretLength || len(*input.Secret) > webhook.MaxSecretLength {
+			return nil, webhook.ErrInvalidSecret
+		}
+		changes["Secret"] = *input.Secret
+	}
+	if input.IsActive != nil && *input.IsActive != current.IsActive {
+		changes["IsActive"] = *input.IsActive
+		if *input.IsActive {
+			changes["FailureCount"] = 0
+			changes["DisabledAt"] = nil
+		}
+	}
+
+	if err := s.webhookRepo.UpdateWebhook(webhookId, changes); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.webhookRepo.GetWebhook(userId, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := webhook.WebhookModelTo(updated)
+	if input.Secret != nil {
+		result.Secret = updated.Secret
+	}
+	return &result, nil
+}
+
+func (s *WebhookService) DeleteWebhook(userId, webhookId int) error {
+	if _, err := s.webhookRepo.GetWebhook(userId, webhookId); err != nil {
+		return err
+	}
+	return s.webhookRepo.DeleteWebhook(webhookId)
+}
+
+func (s *WebhookService) GetDeliveries(userId, webhookId, page, pageSize int) (*webhook.DeliveryPageDTO, error) {
+	if _, err := s.webhookRepo.GetWebhook(userId, webhookId); err != nil {
+		return nil, err
+	}
+
+	deliveries, total, err := s.webhookRepo.GetDeliveries(webhookId, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]webhook.DeliveryDTO, 0, len(deliveries))
+	for i := range deliveries {
+		result = append(result, webhook.DeliveryModelTo(&deliveries[i]))
+	}
+	return &webhook.DeliveryPageDTO{Deliveries: result, Total: total, Page: page}, nil
+}
+
+func (s *WebhookService) Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, "webhooks", func(ctx context.Context, e events.DeckReviewed) error {
+		return s.enqueue(ctx, e.UserId, e)
+	})
+	events.Subscribe(bus, "webhooks", func(ctx context.Context, e events.DeckArchived) error {
+		return s.enqueue(ctx, e.UserId, e)
+	})
+	events.Subscribe(bus, "webhooks", func(ctx context.Context, e events.CardCreated) error {
+		return s.enqueue(ctx, e.UserId, e)
+	})
+}
+
+// enqueue adds a delivery of the event for each webhook of the user that
+// listens to it. A repeated event is skipped by its outbox id.
+func (s *WebhookService) enqueue(ctx context.Context, userId int, event events.Event) error {
+	eventId := events.EventId(ctx)
+	if eventId == 0 {
+		return errors.New("event has no outbox id")
+	}
+
+	webhooks, err := s.webhookRepo.GetSubscribed(userId, event.EventType())
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(webhook.Payload{
+		Id:        eventId,
+		Type:      event.EventType(),
+		CreatedAt: time.Now(),
+		Data:      event,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(webhooks))
+	for _, w := range webhooks {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookId: w.Id,
+			EventId:   eventId,
+			EventType: event.EventType(),
+			Payload:   string(payload),
+		})
+	}
+	return s.webhookRepo.AddDeliveries(deliveries)
+}
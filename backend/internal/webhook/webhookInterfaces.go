@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"context"
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/events"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookService interface {
+	CreateWebhook(userId int, input CreateWebhookDTO) (*WebhookDTO, error)
+	GetWebhooks(userId int) ([]WebhookDTO, error)
+	UpdateWebhook(userId, webhookId int, input UpdateWebhookDTO) (*WebhookDTO, error)
+	DeleteWebhook(userId, webhookId int) error
+	GetDeliveries(userId, webhookId, page, pageSize int) (*DeliveryPageDTO, error)
+	// Subscribe enqueues deliveries for the domain events webhooks can
+	// listen to.
+	Subscribe(bus *events.Bus)
+}
+
+type WebhookRepository interface {
+	CreateWebhook(webhook *models.Webhook) error
+	GetWebhooks(userId int) ([]models.Webhook, error)
+	GetWebhook(userId, webhookId int) (*models.Webhook, error)
+	CountWebhooks(userId int) (int64, error)
+	UpdateWebhook(webhookId int, changes map[string]any) error
+	DeleteWebhook(webhookId int) error
+	GetSubscribed(userId int, eventType string) ([]models.Webhook, error)
+
+	// AddDeliveries skips deliveries of an event the webhook already has.
+	AddDeliveries(deliveries []models.WebhookDelivery) error
+	GetDeliveries(webhookId, limit, offset int) ([]models.WebhookDelivery, int64, error)
+	// ClaimDue takes up to limit due deliveries of active webhooks and pushes
+	// their next attempt lease into the future, so other workers skip them
+	// while they are being sent.
+	ClaimDue(limit int, lease time.Duration) ([]DeliveryTarget, error)
+	SaveAttempt(delivery *models.WebhookDelivery) error
+	ResetFailures(webhookId int) error
+	// RecordFailure counts a failed attempt and disables the webhook once
+	// disableAfter attempts in a row have failed. It reports whether the
+	// webhook was disabled by this call.
+	RecordFailure(webhookId, disableAfter int) (bool, error)
+	// FailPending gives up the pending deliveries of a webhook.
+	FailPending(webhookId int, reason string) error
+
+	WithTx(tx *gorm.DB) WebhookRepository
+}
+
+type DeliveryTarget struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// Sender posts one delivery to its webhook.
+type Sender interface {
+	Send(ctx context.Context, target DeliveryTarget) Attempt
+}
+
+// Attempt is the outcome of one delivery attempt. Err is set for network
+// errors and non-2xx answers.
+type Attempt struct {
+	StatusCode int
+	Body       string
+	Duration   time.Duration
+	Err        error
+}
+
+// EventTypes are the domain events a webhook can subscribe to.
+var EventTypes = []string{
+	events.TypeDeckReviewed,
+	events.TypeDeckArchived,
+	events.TypeCardCreated,
+}
+
+const (
+	MaxWebhooks     = 10
+	MinSecretLength = 16
+	MaxSecretLength = 255
+	MaxURLLength    = 2048
+
+	// MaxAttempts is how many times one delivery is tried before it fails.
+	MaxAttempts = 8
+	// DisableAfterFailures failed attempts in a row, across deliveries,
+	// disable the webhook.
+	DisableAfterFailures = 20
+
+	SignatureHeader = "X-Memofold-Signature"
+	EventHeader     = "X-Memofold-Event"
+	DeliveryHeader  = "X-Memofold-Delivery"
+)
+
+var (
+	ErrInvalidURL       = errors.New("invalid_webhook_url")
+	ErrInvalidEventType = errors.New("invalid_event_type")
+	ErrInvalidSecret    = errors.New("webhook_secret_too_short")
+	ErrTooManyWebhooks  = errors.New("too_many_webhooks")
+	ErrInvalidSignature = errors.New("invalid_signature")
+)
@@ -0,0 +1,43 @@
+package webpush
+
+import (
+	models "dimplom_harmonic/domain"
+	"time"
+)
+
+// SubscribeDTO is PushSubscription.toJSON() from the browser.
+type SubscribeDTO struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+type SubscriptionDTO struct {
+	Id         int        `json:"id"`
+	UserAgent  string     `json:"userAgent"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+func SubscriptionModelTo(m *models.PushSubscription) SubscriptionDTO {
+	return SubscriptionDTO{
+		Id:         m.Id,
+		UserAgent:  m.UserAgent,
+		CreatedAt:  m.CreatedAt,
+		LastUsedAt: m.LastUsedAt,
+	}
+}
+
+type PublicKeyDTO struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// Payload is what the service worker gets in the push event.
+type Payload struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"`
+	Tag   string `json:"tag,omitempty"`
+}
@@ -0,0 +1,137 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// recordSize is the rs of the single aes128gcm record. Push services
+	// accept bodies of 4096 bytes, which leaves MaxPayload for the message.
+	recordSize = 4096
+	headerSize = 16 + 4 + 1 + 65
+	MaxPayload = recordSize - headerSize - 16 - 1
+)
+
+var ErrPayloadTooLarge = errors.New("push payload too large")
+
+// Encrypt encrypts payload for a subscription as RFC 8291 describes: an
+// ECDH secret between a one-time key and the browser's p256dh key, mixed
+// with its auth secret, keys a single aes128gcm record (RFC 8188). The
+// one-time public key travels in the record header.
+func Encrypt(payload, p256dh, authSecret []byte) ([]byte, error) {
+	if len(payload) > MaxPayload {
+		return nil, ErrPayloadTooLarge
+	}
+
+	uaPublic, err := ecdh.P256().NewPublicKey(p256dh)
+	if err != nil {
+		return nil, err
+	}
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	cek, nonce, err := deriveKeys(asPrivate, uaPublic, uaPublic.Bytes(), asPrivate.PublicKey().Bytes(), authSecret, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	// One record, so the payload ends with the last record delimiter 0x02.
+	plaintext := append(bytes.Clone(payload), 0x02)
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, 65)
+	header = append(header, asPrivate.PublicKey().Bytes()...)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// Decrypt is the browser's side of Encrypt. The server doesn't need it; it
+// lets a stand-in push service like cmd/mockpush read what was sent.
+func Decrypt(body []byte, uaPrivate *ecdh.PrivateKey, authSecret []byte) ([]byte, error) {
+	if len(body) < headerSize+16 || body[20] != 65 {
+		return nil, errors.New("invalid aes128gcm header")
+	}
+	salt, keyId, ciphertext := body[:16], body[21:headerSize], body[headerSize:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, nonce, err := deriveKeys(uaPrivate, asPublic, uaPrivate.PublicKey().Bytes(), keyId, authSecret, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext = bytes.TrimRight(plaintext, "\x00")
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		return nil, errors.New("invalid record padding")
+	}
+	return plaintext[:len(plaintext)-1], nil
+}
+
+func deriveKeys(private *ecdh.PrivateKey, peer *ecdh.PublicKey, uaPublic, asPublic, authSecret, salt []byte) ([]byte, []byte, error) {
+	ecdhSecret, err := private.ECDH(peer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cek, nonce, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
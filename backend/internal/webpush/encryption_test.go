@@ -0,0 +1,79 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// The example from RFC 8291, Appendix A.
+const (
+	rfcPlaintext = "When I grow up, I want to be a watermelon"
+	rfcASPrivate = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfcUAPrivate = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfcUAPublic  = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfcSalt      = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfcAuth      = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfcBody      = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func decodeB64(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode %q: %v", s, err)
+	}
+	return b
+}
+
+func TestDecryptRFC8291Example(t *testing.T) {
+	uaPrivate, err := ecdh.P256().NewPrivateKey(decodeB64(t, rfcUAPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(uaPrivate.PublicKey().Bytes(), decodeB64(t, rfcUAPublic)) {
+		t.Fatal("user agent public key doesn't match its private key")
+	}
+
+	plaintext, err := Decrypt(decodeB64(t, rfcBody), uaPrivate, decodeB64(t, rfcAuth))
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if string(plaintext) != rfcPlaintext {
+		t.Fatalf("Decrypt = %q, want %q", plaintext, rfcPlaintext)
+	}
+}
+
+// Encrypt picks a random key and salt, so the sender's side is checked by
+// building the record from the example's key and salt the way Encrypt does.
+func TestEncryptKeysRFC8291Example(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(decodeB64(t, rfcASPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(decodeB64(t, rfcUAPublic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := decodeB64(t, rfcSalt)
+
+	cek, nonce, err := deriveKeys(asPrivate, uaPublic, uaPublic.Bytes(), asPrivate.PublicKey().Bytes(), decodeB64(t, rfcAuth), salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := newGCM(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := append(bytes.Clone(salt), binary.BigEndian.AppendUint32(nil, recordSize)...)
+	header = append(header, 65)
+	header = append(header, asPrivate.PublicKey().Bytes()...)
+	body := gcm.Seal(header, nonce, append([]byte(rfcPlaintext), 0x02), nil)
+
+	if want := decodeB64(t, rfcBody); !bytes.Equal(body, want) {
+		t.Fatalf("body = %x, want %x", body, want)
+	}
+}
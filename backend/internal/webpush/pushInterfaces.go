@@ -0,0 +1,42 @@
+package webpush
+
+import (
+	models "dimplom_harmonic/domain"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type PushService interface {
+	GetPublicKey() (string, error)
+	Subscribe(userId int, input SubscribeDTO, userAgent string) (*SubscriptionDTO, error)
+	GetSubscriptions(userId int) ([]SubscriptionDTO, error)
+	Unsubscribe(userId, subscriptionId int) error
+}
+
+type PushRepository interface {
+	// SaveSubscription keys on the endpoint: subscribing the same browser
+	// again updates its keys and owner.
+	SaveSubscription(subscription *models.PushSubscription) error
+	GetSubscriptions(userId int) ([]models.PushSubscription, error)
+	DeleteSubscription(userId, subscriptionId int) (bool, error)
+	DeleteByEndpoint(endpoint string) error
+	MarkUsed(subscriptionId int) error
+
+	WithTx(tx *gorm.DB) PushRepository
+}
+
+const (
+	MaxSubscriptions  = 20
+	MaxEndpointLength = 2048
+)
+
+var (
+	ErrPushDisabled         = errors.New("push_not_configured")
+	ErrInvalidEndpoint      = errors.New("invalid_push_endpoint")
+	ErrInvalidKeys          = errors.New("invalid_push_keys")
+	ErrTooManySubscriptions = errors.New("too_many_push_subscriptions")
+	// ErrSubscriptionGone means the push service no longer knows the
+	// subscription (404 or 410); it should be deleted.
+	ErrSubscriptionGone = errors.New("push subscription gone")
+)
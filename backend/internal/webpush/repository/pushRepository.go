@@ -0,0 +1,50 @@
+package repository
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/webpush"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PushRepository struct {
+	db *gorm.DB
+}
+
+func NewPushRepository(db *gorm.DB) *PushRepository {
+	return &PushRepository{db: db}
+}
+
+func (r *PushRepository) WithTx(tx *gorm.DB) webpush.PushRepository {
+	return &PushRepository{db: tx}
+}
+
+func (r *PushRepository) SaveSubscription(subscription *models.PushSubscription) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "endpoint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "p256dh", "auth", "user_agent"}),
+	}, clause.Returning{}).Omit("LastUsedAt").Create(subscription).Error
+}
+
+func (r *PushRepository) GetSubscriptions(userId int) ([]models.PushSubscription, error) {
+	var subscriptions []models.PushSubscription
+	err := r.db.Where("user_id = ?", userId).Order("id").Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *PushRepository) DeleteSubscription(userId, subscriptionId int) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", subscriptionId, userId).Delete(&models.PushSubscription{})
+	return result.RowsAffected != 0, result.Error
+}
+
+func (r *PushRepository) DeleteByEndpoint(endpoint string) error {
+	return r.db.Where("endpoint = ?", endpoint).Delete(&models.PushSubscription{}).Error
+}
+
+func (r *PushRepository) MarkUsed(subscriptionId int) error {
+	return r.db.Model(&models.PushSubscription{}).Where("id = ?", subscriptionId).Update("last_used_at", gorm.Expr("NOW()")).Error
+}
@@ -0,0 +1,86 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/safehttp"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	sendTimeout = 10 * time.Second
+	// messageTTL is how long the push service keeps a message for an
+	// offline device; a reminder is stale after a day.
+	messageTTL = 24 * time.Hour
+)
+
+type Sender struct {
+	vapid  *VAPID
+	client *http.Client
+}
+
+// NewSender sends through the browsers' push services. Endpoints on private
+// addresses are refused unless allowPrivate is set, as for webhooks.
+func NewSender(vapid *VAPID, allowPrivate bool) *Sender {
+	return &Sender{vapid: vapid, client: safehttp.NewClient(sendTimeout, allowPrivate)}
+}
+
+// Send encrypts payload for the subscription and posts it to its push
+// service. It returns ErrSubscriptionGone when the service answers 404 or
+// 410.
+func (s *Sender) Send(ctx context.Context, subscription models.PushSubscription, payload []byte, topic string) error {
+	p256dh, err := base64.RawURLEncoding.DecodeString(subscription.P256dh)
+	if err != nil {
+		return ErrInvalidKeys
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(subscription.Auth)
+	if err != nil {
+		return ErrInvalidKeys
+	}
+
+	body, err := Encrypt(payload, p256dh, authSecret)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := s.vapid.Authorization(subscription.Endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(messageTTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+	if topic != "" {
+		// A newer message with the same topic replaces an undelivered one.
+		req.Header.Set("Topic", topic)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	default:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push service answered %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/webpush"
+	"encoding/base64"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PushService struct {
+	pushRepo      webpush.PushRepository
+	vapid         *webpush.VAPID
+	allowInsecure bool
+}
+
+// NewPushService takes a nil vapid when push isn't configured. With
+// allowInsecure, plain http endpoints are accepted, for a local push service
+// stand-in.
+func NewPushService(pushRepo webpush.PushRepository, vapid *webpush.VAPID, allowInsecure bool) *PushService {
+	return &PushService{pushRepo: pushRepo, vapid: vapid, allowInsecure: allowInsecure}
+}
+
+func (s *PushService) GetPublicKey() (string, error) {
+	if s.vapid == nil {
+		return "", webpush.ErrPushDisabled
+	}
+	return s.vapid.PublicKey(), nil
+}
+
+func (s *PushService) validateEndpoint(endpoint string) error {
+	if len(endpoint) > webpush.MaxEndpointLength {
+		return webpush.ErrInvalidEndpoint
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" || parsed.User != nil {
+		return webpush.ErrInvalidEndpoint
+	}
+	if parsed.Scheme != "https" && !(s.allowInsecure && parsed.Scheme == "http") {
+		return webpush.ErrInvalidEndpoint
+	}
+	return nil
+}
+
+func (s *PushService) Subscribe(userId int, input webpush.SubscribeDTO, userAgent string) (*webpush.SubscriptionDTO, error) {
+	if s.vapid == nil {
+		return nil, webpush.ErrPushDisabled
+	}
+	if err := s.validateEndpoint(input.Endpoint); err != nil {
+		return nil, err
+	}
+
+	// Some clients pad the keys; they are stored without padding.
+	input.Keys.P256dh = strings.TrimRight(input.Keys.P256dh, "=")
+	input.Keys.Auth = strings.TrimRight(input.Keys.Auth, "=")
+
+	p256dh, err := base64.RawURLEncoding.DecodeString(input.Keys.P256dh)
+	if err != nil || len(p256dh) != 65 || p256dh[0] != 4 {
+		return nil, webpush.ErrInvalidKeys
+	}
+	auth, err := base64.RawURLEncoding.DecodeString(input.Keys.Auth)
+	if err != nil || len(auth) != 16 {
+		return nil, webpush.ErrInvalidKeys
+	}
+
+	subscriptions, err := s.pushRepo.GetSubscriptions(userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(subscriptions) >= webpush.MaxSubscriptions && !slices.ContainsFunc(subscriptions, func(sub models.PushSubscription) bool {
+		return sub.Endpoint == input.Endpoint
+	}) {
+		return nil, webpush.ErrTooManySubscriptions
+	}
+
+	userAgent = strings.ToValidUTF8(userAgent, "")
+	if runes := []rune(userAgent); len(runes) > 255 {
+		userAgent = string(runes[:255])
+	}
+
+	subscription := models.PushSubscription{
+		UserId:    userId,
+		Endpoint:  input.Endpoint,
+		P256dh:    input.Keys.P256dh,
+		Auth:      input.Keys.Auth,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	}
+	if err := s.pushRepo.SaveSubscription(&subscription); err != nil {
+		return nil, err
+	}
+
+	result := webpush.SubscriptionModelTo(&subscription)
+	return &result, nil
+}
+
+func (s *PushService) GetSubscriptions(userId int) ([]webpush.SubscriptionDTO, error) {
+	subscriptions, err := s.pushRepo.GetSubscriptions(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]webpush.SubscriptionDTO, 0, len(subscriptions))
+	for i := range subscriptions {
+		result = append(result, webpush.SubscriptionModelTo(&subscriptions[i]))
+	}
+	return result, nil
+}
+
+func (s *PushService) Unsubscribe(userId, subscriptionId int) error {
+	deleted, err := s.pushRepo.DeleteSubscription(userId, subscriptionId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// vapidTTL is how long the VAPID token of one request is valid; RFC 8292
+// allows at most 24 hours.
+const vapidTTL = 12 * time.Hour
+
+// VAPID identifies this server to push services (RFC 8292). Browsers bind a
+// subscription to PublicKey, so changing the key drops every subscription.
+type VAPID struct {
+	key     *ecdsa.PrivateKey
+	subject string
+}
+
+// LoadVAPID reads a P-256 private key in PEM (SEC 1 or PKCS #8), as made by
+// `openssl ecparam -name prime256v1 -genkey -noout -out vapid.pem`. The
+// subject is a mailto: or https: contact for the push service operators.
+func LoadVAPID(keyFile, subject string) (*VAPID, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", keyFile)
+	}
+
+	var key any
+	if block.Type == "EC PRIVATE KEY" {
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", keyFile, err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%s: VAPID key must be a P-256 EC key", keyFile)
+	}
+	if subject == "" {
+		return nil, errors.New("VAPID subject is required")
+	}
+	return &VAPID{key: ecKey, subject: subject}, nil
+}
+
+// PublicKey is the applicationServerKey browsers subscribe with: the
+// uncompressed point, base64url without padding.
+func (v *VAPID) PublicKey() string {
+	public, err := v.key.PublicKey.Bytes()
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(public)
+}
+
+// Authorization returns the Authorization header for a request to endpoint.
+func (v *VAPID) Authorization(endpoint string, now time.Time) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": parsed.Scheme + "://" + parsed.Host,
+		"exp": now.Add(vapidTTL).Unix(),
+		"sub": v.subject,
+	}).SignedString(v.key)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + token + ", k=" + v.PublicKey(), nil
+}
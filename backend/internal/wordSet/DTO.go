@@ -3,12 +3,14 @@ package wordset
 import (
 	models "dimplom_harmonic/domain"
 	"dimplom_harmonic/internal/card"
+	"time"
 )
 
 type WordSetDTO struct {
 	Id       int    `json:"id"`
 	Name     string `json:"name"`
 	IsPublic bool   `json:"isPublic"`
+	Version  int    `json:"version"`
 }
 
 type WordSetByIdDTO struct {
@@ -18,6 +20,8 @@ type WordSetByIdDTO struct {
 	IsPublic  bool   `json:"isPublic"`
 	IsDefault bool   `json:"isDefault"`
 	UserName  string `json:"userName"`
+	Role      string `json:"role,omitempty"`
+	Version   int    `json:"version"`
 
 	Cards []card.UpdateCardDTO `json:"cards"`
 }
@@ -27,6 +31,7 @@ func WordSetModelTo(m *models.WordSet) WordSetDTO {
 		Id:       m.Id,
 		Name:     m.Name,
 		IsPublic: m.IsPublic,
+		Version:  m.Version,
 	}
 }
 
@@ -39,6 +44,7 @@ func WordSetGetModelTo(m *models.WordSet) WordSetByIdDTO {
 		Name:      m.Name,
 		IsPublic:  m.IsPublic,
 		IsDefault: m.IsDefault,
+		Version:   m.Version,
 	}
 
 	cards := card.GetCardsModelTo(m.Cards)
@@ -57,6 +63,8 @@ func WordSetGetByIdModelTo(m *WordSetGetResponseByIdDTO) WordSetByIdDTO {
 		IsPublic:  m.IsPublic,
 		IsDefault: m.IsDefault,
 		UserName:  m.UserName,
+		Version:   m.Version,
+		Role:      m.Role,
 	}
 
 	cards := card.GetCardsModelTo(m.Cards)
@@ -64,3 +72,71 @@ func WordSetGetByIdModelTo(m *WordSetGetResponseByIdDTO) WordSetByIdDTO {
 	WS.Cards = cards
 	return WS
 }
+
+type InviteMemberDTO struct {
+	User string `json:"user"`
+	Role string `json:"role"`
+}
+
+type UpdateMemberDTO struct {
+	Role string `json:"role"`
+}
+
+type MemberDTO struct {
+	UserId   int        `json:"userId"`
+	Login    string     `json:"login"`
+	Role     string     `json:"role"`
+	Pending  bool       `json:"pending"`
+	JoinedAt *time.Time `json:"joinedAt"`
+}
+
+func MemberResultTo(m *MemberResult) MemberDTO {
+	return MemberDTO{
+		UserId:   m.UserId,
+		Login:    m.Login,
+		Role:     m.Role,
+		Pending:  m.AcceptedAt == nil,
+		JoinedAt: m.AcceptedAt,
+	}
+}
+
+type InvitationDTO struct {
+	WordSetId   int       `json:"wordSetId"`
+	WordSetName string    `json:"wordSetName"`
+	OwnerName   string    `json:"ownerName"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ActivityDTO struct {
+	Id        int       `json:"id"`
+	UserId    *int      `json:"userId"`
+	UserName  string    `json:"userName"`
+	Action    string    `json:"action"`
+	CardId    *int      `json:"cardId"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ActivityPageDTO struct {
+	Entries []ActivityDTO `json:"entries"`
+	Total   int64         `json:"total"`
+	Page    int           `json:"page"`
+}
+
+func ActivityResultTo(r []ActivityResult) []ActivityDTO {
+	entries := make([]ActivityDTO, 0, len(r))
+
+	for _, value := range r {
+		entries = append(entries, ActivityDTO{
+			Id:        value.Id,
+			UserId:    value.UserId,
+			UserName:  value.UserName,
+			Action:    value.Action,
+			CardId:    value.CardId,
+			Details:   value.Details,
+			CreatedAt: value.CreatedAt,
+		})
+	}
+	return entries
+}
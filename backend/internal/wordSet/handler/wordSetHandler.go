@@ -2,13 +2,16 @@ package handler
 
 import (
 	"dimplom_harmonic/internal/card"
+	"dimplom_harmonic/internal/concurrency"
 	"dimplom_harmonic/internal/middleware"
 	wordset "dimplom_harmonic/internal/wordSet"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
 type WordSetHandler struct {
@@ -19,6 +22,19 @@ func NewWordSetHandler(service wordset.WordSetService) *WordSetHandler {
 	return &WordSetHandler{service: service}
 }
 
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, wordset.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, wordset.ErrAlreadyMember):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
 func (h *WordSetHandler) HDCreateWordSet(w http.ResponseWriter, r *http.Request) {
 	userId := r.Context().Value(middleware.UserIDKey).(int)
 	var input wordset.WordSetDTO
@@ -70,9 +86,14 @@ func (h *WordSetHandler) HDGetWordSetById(w http.ResponseWriter, r *http.Request
 
 	wordSetM, err := h.service.GetWordSetByID(userId, wordSetId)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
+	if concurrency.NotModified(w, r, wordSetM.Version) {
+		return
+	}
+
+	concurrency.SetETag(w, wordSetM.Version)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -80,6 +101,7 @@ func (h *WordSetHandler) HDGetWordSetById(w http.ResponseWriter, r *http.Request
 }
 
 func (h *WordSetHandler) HDUpdateWordSet(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(int)
 	var input wordset.WordSetDTO
 	wordSetIdStr := chi.URLParam(r, "wordSetID")
 	wordSetId, err := strconv.Atoi(wordSetIdStr)
@@ -93,11 +115,23 @@ func (h *WordSetHandler) HDUpdateWordSet(w http.ResponseWriter, r *http.Request)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	wordSetUpdated, err := h.service.UpdateWordSet(wordSetId, input.Name, input.IsPublic)
+
+	version, fromHeader, err := concurrency.ExpectedVersion(r, input.Version)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+
+	wordSetUpdated, err := h.service.UpdateWordSet(userId, wordSetId, version, input.Name, input.IsPublic)
+	if errors.Is(err, concurrency.ErrVersionConflict) {
+		concurrency.WriteConflict(w, fromHeader)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	concurrency.SetETag(w, wordSetUpdated.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(wordSetUpdated)
@@ -115,7 +149,7 @@ func (h *WordSetHandler) HDDeleteWordSet(w http.ResponseWriter, r *http.Request)
 
 	err = h.service.DeleteWordSet(userId, wordSetId)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -133,7 +167,7 @@ func (h *WordSetHandler) HDCopyWordSet(w http.ResponseWriter, r *http.Request) {
 	}
 	wordSetC, err := h.service.CopyWordSet(wordSetId, userId)
 	if err != nil {
-		http.Error(w, "Internal error", http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
@@ -159,7 +193,11 @@ func (h *WordSetHandler) HDCreateBatchCards(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	h.service.CreateBatchCards(card.CreateCardsToModel(cards.Cards, wordSetId), userId)
+	err = h.service.CreateBatchCards(wordSetId, card.CreateCardsToModel(cards.Cards, wordSetId), userId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
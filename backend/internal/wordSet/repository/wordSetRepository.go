@@ -2,9 +2,12 @@ package repository
 
 import (
 	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/concurrency"
 	wordset "dimplom_harmonic/internal/wordSet"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WordSetRepository struct {
@@ -28,13 +31,20 @@ func (r *WordSetRepository) GetAllWordSet(filter wordset.WordSetFilter) ([]words
 	var results []wordset.WordSetGetResult
 
 	query := r.db.Model(&models.WordSet{}).
-		Select("word_sets.*, COUNT(set_to_card_link.card_id) as cards_count, users.login as user_name").
+		Select("word_sets.*, COUNT(cards.id) as cards_count, users.login as user_name").
 		Joins("LEFT JOIN set_to_card_link ON set_to_card_link.word_set_id = word_sets.id").
+		Joins("LEFT JOIN cards ON cards.id = set_to_card_link.card_id AND cards.deleted_at IS NULL").
 		Joins("LEFT JOIN users ON word_sets.user_id = users.id")
 
-	if filter.Type == "public" {
-		query.Where("word_sets.is_public = ? AND word_sets.user_id != ?", true, filter.UserId)
-	} else {
+	switch filter.Type {
+	case "public":
+		query.Where("word_sets.is_public = ? AND word_sets.hidden_at IS NULL AND (word_sets.user_id IS NULL OR word_sets.user_id != ?)", true, filter.UserId)
+	case "shared":
+		query.Select("word_sets.*, COUNT(cards.id) as cards_count, users.login as user_name, members.role as role").
+			Joins("JOIN word_set_members members ON members.word_set_id = word_sets.id").
+			Where("members.user_id = ? AND members.accepted_at IS NOT NULL", filter.UserId).
+			Group("members.role")
+	default:
 		query.Where("word_sets.user_id = ?", filter.UserId)
 	}
 	err := query.
@@ -58,7 +68,7 @@ func (r *WordSetRepository) GetWordSetByID(userId, wordSetId int) (*wordset.Word
 	err := r.db.Table("word_sets").
 		Select("word_sets.*, users.login as user_name").
 		Joins("LEFT JOIN users ON word_sets.user_id = users.id").
-		Where("word_sets.id = ?", wordSetId).
+		Where("word_sets.id = ? AND word_sets.deleted_at IS NULL", wordSetId).
 		Scan(&wsResult).Error
 
 	if err != nil {
@@ -84,10 +94,11 @@ func (r *WordSetRepository) GetWordSetByID(userId, wordSetId int) (*wordset.Word
                 JOIN decks d ON d.id = dc.deck_id 
                 WHERE dc.card_id = cards.id 
                 AND d.user_id = ? 
+                AND d.deleted_at IS NULL
             ) as is_learning
         `, userId).
 		Joins("JOIN set_to_card_link link ON link.card_id = cards.id").
-		Where("link.word_set_id = ?", wordSetId).
+		Where("link.word_set_id = ? AND cards.deleted_at IS NULL", wordSetId).
 		Scan(&cards).Error // Direct Scan! No temporary struct needed.
 
 	if err != nil {
@@ -99,12 +110,30 @@ func (r *WordSetRepository) GetWordSetByID(userId, wordSetId int) (*wordset.Word
 	return result, nil
 }
 
-func (r *WordSetRepository) UpdateWordSet(wordSetId int, changeWordSet map[string]any) error {
-	err := r.db.Model(&models.WordSet{}).Where("id = ? AND is_default = FALSE", wordSetId).Updates(changeWordSet).Error
-	if err != nil {
-		return err
+// UpdateWordSet bumps the set's version and returns the new one. A non-zero
+// version makes the update conditional on the set still having it.
+func (r *WordSetRepository) UpdateWordSet(wordSetId, version int, changeWordSet map[string]any) (int, error) {
+	var updated models.WordSet
+
+	query := r.db.Model(&updated).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "version"}}}).
+		Where("id = ? AND is_default = FALSE", wordSetId)
+	if version != 0 {
+		query = query.Where("version = ?", version)
 	}
-	return nil
+
+	changeWordSet["version"] = gorm.Expr("version + 1")
+	result := query.Updates(changeWordSet)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if version != 0 {
+			return 0, concurrency.ErrVersionConflict
+		}
+		return 0, gorm.ErrRecordNotFound
+	}
+	return updated.Version, nil
 }
 
 func (r *WordSetRepository) DeleteWordSet(wordSetId int) error {
@@ -116,6 +145,43 @@ func (r *WordSetRepository) DeleteWordSet(wordSetId int) error {
 	return nil
 }
 
+func (r *WordSetRepository) CountWordSets(userId int) (int, error) {
+	var countWordSets int64
+
+	err := r.db.Model(&models.WordSet{}).
+		Where("user_id = ? AND is_default = FALSE", userId).
+		Count(&countWordSets).Error
+	if err != nil {
+		return 0, err
+	}
+	return int(countWordSets), nil
+}
+
+func (r *WordSetRepository) GetDeleted(userId int) ([]models.WordSet, error) {
+	var wordSets []models.WordSet
+	err := r.db.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userId).
+		Order("deleted_at DESC").
+		Find(&wordSets).Error
+	if err != nil {
+		return nil, err
+	}
+	return wordSets, nil
+}
+
+func (r *WordSetRepository) Restore(userId, wordSetId int) error {
+	result := r.db.Unscoped().Model(&models.WordSet{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", wordSetId, userId).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 func (r *WordSetRepository) AddConection(wordSet *models.WordSet, cards []models.Card) error {
 	return r.db.Model(wordSet).Association("Cards").Append(cards)
 }
@@ -126,7 +192,7 @@ func (r *WordSetRepository) GetDefault(userId int) (*models.WordSet, error) {
 	query := `
 		SELECT *
 		FROM word_sets w 
-		WHERE w.user_id = ? AND is_default = TRUE
+		WHERE w.user_id = ? AND is_default = TRUE AND w.deleted_at IS NULL
 	`
 
 	err := r.db.Raw(query, userId).Scan(&getWordSet).Error
@@ -137,6 +203,220 @@ func (r *WordSetRepository) GetDefault(userId int) (*models.WordSet, error) {
 	return &getWordSet, nil
 }
 
+// AnonymizeCopied detaches the user's public word sets that other users have
+// copied, so they stay in the catalog after the account is deleted.
+func (r *WordSetRepository) AnonymizeCopied(userId int) (int64, error) {
+	query := `
+		UPDATE 
+			word_sets w
+		SET 
+			user_id = NULL,
+			is_default = FALSE
+		WHERE 
+			w.user_id = ?
+			AND w.is_public = TRUE
+			AND w.deleted_at IS NULL
+			AND EXISTS (
+				SELECT 1 FROM word_sets c 
+				WHERE c.source_word_set_id = w.id AND c.user_id != ?
+			)
+	`
+	result := r.db.Exec(query, userId, userId)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// PurgeUserWordSets hard-deletes all the user's word sets, trashed ones too.
+func (r *WordSetRepository) PurgeUserWordSets(userId int) error {
+	return r.db.Unscoped().Where("user_id = ?", userId).Delete(&models.WordSet{}).Error
+}
+
+func (r *WordSetRepository) GetAccess(userId, wordSetId int) (*wordset.WordSetAccess, error) {
+	var access wordset.WordSetAccess
+
+	query := `
+		SELECT 
+			w.is_public,
+			w.is_default,
+			w.hidden_at IS NOT NULL as hidden,
+			w.unpublished_at IS NOT NULL as unpublished,
+			CASE WHEN w.user_id = ? THEN 'owner' ELSE COALESCE(m.role, '') END as role
+		FROM 
+			word_sets w
+			LEFT JOIN word_set_members m ON m.word_set_id = w.id AND m.user_id = ? AND m.accepted_at IS NOT NULL
+		WHERE 
+			w.id = ? AND w.deleted_at IS NULL
+	`
+	result := r.db.Raw(query, userId, userId, wordSetId).Scan(&access)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &access, nil
+}
+
+func (r *WordSetRepository) GetAudience(wordSetIds []int) ([]int, error) {
+	var userIds []int
+
+	query := `
+		SELECT user_id FROM word_sets
+		WHERE id IN ? AND user_id IS NOT NULL
+		UNION
+		SELECT user_id FROM word_set_members
+		WHERE word_set_id IN ? AND accepted_at IS NOT NULL
+	`
+	err := r.db.Raw(query, wordSetIds, wordSetIds).Scan(&userIds).Error
+	if err != nil {
+		return nil, err
+	}
+	return userIds, nil
+}
+
+func (r *WordSetRepository) FindUser(loginOrEmail string) (*models.User, error) {
+	var user models.User
+	err := r.db.Select("id", "login").
+		Where("login = ? OR LOWER(email) = LOWER(?)", loginOrEmail, loginOrEmail).
+		First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateMember returns false when the user is already invited to the set.
+func (r *WordSetRepository) CreateMember(member *models.WordSetMember) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(member)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *WordSetRepository) GetMembers(wordSetId int) ([]wordset.MemberResult, error) {
+	var members []wordset.MemberResult
+
+	query := `
+		SELECT 
+			m.*,
+			u.login,
+			u.email
+		FROM 
+			word_set_members m
+			JOIN users u ON u.id = m.user_id
+		WHERE 
+			m.word_set_id = ?
+		ORDER BY 
+			m.created_at, m.id
+	`
+	err := r.db.Raw(query, wordSetId).Scan(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *WordSetRepository) UpdateMemberRole(wordSetId, userId int, role string) error {
+	result := r.db.Model(&models.WordSetMember{}).
+		Where("word_set_id = ? AND user_id = ?", wordSetId, userId).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *WordSetRepository) DeleteMember(wordSetId, userId int) error {
+	result := r.db.Where("word_set_id = ? AND user_id = ?", wordSetId, userId).Delete(&models.WordSetMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *WordSetRepository) GetInvitations(userId int) ([]wordset.InvitationResult, error) {
+	var invitations []wordset.InvitationResult
+
+	query := `
+		SELECT 
+			m.*,
+			w.name as word_set_name,
+			COALESCE(u.login, '') as owner_name
+		FROM 
+			word_set_members m
+			JOIN word_sets w ON w.id = m.word_set_id AND w.deleted_at IS NULL
+			LEFT JOIN users u ON u.id = w.user_id
+		WHERE 
+			m.user_id = ? AND m.accepted_at IS NULL
+		ORDER BY 
+			m.created_at DESC
+	`
+	err := r.db.Raw(query, userId).Scan(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *WordSetRepository) AcceptInvitation(wordSetId, userId int) error {
+	result := r.db.Model(&models.WordSetMember{}).
+		Where("word_set_id = ? AND user_id = ? AND accepted_at IS NULL", wordSetId, userId).
+		Update("accepted_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *WordSetRepository) CreateActivities(activities []models.WordSetActivity) error {
+	if len(activities) == 0 {
+		return nil
+	}
+	return r.db.Create(&activities).Error
+}
+
+func (r *WordSetRepository) GetActivities(wordSetId, limit, offset int) ([]wordset.ActivityResult, int64, error) {
+	var entries []wordset.ActivityResult
+	var total int64
+
+	err := r.db.Model(&models.WordSetActivity{}).Where("word_set_id = ?", wordSetId).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT 
+			a.*,
+			COALESCE(u.login, '') as user_name
+		FROM 
+			word_set_activities a
+			LEFT JOIN users u ON u.id = a.user_id
+		WHERE 
+			a.word_set_id = ?
+		ORDER BY 
+			a.created_at DESC, a.id DESC
+		LIMIT ? OFFSET ?
+	`
+	err = r.db.Raw(query, wordSetId, limit, offset).Scan(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
 func (r *WordSetRepository) WithTx(tx *gorm.DB) wordset.WordSetRepository {
 	return &WordSetRepository{
 		db: tx,
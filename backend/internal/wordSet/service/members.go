@@ -0,0 +1,205 @@
+package service
+
+import (
+	models "dimplom_harmonic/domain"
+	wordset "dimplom_harmonic/internal/wordSet"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func validMemberRole(role string) bool {
+	return role == models.WordSetEditor || role == models.WordSetViewer
+}
+
+// requireOwner is for managing members, which only the owner may do.
+func (s *WordSetService) requireOwner(userId, wordSetId int) (*wordset.WordSetAccess, error) {
+	access, err := wordset.RequireAccess(s.wordSetRepo, userId, wordSetId, false)
+	if err != nil {
+		return nil, err
+	}
+	if access.Role != wordset.RoleOwner {
+		return nil, wordset.ErrForbidden
+	}
+	return access, nil
+}
+
+func (s *WordSetService) GetMembers(userId, wordSetId int) ([]wordset.MemberDTO, error) {
+	access, err := wordset.RequireAccess(s.wordSetRepo, userId, wordSetId, false)
+	if err != nil {
+		return nil, err
+	}
+	if access.Role == "" {
+		return nil, wordset.ErrForbidden
+	}
+
+	members, err := s.wordSetRepo.GetMembers(wordSetId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]wordset.MemberDTO, 0, len(members))
+	for i := range members {
+		// Pending invites are the owner's business.
+		if members[i].AcceptedAt == nil && access.Role != wordset.RoleOwner {
+			continue
+		}
+		result = append(result, wordset.MemberResultTo(&members[i]))
+	}
+	return result, nil
+}
+
+func (s *WordSetService) InviteMember(userId, wordSetId int, input wordset.InviteMemberDTO) (*wordset.MemberDTO, error) {
+	if !validMemberRole(input.Role) {
+		return nil, wordset.ErrInvalidRole
+	}
+
+	access, err := s.requireOwner(userId, wordSetId)
+	if err != nil {
+		return nil, err
+	}
+	if access.IsDefault {
+		return nil, wordset.ErrShareDefaultSet
+	}
+
+	user, err := s.wordSetRepo.FindUser(strings.TrimSpace(input.User))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, wordset.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Id == userId {
+		return nil, wordset.ErrShareOwnSet
+	}
+
+	member := models.WordSetMember{
+		WordSetId: wordSetId,
+		UserId:    user.Id,
+		Role:      input.Role,
+		InvitedBy: &userId,
+		CreatedAt: time.Now(),
+	}
+	created, err := s.wordSetRepo.CreateMember(&member)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, wordset.ErrAlreadyMember
+	}
+	s.publishChanged(userId, wordSetId, wordset.ChangeMembers, user.Id)
+
+	result := wordset.MemberDTO{
+		UserId:  user.Id,
+		Login:   user.Login,
+		Role:    member.Role,
+		Pending: true,
+	}
+	return &result, nil
+}
+
+func (s *WordSetService) UpdateMember(userId, wordSetId, memberId int, role string) error {
+	if !validMemberRole(role) {
+		return wordset.ErrInvalidRole
+	}
+
+	if _, err := s.requireOwner(userId, wordSetId); err != nil {
+		return err
+	}
+
+	if err := s.wordSetRepo.UpdateMemberRole(wordSetId, memberId, role); err != nil {
+		return err
+	}
+	s.publishChanged(userId, wordSetId, wordset.ChangeMembers)
+	return nil
+}
+
+// RemoveMember is used by the owner to remove anyone and by members to leave
+// the set or decline an invite.
+func (s *WordSetService) RemoveMember(userId, wordSetId, memberId int) error {
+	if userId != memberId {
+		if _, err := s.requireOwner(userId, wordSetId); err != nil {
+			return err
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txWordSetRepo := s.wordSetRepo.WithTx(tx)
+
+		if err := txWordSetRepo.DeleteMember(wordSetId, memberId); err != nil {
+			return err
+		}
+		return txWordSetRepo.CreateActivities([]models.WordSetActivity{
+			wordset.NewActivity(wordSetId, userId, wordset.ActivityMemberRemoved, nil, map[string]any{
+				"userId": memberId,
+			}),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publishChanged(userId, wordSetId, wordset.ChangeMembers, memberId)
+	return nil
+}
+
+func (s *WordSetService) GetInvitations(userId int) ([]wordset.InvitationDTO, error) {
+	invitations, err := s.wordSetRepo.GetInvitations(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]wordset.InvitationDTO, 0, len(invitations))
+	for _, value := range invitations {
+		result = append(result, wordset.InvitationDTO{
+			WordSetId:   value.WordSetId,
+			WordSetName: value.WordSetName,
+			OwnerName:   value.OwnerName,
+			Role:        value.Role,
+			CreatedAt:   value.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+func (s *WordSetService) AcceptInvitation(userId, wordSetId int) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txWordSetRepo := s.wordSetRepo.WithTx(tx)
+
+		if err := txWordSetRepo.AcceptInvitation(wordSetId, userId); err != nil {
+			return err
+		}
+		return txWordSetRepo.CreateActivities([]models.WordSetActivity{
+			wordset.NewActivity(wordSetId, userId, wordset.ActivityMemberJoined, nil, nil),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publishChanged(userId, wordSetId, wordset.ChangeMembers)
+	return nil
+}
+
+func (s *WordSetService) GetActivity(userId, wordSetId, page, pageSize int) (*wordset.ActivityPageDTO, error) {
+	access, err := wordset.RequireAccess(s.wordSetRepo, userId, wordSetId, false)
+	if err != nil {
+		return nil, err
+	}
+	if access.Role == "" {
+		return nil, wordset.ErrForbidden
+	}
+
+	entries, total, err := s.wordSetRepo.GetActivities(wordSetId, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wordset.ActivityPageDTO{
+		Entries: wordset.ActivityResultTo(entries),
+		Total:   total,
+		Page:    page,
+	}, nil
+}
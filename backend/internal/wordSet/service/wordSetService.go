@@ -3,6 +3,9 @@ package service
 import (
 	models "dimplom_harmonic/domain"
 	"dimplom_harmonic/internal/card"
+	"dimplom_harmonic/internal/entitlement"
+	"dimplom_harmonic/internal/events"
+	"dimplom_harmonic/internal/realtime"
 	wordset "dimplom_harmonic/internal/wordSet"
 	"errors"
 
@@ -10,21 +13,47 @@ import (
 )
 
 type WordSetService struct {
-	wordSetRepo wordset.WordSetRepository
-	cardRepo    card.CardRepository
-	db          *gorm.DB
+	wordSetRepo  wordset.WordSetRepository
+	cardRepo     card.CardRepository
+	entitlements entitlement.EntitlementService
+	outboxRepo   events.OutboxRepository
+	publisher    realtime.Publisher
+	db           *gorm.DB
 }
 
-func NewWordSetService(wordSetRepo wordset.WordSetRepository, cardRepo card.CardRepository, db *gorm.DB) *WordSetService {
+func NewWordSetService(wordSetRepo wordset.WordSetRepository, cardRepo card.CardRepository, entitlements entitlement.EntitlementService, outboxRepo events.OutboxRepository, publisher realtime.Publisher, db *gorm.DB) *WordSetService {
 	return &WordSetService{
-		wordSetRepo: wordSetRepo,
-		cardRepo:    cardRepo,
-		db:          db,
+		wordSetRepo:  wordSetRepo,
+		cardRepo:     cardRepo,
+		entitlements: entitlements,
+		outboxRepo:   outboxRepo,
+		publisher:    publisher,
+		db:           db,
 	}
 }
 
+// publishChanged tells everyone who sees the set about a change. extraUserIds
+// are told too, like a member who was just removed.
+func (s *WordSetService) publishChanged(userId, wordSetId int, action string, extraUserIds ...int) {
+	userIds := append(wordset.Audience(s.wordSetRepo, userId, []int{wordSetId}), extraUserIds...)
+	s.publisher.Publish(realtime.NewEvent(realtime.EventWordSetChanged, userIds, map[string]any{
+		"wordSetId": wordSetId,
+		"action":    action,
+	}))
+}
+
 func (s *WordSetService) CreateWordSet(input *wordset.WordSetDTO, userId int) (*models.WordSet, error) {
 
+	if err := s.entitlements.CheckWordSets(userId, 1); err != nil {
+		return nil, err
+	}
+
+	if input.IsPublic {
+		if err := s.entitlements.RequireVerifiedEmail(userId); err != nil {
+			return nil, err
+		}
+	}
+
 	newWordSet := &models.WordSet{
 		UserId:   userId,
 		Name:     input.Name,
@@ -56,6 +85,7 @@ func (s *WordSetService) GetAllWordSet(userId int, typeWS string) ([]wordset.Wor
 			UserId:     value.UserId,
 			IsDefault:  value.IsDefault,
 			UserName:   value.UserName,
+			Role:       value.Role,
 		}
 		wordSetsResponse = append(wordSetsResponse, wordSet)
 	}
@@ -67,14 +97,18 @@ func (s *WordSetService) GetAllWordSet(userId int, typeWS string) ([]wordset.Wor
 }
 func (s *WordSetService) GetWordSetByID(userId, wordSetId int) (*wordset.WordSetGetResponseByIdDTO, error) {
 
-	wordSetG, err := s.wordSetRepo.GetWordSetByID(userId, wordSetId)
-
-	wordSet := wordSetG.WordSet
+	access, err := wordset.RequireAccess(s.wordSetRepo, userId, wordSetId, false)
+	if err != nil {
+		return nil, err
+	}
 
+	wordSetG, err := s.wordSetRepo.GetWordSetByID(userId, wordSetId)
 	if err != nil {
 		return nil, err
 	}
 
+	wordSet := wordSetG.WordSet
+
 	wordSetDTO := wordset.WordSetGetResponseByIdDTO{
 		Id:         wordSetId,
 		Name:       wordSet.Name,
@@ -83,25 +117,52 @@ func (s *WordSetService) GetWordSetByID(userId, wordSetId int) (*wordset.WordSet
 		UserId:     wordSet.UserId,
 		IsDefault:  wordSet.IsDefault,
 		UserName:   wordSetG.UserName,
+		Role:       access.Role,
+		Version:    wordSet.Version,
 		Cards:      wordSet.Cards,
 	}
 
 	return &wordSetDTO, nil
 }
 
-func (s *WordSetService) UpdateWordSet(wordSetId int, name string, isPublic bool) (*wordset.WordSetResponseUpdate, error) {
+// UpdateWordSet lets editors rename the set; publishing it stays with the
+// owner, and a set taken down by a moderator can't be published again.
+func (s *WordSetService) UpdateWordSet(userId, wordSetId, version int, name string, isPublic bool) (*wordset.WordSetResponseUpdate, error) {
+
+	access, err := wordset.RequireAccess(s.wordSetRepo, userId, wordSetId, true)
+	if err != nil {
+		return nil, err
+	}
+	if access.IsDefault {
+		return nil, errors.New("You can't change default word set")
+	}
+	if isPublic != access.IsPublic && access.Role != wordset.RoleOwner {
+		return nil, wordset.ErrForbidden
+	}
+	if isPublic && !access.IsPublic && access.Unpublished {
+		return nil, wordset.ErrForbidden
+	}
+
+	if isPublic {
+		if err := s.entitlements.RequireVerifiedEmail(userId); err != nil {
+			return nil, err
+		}
+	}
+
 	changeWordSet := make(map[string]any)
 	changeWordSet["Name"] = name
 	changeWordSet["IsPublic"] = isPublic
 
-	err := s.wordSetRepo.UpdateWordSet(wordSetId, changeWordSet)
+	newVersion, err := s.wordSetRepo.UpdateWordSet(wordSetId, version, changeWordSet)
 	if err != nil {
 		return nil, err
 	}
+	s.publishChanged(userId, wordSetId, wordset.ChangeUpdated)
 	wordSetUpdated := wordset.WordSetResponseUpdate{
 		Id:       wordSetId,
 		Name:     name,
 		IsPublic: isPublic,
+		Version:  newVersion,
 	}
 
 	return &wordSetUpdated, nil
@@ -109,6 +170,14 @@ func (s *WordSetService) UpdateWordSet(wordSetId int, name string, isPublic bool
 
 func (s *WordSetService) DeleteWordSet(userId, wordSetId int) error {
 
+	access, err := wordset.RequireAccess(s.wordSetRepo, userId, wordSetId, true)
+	if err != nil {
+		return err
+	}
+	if access.Role != wordset.RoleOwner {
+		return wordset.ErrForbidden
+	}
+
 	set, err := s.wordSetRepo.GetWordSetByID(userId, wordSetId)
 	if err != nil {
 		return err
@@ -121,20 +190,30 @@ func (s *WordSetService) DeleteWordSet(userId, wordSetId int) error {
 	if err != nil {
 		return err
 	}
+	s.publishChanged(userId, wordSetId, wordset.ChangeDeleted)
 	return nil
 }
 
 func (s *WordSetService) CopyWordSet(wordSetId, userId int) (*models.WordSet, error) {
 
+	if _, err := wordset.RequireAccess(s.wordSetRepo, userId, wordSetId, false); err != nil {
+		return nil, err
+	}
+
+	if err := s.entitlements.CheckWordSets(userId, 1); err != nil {
+		return nil, err
+	}
+
 	copyWS, err := s.wordSetRepo.GetWordSetByID(userId, wordSetId)
 	if err != nil {
 		return nil, err
 	}
 
 	newWordSet := models.WordSet{
-		UserId:   userId,
-		Name:     copyWS.Name,
-		IsPublic: false,
+		UserId:          userId,
+		Name:            copyWS.Name,
+		IsPublic:        false,
+		SourceWordSetId: &copyWS.Id,
 	}
 
 	err = s.db.Transaction(func(tx *gorm.DB) error {
@@ -169,14 +248,42 @@ func (s *WordSetService) CopyWordSet(wordSetId, userId int) (*models.WordSet, er
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	return &newWordSet, nil
 }
 
-func (s *WordSetService) CreateBatchCards(cards []models.Card, userId int) error {
-	err := s.cardRepo.CreateCard(cards, userId)
+func (s *WordSetService) CreateBatchCards(wordSetId int, cards []models.Card, userId int) error {
+
+	if _, err := wordset.RequireAccess(s.wordSetRepo, userId, wordSetId, true); err != nil {
+		return err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := s.cardRepo.WithTx(tx).CreateCard(cards, userId)
+		if err != nil {
+			return err
+		}
+
+		if err := events.Record(s.outboxRepo.WithTx(tx), events.CardsCreated(userId, cards)...); err != nil {
+			return err
+		}
+
+		activities := make([]models.WordSetActivity, 0, len(cards))
+		for _, value := range cards {
+			activities = append(activities, wordset.NewActivity(wordSetId, userId, wordset.ActivityCardAdded, &value.Id, map[string]any{
+				"originalWord": value.OriginalWord,
+				"translation":  value.Translation,
+			}))
+		}
+		return s.wordSetRepo.WithTx(tx).CreateActivities(activities)
+	})
 	if err != nil {
 		return err
 	}
+
+	s.publishChanged(userId, wordSetId, wordset.ChangeCardsAdded)
 	return nil
 }
@@ -2,6 +2,10 @@ package wordset
 
 import (
 	models "dimplom_harmonic/domain"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -10,20 +14,45 @@ type WordSetService interface {
 	CreateWordSet(input *WordSetDTO, userId int) (*models.WordSet, error)
 	GetAllWordSet(userId int, typeWS string) ([]WordSetGetResponseDTO, error)
 	GetWordSetByID(userId, wordSetId int) (*WordSetGetResponseByIdDTO, error)
-	UpdateWordSet(wordSetId int, name string, isPublic bool) (*WordSetResponseUpdate, error)
+	UpdateWordSet(userId, wordSetId, version int, name string, isPublic bool) (*WordSetResponseUpdate, error)
 	DeleteWordSet(userId, wordSetId int) error
 	CopyWordSet(wordSetId, userId int) (*models.WordSet, error)
-	CreateBatchCards(cards []models.Card, userId int) error
+	CreateBatchCards(wordSetId int, cards []models.Card, userId int) error
+
+	GetMembers(userId, wordSetId int) ([]MemberDTO, error)
+	InviteMember(userId, wordSetId int, input InviteMemberDTO) (*MemberDTO, error)
+	UpdateMember(userId, wordSetId, memberId int, role string) error
+	RemoveMember(userId, wordSetId, memberId int) error
+	GetInvitations(userId int) ([]InvitationDTO, error)
+	AcceptInvitation(userId, wordSetId int) error
+	GetActivity(userId, wordSetId, page, pageSize int) (*ActivityPageDTO, error)
 }
 
 type WordSetRepository interface {
 	CreateWordSet(*models.WordSet) error
 	GetAllWordSet(filter WordSetFilter) ([]WordSetGetResult, error)
 	GetWordSetByID(userId, wordSetId int) (*WordSetGetResult, error)
-	UpdateWordSet(wordSetId int, changeWordSet map[string]any) error
+	UpdateWordSet(wordSetId, version int, changeWordSet map[string]any) (int, error)
 	DeleteWordSet(wordSetId int) error
 	AddConection(wordSet *models.WordSet, cards []models.Card) error
 	GetDefault(userId int) (*models.WordSet, error)
+	CountWordSets(userId int) (int, error)
+	GetDeleted(userId int) ([]models.WordSet, error)
+	Restore(userId, wordSetId int) error
+	AnonymizeCopied(userId int) (int64, error)
+	PurgeUserWordSets(userId int) error
+
+	GetAccess(userId, wordSetId int) (*WordSetAccess, error)
+	FindUser(loginOrEmail string) (*models.User, error)
+	CreateMember(member *models.WordSetMember) (bool, error)
+	GetMembers(wordSetId int) ([]MemberResult, error)
+	GetAudience(wordSetIds []int) ([]int, error)
+	UpdateMemberRole(wordSetId, userId int, role string) error
+	DeleteMember(wordSetId, userId int) error
+	GetInvitations(userId int) ([]InvitationResult, error)
+	AcceptInvitation(wordSetId, userId int) error
+	CreateActivities(activities []models.WordSetActivity) error
+	GetActivities(wordSetId, limit, offset int) ([]ActivityResult, int64, error)
 	WithTx(tx *gorm.DB) WordSetRepository
 }
 
@@ -31,12 +60,14 @@ type WordSetResponseUpdate struct {
 	Id       int    `json:"id"`
 	Name     string `json:"name"`
 	IsPublic bool   `json:"isPublic"`
+	Version  int    `json:"version"`
 }
 
 type WordSetGetResult struct {
 	models.WordSet
 	CardsCount int
 	UserName   string
+	Role       string
 }
 
 type WordSetGetResponseByIdDTO struct {
@@ -47,6 +78,8 @@ type WordSetGetResponseByIdDTO struct {
 	UserId     int           `json:"userId"`
 	IsDefault  bool          `json:"isDefault"`
 	UserName   string        `json:"userName"`
+	Role       string        `json:"role"`
+	Version    int           `json:"version"`
 	Cards      []models.Card `json:"cards"`
 }
 
@@ -58,9 +91,129 @@ type WordSetGetResponseDTO struct {
 	UserId     int    `json:"userId"`
 	IsDefault  bool   `json:"isDefault"`
 	UserName   string `json:"userName"`
+	Role       string `json:"role,omitempty"`
 }
 
 type WordSetFilter struct {
 	UserId int
 	Type   string
 }
+
+// WordSetAccess is what a user may do with a word set. Role is empty for
+// users who only see the set because it is public. A hidden set is seen only
+// by its owner and members until a moderator looks at it.
+type WordSetAccess struct {
+	Role        string
+	IsPublic    bool
+	IsDefault   bool
+	Hidden      bool
+	Unpublished bool
+}
+
+func (a *WordSetAccess) CanView() bool {
+	return a.Role != "" || (a.IsPublic && !a.Hidden)
+}
+
+func (a *WordSetAccess) CanEdit() bool {
+	return a.Role == RoleOwner || a.Role == models.WordSetEditor
+}
+
+// RequireAccess loads the user's access to the set. Sets the user can't see
+// are reported as missing; visible ones the user can't change as forbidden.
+func RequireAccess(repo WordSetRepository, userId, wordSetId int, edit bool) (*WordSetAccess, error) {
+	access, err := repo.GetAccess(userId, wordSetId)
+	if err != nil {
+		return nil, err
+	}
+	if !access.CanView() {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if edit && !access.CanEdit() {
+		return nil, ErrForbidden
+	}
+	return access, nil
+}
+
+// Audience returns the user plus everyone who sees the word sets: their
+// owners and accepted members. A failed lookup only narrows it to the user.
+func Audience(repo WordSetRepository, userId int, wordSetIds []int) []int {
+	userIds := []int{userId}
+	if len(wordSetIds) == 0 {
+		return userIds
+	}
+
+	members, err := repo.GetAudience(wordSetIds)
+	if err != nil {
+		log.Printf("Get audience of word sets %v error: %v", wordSetIds, err)
+		return userIds
+	}
+	return append(userIds, members...)
+}
+
+func WordSetIds(wordSets []models.WordSet) []int {
+	ids := make([]int, 0, len(wordSets))
+	for _, value := range wordSets {
+		ids = append(ids, value.Id)
+	}
+	return ids
+}
+
+type MemberResult struct {
+	models.WordSetMember
+	Login string
+	Email string
+}
+
+type InvitationResult struct {
+	models.WordSetMember
+	WordSetName string
+	OwnerName   string
+}
+
+type ActivityResult struct {
+	models.WordSetActivity
+	UserName string
+}
+
+const RoleOwner = "owner"
+
+const (
+	ActivityCardAdded     = "card_added"
+	ActivityCardUpdated   = "card_updated"
+	ActivityCardRemoved   = "card_removed"
+	ActivityMemberJoined  = "member_joined"
+	ActivityMemberRemoved = "member_removed"
+)
+
+// Actions sent with word_set.changed events.
+const (
+	ChangeUpdated    = "updated"
+	ChangeDeleted    = "deleted"
+	ChangeCardsAdded = "cards_added"
+	ChangeMembers    = "members_changed"
+)
+
+// NewActivity builds a feed entry with the details stored as JSON.
+func NewActivity(wordSetId, userId int, action string, cardId *int, details map[string]any) models.WordSetActivity {
+	activity := models.WordSetActivity{
+		WordSetId: wordSetId,
+		UserId:    &userId,
+		Action:    action,
+		CardId:    cardId,
+		CreatedAt: time.Now(),
+	}
+	if details != nil {
+		detailsJSON, _ := json.Marshal(details)
+		activity.Details = string(detailsJSON)
+	}
+	return activity
+}
+
+var (
+	ErrForbidden       = errors.New("word_set_forbidden")
+	ErrInvalidRole     = errors.New("invalid_member_role")
+	ErrUserNotFound    = errors.New("user_not_found")
+	ErrAlreadyMember   = errors.New("already_member")
+	ErrShareOwnSet     = errors.New("cant_invite_owner")
+	ErrShareDefaultSet = errors.New("cant_share_default_word_set")
+)
@@ -1,6 +1,10 @@
 package workers
 
 import (
+	"context"
+	"dimplom_harmonic/internal/jobs"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -8,49 +12,122 @@ import (
 )
 
 type Cleaner struct {
-	db *gorm.DB
+	db        *gorm.DB
+	retention time.Duration
 }
 
-func NewCleaner(db *gorm.DB) *Cleaner {
-	return &Cleaner{db: db}
+func NewCleaner(db *gorm.DB, retention time.Duration) *Cleaner {
+	return &Cleaner{db: db, retention: retention}
 }
 
-func (c *Cleaner) StartClean() {
-	tiker := time.NewTicker(1 * time.Hour)
+// Jobs returns the cleanups to register in a jobs.Runner.
+func (c *Cleaner) Jobs() []jobs.Job {
+	return []jobs.Job{
+		{Name: "purge-trash", Interval: time.Hour, Run: c.PurgeTrash},
+		{Name: "clean-orphan-cards", Interval: time.Hour, Run: c.CleanOrphanCards},
+		{Name: "clean-expired-tokens", Interval: time.Hour, Run: c.CleanExpiredTokens},
+		{Name: "purge-dispatched-events", Interval: time.Hour, Run: c.PurgeDispatchedEvents},
+		{Name: "purge-webhook-deliveries", Interval: time.Hour, Run: c.PurgeWebhookDeliveries},
+	}
+}
 
-	go func() {
-		for {
-			<-tiker.C
-			c.CleanOrphance()
-		}
-	}()
+// PurgeDispatchedEvents deletes outbox events dispatched more than a week ago.
+func (c *Cleaner) PurgeDispatchedEvents(ctx context.Context) (int64, error) {
+	result := c.db.WithContext(ctx).Exec(`DELETE FROM outbox_events WHERE dispatched_at < NOW() - INTERVAL '7 days'`)
+	return result.RowsAffected, result.Error
 }
 
-func (c *Cleaner) CleanOrphance() {
-	batchSize := 100
+// PurgeWebhookDeliveries deletes finished webhook deliveries older than 30
+// days, which is as far back as the delivery log goes.
+func (c *Cleaner) PurgeWebhookDeliveries(ctx context.Context) (int64, error) {
+	result := c.db.WithContext(ctx).Exec(`DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < NOW() - INTERVAL '30 days'`)
+	return result.RowsAffected, result.Error
+}
 
-	for {
-		query := `DELETE FROM cards 
-				  WHERE id NOT IN (SELECT card_id FROM deck_cards)	
-				  AND id NOT IN (SELECT card_id FROM set_to_card_link
-				  LIMIT ?
-				)
-		`
+// PurgeTrash hard-deletes decks, word sets and cards that stayed in the trash
+// longer than the retention period. Deleting them cascades into links and
+// histories; cards left without links are removed by CleanOrphanCards.
+func (c *Cleaner) PurgeTrash(ctx context.Context) (int64, error) {
+	purgeBefore := time.Now().Add(-c.retention)
+
+	queries := map[string]string{
+		"decks":     `DELETE FROM decks WHERE deleted_at IS NOT NULL AND deleted_at < ?`,
+		"word sets": `DELETE FROM word_sets WHERE deleted_at IS NOT NULL AND deleted_at < ?`,
+		"cards":     `DELETE FROM cards WHERE deleted_at IS NOT NULL AND deleted_at < ?`,
+	}
+
+	return c.execAll(ctx, queries, purgeBefore)
+}
+
+// CleanExpiredTokens deletes expired refresh tokens, email tokens, OAuth
+// states, rate limit counters and idempotency keys, sync deletions older than
+// offlinesync.DeletionsRetention, sent notifications older than 90 days and
+// job runs older than 30 days. Rotated refresh tokens are kept until they
+// expire, so a replay is still recognised while it could be accepted.
+func (c *Cleaner) CleanExpiredTokens(ctx context.Context) (int64, error) {
+	queries := map[string]string{
+		"refresh tokens":   `DELETE FROM refresh_tokens WHERE expires_at < NOW()`,
+		"email tokens":     `DELETE FROM user_tokens WHERE expires_at < NOW()`,
+		"oauth states":     `DELETE FROM oauth_states WHERE expires_at < NOW()`,
+		"rate limits":      `DELETE FROM rate_limits WHERE expires_at < NOW()`,
+		"idempotency keys": `DELETE FROM idempotency_keys WHERE expires_at < NOW()`,
+		"sync deletions":   `DELETE FROM sync_deletions WHERE created_at < NOW() - INTERVAL '90 days'`,
+		"notifications":    `DELETE FROM sent_notifications WHERE created_at < NOW() - INTERVAL '90 days'`,
+		"job runs":         `DELETE FROM job_runs WHERE started_at < NOW() - INTERVAL '30 days'`,
+	}
 
-		result := c.db.Exec(query, batchSize)
+	return c.execAll(ctx, queries)
+}
+
+// execAll runs every query even if some fail, so one broken table doesn't
+// stop the others from being cleaned.
+func (c *Cleaner) execAll(ctx context.Context, queries map[string]string, args ...any) (int64, error) {
+	var total int64
+	var errs []error
 
+	for name, query := range queries {
+		result := c.db.WithContext(ctx).Exec(query, args...)
 		if result.Error != nil {
-			log.Printf("Clean up error: %v", result.Error)
+			errs = append(errs, fmt.Errorf("%s: %w", name, result.Error))
+			continue
+		}
+		if result.RowsAffected != 0 {
+			log.Println("Was deleted", result.RowsAffected, name)
 		}
+		total += result.RowsAffected
+	}
+	return total, errors.Join(errs...)
+}
+
+// CleanOrphanCards deletes cards that are in no deck and no word set, in
+// batches so a large cleanup doesn't hold long locks on cards.
+func (c *Cleaner) CleanOrphanCards(ctx context.Context) (int64, error) {
+	const batchSize = 100
 
-		deletedCount := result.RowsAffected
+	query := `DELETE FROM cards
+			  WHERE id IN (
+				SELECT c.id FROM cards c
+				WHERE NOT EXISTS (SELECT 1 FROM deck_cards dc WHERE dc.card_id = c.id)
+				AND NOT EXISTS (SELECT 1 FROM set_to_card_link l WHERE l.card_id = c.id)
+				LIMIT ?
+			  )`
 
-		if deletedCount == 0 {
-			log.Println("Deleted Orphance card is completed")
-			break
+	var total int64
+	for {
+		result := c.db.WithContext(ctx).Exec(query, batchSize)
+		if result.Error != nil {
+			return total, result.Error
 		}
-		log.Println("Was deleted", deletedCount, "cards")
 
-		time.Sleep(100 * time.Millisecond)
+		total += result.RowsAffected
+		if result.RowsAffected < batchSize {
+			return total, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
 }
@@ -0,0 +1,113 @@
+package workers
+
+import (
+	"context"
+	"dimplom_harmonic/internal/events"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	outboxBatchSize   = 20
+	outboxMaxAttempts = 10
+)
+
+// OutboxDispatcher hands saved domain events to the bus subscribers. Several
+// instances can run at once: each claims its own batch.
+type OutboxDispatcher struct {
+	db   *gorm.DB
+	repo events.OutboxRepository
+	bus  *events.Bus
+
+	wg sync.WaitGroup
+}
+
+func NewOutboxDispatcher(db *gorm.DB, repo events.OutboxRepository, bus *events.Bus) *OutboxDispatcher {
+	return &OutboxDispatcher{db: db, repo: repo, bus: bus}
+}
+
+// StartDispatch runs every 2 seconds until ctx is cancelled.
+func (d *OutboxDispatcher) StartDispatch(ctx context.Context) {
+	d.wg.Go(func() {
+		tiker := time.NewTicker(2 * time.Second)
+		defer tiker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tiker.C:
+				d.DispatchPending()
+			}
+		}
+	})
+}
+
+// Wait returns once the current run has finished after the StartDispatch ctx is
+// cancelled.
+func (d *OutboxDispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// DispatchPending dispatches due events batch by batch. A failed event is
+// retried with exponential backoff and given up after outboxMaxAttempts; its
+// last error stays in the table.
+func (d *OutboxDispatcher) DispatchPending() {
+	for {
+		claimed := 0
+
+		err := d.db.Transaction(func(tx *gorm.DB) error {
+			txRepo := d.repo.WithTx(tx)
+
+			pending, err := txRepo.ClaimPending(outboxBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(pending)
+
+			for _, event := range pending {
+				dispatchErr := d.bus.Dispatch(events.WithEventId(context.Background(), event.Id), event.EventType, []byte(event.Payload))
+				if dispatchErr == nil {
+					if err := txRepo.MarkDispatched(event.Id); err != nil {
+						return err
+					}
+					continue
+				}
+
+				attempts := event.Attempts + 1
+				retryAt := time.Now().Add(outboxBackoff(attempts))
+				if err := txRepo.MarkFailed(event.Id, attempts, dispatchErr.Error(), retryAt); err != nil {
+					return err
+				}
+				if attempts >= outboxMaxAttempts {
+					log.Printf("Gave up dispatching %s event %d: %v", event.EventType, event.Id, dispatchErr)
+					if err := txRepo.MarkDispatched(event.Id); err != nil {
+						return err
+					}
+					continue
+				}
+				log.Printf("Dispatch %s event %d error (attempt %d): %v", event.EventType, event.Id, attempts, dispatchErr)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Outbox dispatch error: %v", err)
+			return
+		}
+		if claimed < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// outboxBackoff doubles from 10 seconds up to an hour.
+func outboxBackoff(attempts int) time.Duration {
+	delay := 10 * time.Second << (attempts - 1)
+	if delay > time.Hour || delay <= 0 {
+		return time.Hour
+	}
+	return delay
+}
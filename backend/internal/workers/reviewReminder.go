@@ -0,0 +1,221 @@
+package workers
+
+import (
+	"context"
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/notification"
+	"log"
+	"sync"
+	"time"
+)
+
+// ReviewReminder tells users about due decks through every channel they have
+// enabled: a reminder when decks become due, or one daily digest for users
+// who asked for it. Sent notifications are recorded first, so a deck is
+// reminded about once per review date even with several instances running.
+type ReviewReminder struct {
+	repo     notification.NotificationRepository
+	channels []notification.Channel
+	appURL   string
+
+	wg sync.WaitGroup
+}
+
+func NewReviewReminder(repo notification.NotificationRepository, appURL string, channels ...notification.Channel) *ReviewReminder {
+	return &ReviewReminder{repo: repo, channels: channels, appURL: appURL}
+}
+
+// StartRemind runs every minute until ctx is cancelled.
+func (r *ReviewReminder) StartRemind(ctx context.Context) {
+	r.wg.Go(func() {
+		tiker := time.NewTicker(1 * time.Minute)
+		defer tiker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tiker.C:
+				r.Remind()
+			}
+		}
+	})
+}
+
+// Wait returns once the current run has finished after the StartRemind ctx is
+// cancelled.
+func (r *ReviewReminder) Wait() {
+	r.wg.Wait()
+}
+
+func (r *ReviewReminder) Remind() {
+	now := time.Now()
+	r.sendReminders(now)
+	r.sendDigests(now)
+}
+
+// sendReminders looks back notification.ReminderLookback rather than to the
+// previous run; decks already reminded about are skipped by the query, and
+// users in quiet hours get theirs once the quiet hours end.
+func (r *ReviewReminder) sendReminders(now time.Time) {
+	due, err := r.repo.GetNewlyDue(now.Add(-notification.ReminderLookback), now)
+	if err != nil {
+		log.Printf("Get due decks error: %v", err)
+		return
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	decksByUser := groupByUser(due)
+	recipients, err := r.recipients(decksByUser)
+	if err != nil {
+		log.Printf("Get reminder recipients error: %v", err)
+		return
+	}
+
+	for _, recipient := range recipients {
+		if notification.InQuietHours(recipient.Preferences, now) {
+			continue
+		}
+
+		for _, channel := range r.channels {
+			if !channel.Enabled(recipient) {
+				continue
+			}
+
+			claims := make([]models.SentNotification, 0, len(decksByUser[recipient.UserId]))
+			for _, deck := range decksByUser[recipient.UserId] {
+				claims = append(claims, models.SentNotification{
+					UserId:  recipient.UserId,
+					Channel: channel.Name(),
+					Kind:    notification.KindReminder,
+					DeckId:  &deck.DeckId,
+					DueAt:   deck.NextReviewDate,
+				})
+			}
+
+			r.send(channel, recipient, claims, func(claimed []models.SentNotification) notification.Message {
+				return notification.ReminderMessage(r.appURL, recipient, claimedDecks(decksByUser[recipient.UserId], claimed))
+			})
+		}
+	}
+}
+
+// sendDigests sends the daily list of due decks once the digest time has
+// passed in the user's timezone. Quiet hours don't apply: the user picked
+// the time.
+func (r *ReviewReminder) sendDigests(now time.Time) {
+	userIds, err := r.repo.GetDigestUsers()
+	if err != nil {
+		log.Printf("Get digest users error: %v", err)
+		return
+	}
+	if len(userIds) == 0 {
+		return
+	}
+
+	due, err := r.repo.GetDueDecks(userIds)
+	if err != nil {
+		log.Printf("Get digest decks error: %v", err)
+		return
+	}
+
+	decksByUser := groupByUser(due)
+	recipients, err := r.recipients(decksByUser)
+	if err != nil {
+		log.Printf("Get digest recipients error: %v", err)
+		return
+	}
+
+	for _, recipient := range recipients {
+		decks := decksByUser[recipient.UserId]
+		day := notification.StartOfDay(recipient.Preferences, now)
+
+		for _, channel := range r.channels {
+			if !channel.Enabled(recipient) {
+				continue
+			}
+
+			claims := []models.SentNotification{{
+				UserId:  recipient.UserId,
+				Channel: channel.Name(),
+				Kind:    notification.KindDigest,
+				DueAt:   day,
+			}}
+			r.send(channel, recipient, claims, func([]models.SentNotification) notification.Message {
+				return notification.DigestMessage(r.appURL, recipient, decks)
+			})
+		}
+	}
+}
+
+// send claims the notifications and sends one message about the claimed
+// ones. A failed send is recorded and not retried.
+func (r *ReviewReminder) send(channel notification.Channel, recipient notification.Recipient, claims []models.SentNotification, build func([]models.SentNotification) notification.Message) {
+	message := build(claims)
+	for i := range claims {
+		claims[i].Title = message.Title
+		claims[i].Body = message.Body
+		claims[i].Status = models.NotificationSent
+	}
+
+	claimed, err := r.repo.Claim(claims)
+	if err != nil {
+		log.Printf("Record %s notification for user %d error: %v", channel.Name(), recipient.UserId, err)
+		return
+	}
+	if len(claimed) == 0 {
+		return
+	}
+	if len(claimed) != len(claims) {
+		message = build(claimed)
+	}
+
+	err = channel.Send(context.Background(), recipient, message)
+	if err == nil {
+		return
+	}
+	log.Printf("Send %s notification to user %d error: %v", channel.Name(), recipient.UserId, err)
+
+	ids := make([]int64, 0, len(claimed))
+	for _, n := range claimed {
+		ids = append(ids, n.Id)
+	}
+	if err := r.repo.MarkFailed(ids, err.Error()); err != nil {
+		log.Printf("Mark notifications failed error: %v", err)
+	}
+}
+
+func (r *ReviewReminder) recipients(decksByUser map[int][]notification.DueDeckResult) ([]notification.Recipient, error) {
+	userIds := make([]int, 0, len(decksByUser))
+	for userId := range decksByUser {
+		userIds = append(userIds, userId)
+	}
+	return r.repo.GetRecipients(userIds)
+}
+
+func groupByUser(decks []notification.DueDeckResult) map[int][]notification.DueDeckResult {
+	decksByUser := make(map[int][]notification.DueDeckResult)
+	for _, d := range decks {
+		decksByUser[d.UserId] = append(decksByUser[d.UserId], d)
+	}
+	return decksByUser
+}
+
+// claimedDecks keeps the decks another instance hasn't already reminded
+// about.
+func claimedDecks(decks []notification.DueDeckResult, claimed []models.SentNotification) []notification.DueDeckResult {
+	ids := make(map[int]bool, len(claimed))
+	for _, n := range claimed {
+		ids[*n.DeckId] = true
+	}
+
+	result := make([]notification.DueDeckResult, 0, len(claimed))
+	for _, d := range decks {
+		if ids[d.DeckId] {
+			result = append(result, d)
+		}
+	}
+	return result
+}
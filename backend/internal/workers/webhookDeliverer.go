@@ -0,0 +1,142 @@
+package workers
+
+import (
+	"context"
+	models "dimplom_harmonic/domain"
+	"dimplom_harmonic/internal/webhook"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	webhookBatchSize = 20
+	// webhookLease keeps claimed deliveries from other workers while they
+	// are sent; it is well above the send timeout.
+	webhookLease = 2 * time.Minute
+)
+
+// WebhookDeliverer sends pending webhook deliveries. A batch is sent in
+// parallel, so one slow receiver doesn't hold up the others.
+type WebhookDeliverer struct {
+	repo   webhook.WebhookRepository
+	sender webhook.Sender
+
+	wg sync.WaitGroup
+}
+
+func NewWebhookDeliverer(repo webhook.WebhookRepository, sender webhook.Sender) *WebhookDeliverer {
+	return &WebhookDeliverer{repo: repo, sender: sender}
+}
+
+// StartDeliver runs every 5 seconds until ctx is cancelled.
+func (d *WebhookDeliverer) StartDeliver(ctx context.Context) {
+	d.wg.Go(func() {
+		tiker := time.NewTicker(5 * time.Second)
+		defer tiker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tiker.C:
+				d.DeliverPending()
+			}
+		}
+	})
+}
+
+// Wait returns once the current run has finished after the StartDeliver ctx is
+// cancelled.
+func (d *WebhookDeliverer) Wait() {
+	d.wg.Wait()
+}
+
+func (d *WebhookDeliverer) DeliverPending() {
+	for {
+		targets, err := d.repo.ClaimDue(webhookBatchSize, webhookLease)
+		if err != nil {
+			log.Printf("Claim webhook deliveries error: %v", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, target := range targets {
+			wg.Go(func() {
+				d.deliver(target)
+			})
+		}
+		wg.Wait()
+
+		if len(targets) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// deliver sends one delivery and saves the attempt. A failure is retried with
+// exponential backoff up to webhook.MaxAttempts, and every failed attempt
+// counts towards disabling the webhook, even when the attempt can't be saved.
+func (d *WebhookDeliverer) deliver(target webhook.DeliveryTarget) {
+	attempt := d.sender.Send(context.Background(), target)
+
+	delivery := target.WebhookDelivery
+	delivery.Attempts++
+	durationMs := int(attempt.Duration.Milliseconds())
+	delivery.DurationMs = &durationMs
+	delivery.ResponseStatus = nil
+	if attempt.StatusCode != 0 {
+		delivery.ResponseStatus = &attempt.StatusCode
+	}
+	delivery.ResponseBody = &attempt.Body
+	delivery.LastError = nil
+
+	if attempt.Err == nil {
+		now := time.Now()
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	} else {
+		lastError := attempt.Err.Error()
+		delivery.LastError = &lastError
+		if delivery.Attempts >= webhook.MaxAttempts {
+			delivery.Status = models.DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts))
+		}
+	}
+
+	if err := d.repo.SaveAttempt(&delivery); err != nil {
+		log.Printf("Save webhook delivery %d error: %v", delivery.Id, err)
+		// The attempt still counts: without it a delivery that can't be
+		// saved would be retried forever.
+		delivery.ResponseBody = nil
+		if err := d.repo.SaveAttempt(&delivery); err != nil {
+			log.Printf("Save webhook delivery %d without body error: %v", delivery.Id, err)
+		}
+	}
+
+	if attempt.Err == nil {
+		if err := d.repo.ResetFailures(delivery.WebhookId); err != nil {
+			log.Printf("Reset webhook %d failures error: %v", delivery.WebhookId, err)
+		}
+		return
+	}
+
+	disabled, err := d.repo.RecordFailure(delivery.WebhookId, webhook.DisableAfterFailures)
+	if err != nil {
+		log.Printf("Record webhook %d failure error: %v", delivery.WebhookId, err)
+		return
+	}
+	if disabled {
+		log.Printf("Webhook %d disabled after %d failed attempts", delivery.WebhookId, webhook.DisableAfterFailures)
+		if err := d.repo.FailPending(delivery.WebhookId, "webhook disabled"); err != nil {
+			log.Printf("Fail webhook %d deliveries error: %v", delivery.WebhookId, err)
+		}
+	}
+}
+
+// webhookBackoff doubles from a minute, so the last of webhook.MaxAttempts
+// comes about two hours after the first.
+func webhookBackoff(attempts int) time.Duration {
+	return time.Minute << (attempts - 1)
+}
@@ -1,10 +1,14 @@
 import { apiClient } from "../../shared/api/client";
-import type { AuthResponse, LoginPayload, RegisterPayload} from "./type";
+import type { AuthResponse, LoginPayload, RegisterPayload, TwoFactorLoginPayload} from "./type";
 
 export const login = async (payload:LoginPayload):Promise<AuthResponse> => {
     return await apiClient.post('login', {json:payload}).json()
 } 
 
+export const loginTwoFactor = async (payload:TwoFactorLoginPayload):Promise<AuthResponse> => {
+    return await apiClient.post('login/2fa', {json:payload}).json()
+} 
+
 export const register = async (payload: RegisterPayload): Promise<AuthResponse> => {
     return await apiClient.post('register',{json: payload}).json()
 
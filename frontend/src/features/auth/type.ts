@@ -5,6 +5,13 @@ export interface User {
 
 export interface AuthResponse {
     accessToken: string
+    twoFactorRequired?: boolean
+    twoFactorToken?: string
+}
+
+export interface TwoFactorLoginPayload {
+    token: string
+    code: string
 }
 
 export interface LoginPayload {
@@ -14,7 +14,22 @@ export interface UserProfile {
   premiumExpiresAt?: string;
 }
 
+export interface QuotaLimit {
+  limit: number | null;     // null — без ограничений
+  used: number;
+  remaining: number | null;
+}
+
+export interface UserQuota {
+  planId: string;
+  decksToday: QuotaLimit;
+  wordSets: QuotaLimit;
+  cardsPerDeck: number | null;
+  mediaStorageMb: number | null;
+}
+
 export interface FullProfile {
   user: UserProfile;
   stats: UserStats;
+  quota: UserQuota;
 }
\ No newline at end of file
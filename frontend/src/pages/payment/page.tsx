@@ -40,16 +40,12 @@ const PaymentPage = () => {
     if (!selectedPlan) return;
     setLoading(true);
     try {
-        // Эмуляция задержки банка
-        await new Promise(r => setTimeout(r, 1500));
-        
-        // Отправка на бэк
-        await apiClient.post('payment/mock', { json: { planId: selectedPlan.id } });
-        
-        // Close payment modal first
-        setPaymentModalOpen(false);
-        // Open Success Modal
-        setSuccessModalOpen(true);
+        // Создаём сессию оплаты и уходим на страницу провайдера
+        const checkout = await apiClient
+          .post('payment/checkout', { json: { planId: selectedPlan.id } })
+          .json<{ sessionId: string; url: string }>();
+
+        window.location.href = checkout.url;
 
     } catch (e) {
         setPaymentModalOpen(false);